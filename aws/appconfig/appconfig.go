@@ -0,0 +1,215 @@
+// Package appconfig provides a config.Source backed by a configuration profile deployed with AWS
+// AppConfig. The configuration is retrieved with the AppConfig Data API, the session token returned
+// by each poll is kept so the Source can poll for new deployments.
+//
+//	awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	source := appconfig.New(awsCfg, "app", "my-app", "prod", "settings",
+//		appconfig.WithPollInterval(time.Minute),
+//	)
+//	err = config.ParseSources("app", &cfg, source, config.EnvSource())
+package appconfig
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/appconfigdata"
+	"github.com/aws/aws-sdk-go-v2/service/appconfigdata/types"
+	"github.com/josemukorivo/config"
+)
+
+// Client is the part of *appconfigdata.Client used by the Source.
+type Client interface {
+	StartConfigurationSession(ctx context.Context, params *appconfigdata.StartConfigurationSessionInput, optFns ...func(*appconfigdata.Options)) (*appconfigdata.StartConfigurationSessionOutput, error)
+	GetLatestConfiguration(ctx context.Context, params *appconfigdata.GetLatestConfigurationInput, optFns ...func(*appconfigdata.Options)) (*appconfigdata.GetLatestConfigurationOutput, error)
+}
+
+// Option configures the Source.
+type Option func(*Source)
+
+// WithPollInterval polls for a new deployment of the configuration on the first lookup after d has
+// elapsed since the last poll, or the interval required by AppConfig if it is longer. By default the
+// configuration is retrieved once and cached for the lifetime of the Source.
+func WithPollInterval(d time.Duration) Option {
+	return func(s *Source) {
+		s.interval = d
+	}
+}
+
+// WithFormat sets the format of the configuration, for example config.FormatYAML. By default the format
+// is detected from the content type of the configuration profile.
+func WithFormat(format string) Option {
+	return func(s *Source) {
+		s.format = format
+	}
+}
+
+// WithTimeout bounds the time spent retrieving the configuration, it defaults to 10 seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(s *Source) {
+		s.timeout = d
+	}
+}
+
+// contentTypes maps the content types of configuration profiles to their format.
+var contentTypes = map[string]string{
+	"application/json":   config.FormatJSON,
+	"application/x-yaml": config.FormatYAML,
+	"application/yaml":   config.FormatYAML,
+}
+
+// Source is a config.Source that looks up the keys of an AppConfig configuration profile. It is safe
+// for concurrent use.
+type Source struct {
+	client      Client
+	prefix      string
+	application string
+	environment string
+	profile     string
+	format      string
+	interval    time.Duration
+	timeout     time.Duration
+	now         func() time.Time
+	err         error
+
+	mu     sync.Mutex
+	values config.MapSource
+	token  string
+	next   time.Time
+}
+
+// New returns a Source that looks up the keys of the configuration profile deployed to the environment
+// of the application, identified by their names or IDs. The configuration is a JSON or YAML document,
+// nested objects map to nested structs and the keys are prefixed with prefix the same way
+// config.ReadValues does, so with the prefix "app" the key "host" in the "db" object maps to
+// APP_DB_HOST. The configuration is retrieved with an AppConfig Data client created from cfg, on the
+// first lookup, and cached. A cfg without a region is reported by the first lookup.
+func New(cfg aws.Config, prefix, application, environment, profile string, opts ...Option) *Source {
+	s := &Source{
+		client:      appconfigdata.NewFromConfig(cfg),
+		prefix:      prefix,
+		application: application,
+		environment: environment,
+		profile:     profile,
+		timeout:     10 * time.Second,
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if cfg.Region == "" {
+		s.err = errors.New("appconfig: no region in the AWS config")
+	}
+	return s
+}
+
+// Lookup returns the value of key in the configuration, retrieving the configuration if it is not
+// cached or polling for a new deployment if the poll interval has elapsed.
+func (s *Source) Lookup(key string) (string, bool, error) {
+	if s.err != nil {
+		return "", false, s.err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.values == nil || (s.interval > 0 && !s.now().Before(s.next)) {
+		if err := s.poll(); err != nil {
+			return "", false, err
+		}
+	}
+	return s.values.Lookup(key)
+}
+
+// poll retrieves the latest configuration, starting a new session if there is none or it expired.
+func (s *Source) poll() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	if s.token != "" {
+		err := s.latest(ctx)
+		if !isExpired(err) {
+			return err
+		}
+	}
+	if err := s.startSession(ctx); err != nil {
+		return fmt.Errorf("appconfig: starting session for %s/%s/%s: %w", s.application, s.environment, s.profile, err)
+	}
+	return s.latest(ctx)
+}
+
+// startSession starts a configuration session and keeps its initial token.
+func (s *Source) startSession(ctx context.Context) error {
+	out, err := s.client.StartConfigurationSession(ctx, &appconfigdata.StartConfigurationSessionInput{
+		ApplicationIdentifier:                aws.String(s.application),
+		EnvironmentIdentifier:                aws.String(s.environment),
+		ConfigurationProfileIdentifier:       aws.String(s.profile),
+		RequiredMinimumPollIntervalInSeconds: aws.Int32(int32(max(s.interval/time.Second, 15))),
+	})
+	if err != nil {
+		return err
+	}
+	s.token = aws.ToString(out.InitialConfigurationToken)
+	return nil
+}
+
+// latest retrieves the configuration with the current token. The values are kept when the
+// configuration has not changed since the last poll.
+func (s *Source) latest(ctx context.Context) error {
+	out, err := s.client.GetLatestConfiguration(ctx, &appconfigdata.GetLatestConfigurationInput{
+		ConfigurationToken: aws.String(s.token),
+	})
+	if err != nil {
+		return fmt.Errorf("appconfig: retrieving %s/%s/%s: %w", s.application, s.environment, s.profile, err)
+	}
+
+	s.token = aws.ToString(out.NextPollConfigurationToken)
+	s.next = s.now().Add(s.interval)
+	if d := time.Duration(out.NextPollIntervalInSeconds) * time.Second; d > s.interval {
+		s.next = s.now().Add(d)
+	}
+
+	// An empty configuration means it has not changed since the last poll.
+	if len(out.Configuration) == 0 {
+		if s.values == nil {
+			s.values = config.MapSource{}
+		}
+		return nil
+	}
+
+	format := s.format
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(aws.ToString(out.ContentType))
+		var ok bool
+		if format, ok = contentTypes[mediaType]; !ok {
+			return fmt.Errorf("appconfig: unsupported content type %q of %s/%s/%s, use WithFormat", mediaType, s.application, s.environment, s.profile)
+		}
+	}
+	values, err := config.ReadValues(s.prefix, bytes.NewReader(out.Configuration), format)
+	if err != nil {
+		return fmt.Errorf("appconfig: decoding %s/%s/%s: %w", s.application, s.environment, s.profile, err)
+	}
+	s.values = values
+	return nil
+}
+
+// isExpired reports whether err rejects an expired configuration token, the session must be restarted.
+// The other invalid parameters, like a corrupted token or a poll too early, are not fixed by a new
+// session.
+func isExpired(err error) bool {
+	var badRequest *types.BadRequestException
+	if !errors.As(err, &badRequest) {
+		return false
+	}
+	invalid, ok := badRequest.Details.(*types.BadRequestDetailsMemberInvalidParameters)
+	return ok && invalid.Value["ConfigurationToken"].Problem == types.InvalidParameterProblemExpired
+}
@@ -0,0 +1,129 @@
+package appconfig
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/appconfigdata"
+	"github.com/aws/aws-sdk-go-v2/service/appconfigdata/types"
+	"github.com/josemukorivo/config"
+)
+
+type Config struct {
+	Host string
+	DB   struct {
+		Port int
+	}
+}
+
+// fakeClient serves a configuration that changes with every deployment.
+type fakeClient struct {
+	t          *testing.T
+	deployment int
+	sessions   int
+	polls      int
+	invalid    map[string]types.InvalidParameterProblem // The problem of the invalid tokens, like Expired.
+}
+
+func (f *fakeClient) StartConfigurationSession(ctx context.Context, in *appconfigdata.StartConfigurationSessionInput, optFns ...func(*appconfigdata.Options)) (*appconfigdata.StartConfigurationSessionOutput, error) {
+	if aws.ToString(in.ApplicationIdentifier) != "my-app" || aws.ToString(in.EnvironmentIdentifier) != "prod" || aws.ToString(in.ConfigurationProfileIdentifier) != "settings" {
+		f.t.Errorf("unexpected session request %+v", in)
+	}
+	f.sessions++
+	return &appconfigdata.StartConfigurationSessionOutput{InitialConfigurationToken: aws.String(fmt.Sprintf("session-%d", f.sessions))}, nil
+}
+
+func (f *fakeClient) GetLatestConfiguration(ctx context.Context, in *appconfigdata.GetLatestConfigurationInput, optFns ...func(*appconfigdata.Options)) (*appconfigdata.GetLatestConfigurationOutput, error) {
+	token := aws.ToString(in.ConfigurationToken)
+	if problem, ok := f.invalid[token]; ok {
+		return nil, &types.BadRequestException{
+			Message: aws.String("Request contains an invalid configuration token"),
+			Reason:  types.BadRequestReasonInvalidParameters,
+			Details: &types.BadRequestDetailsMemberInvalidParameters{
+				Value: map[string]types.InvalidParameterDetail{"ConfigurationToken": {Problem: problem}},
+			},
+		}
+	}
+	f.polls++
+	out := &appconfigdata.GetLatestConfigurationOutput{
+		NextPollConfigurationToken: aws.String(fmt.Sprintf("poll-%d", f.polls)),
+		NextPollIntervalInSeconds:  60,
+		ContentType:                aws.String("application/json"),
+	}
+	// The configuration is only returned to sessions that have not seen the deployment.
+	if !strings.HasPrefix(token, "poll-") || f.deployment != 1 {
+		out.Configuration = []byte(fmt.Sprintf(`{"host": "example.com", "db": {"port": %d}}`, 5431+f.deployment))
+	}
+	return out, nil
+}
+
+func TestSource(t *testing.T) {
+	client := &fakeClient{t: t, deployment: 1, invalid: make(map[string]types.InvalidParameterProblem)}
+	now := time.Now()
+	source := New(aws.Config{Region: "us-east-1"}, "app", "my-app", "prod", "settings", WithPollInterval(30*time.Second))
+	source.client = client
+	source.now = func() time.Time { return now }
+
+	parse := func(port int) {
+		t.Helper()
+		var c Config
+		if err := config.ParseSources("app", &c, source); err != nil {
+			t.Fatal(err)
+		}
+		if c.Host != "example.com" {
+			t.Fatalf("expected host to be example.com, got %s", c.Host)
+		}
+		if c.DB.Port != port {
+			t.Fatalf("expected db port to be %d, got %d", port, c.DB.Port)
+		}
+	}
+
+	parse(5432)
+
+	// The interval required by AppConfig is longer than the poll interval.
+	now = now.Add(40 * time.Second)
+	parse(5432)
+	if client.polls != 1 {
+		t.Fatalf("expected 1 poll, got %d", client.polls)
+	}
+
+	// The configuration has not changed.
+	now = now.Add(40 * time.Second)
+	parse(5432)
+
+	client.deployment++
+	now = now.Add(time.Minute)
+	parse(5433)
+
+	// An expired token starts a new session.
+	client.invalid[fmt.Sprintf("poll-%d", client.polls)] = types.InvalidParameterProblemExpired
+	now = now.Add(time.Minute)
+	parse(5433)
+	if client.sessions != 2 || client.polls != 4 {
+		t.Fatalf("expected 2 sessions and 4 polls, got %d and %d", client.sessions, client.polls)
+	}
+
+	// Other invalid tokens are errors.
+	client.invalid[fmt.Sprintf("poll-%d", client.polls)] = types.InvalidParameterProblemCorrupted
+	now = now.Add(time.Minute)
+	var c Config
+	if err := config.ParseSources("app", &c, source); err == nil {
+		t.Fatal("expected error for a corrupted token, got nil")
+	}
+	if client.sessions != 2 {
+		t.Fatalf("expected no new session for a corrupted token, got %d sessions", client.sessions)
+	}
+}
+
+func TestSourceNoRegion(t *testing.T) {
+	source := New(aws.Config{}, "app", "my-app", "prod", "settings")
+	source.client = &fakeClient{t: t, invalid: make(map[string]types.InvalidParameterProblem)}
+	var c Config
+	if err := config.ParseSources("app", &c, source); err == nil || !strings.Contains(err.Error(), "no region") {
+		t.Fatalf("expected an error for the missing region, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+// Package s3 provides a config.Source backed by a config document stored in Amazon S3.
+//
+//	awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	source := s3.New(awss3.NewFromConfig(awsCfg), "app", "s3://my-bucket/app/config.yaml")
+//	err = config.ParseSources("app", &cfg, source, config.EnvSource())
+package s3
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/josemukorivo/config"
+)
+
+// Client is the part of *s3.Client used by the Source.
+type Client interface {
+	GetObject(ctx context.Context, params *awss3.GetObjectInput, optFns ...func(*awss3.Options)) (*awss3.GetObjectOutput, error)
+}
+
+// Option configures the Source.
+type Option func(*source)
+
+// WithFormat sets the format of the document, for example config.FormatYAML. By default the format is
+// detected from the extension of the object key.
+func WithFormat(format string) Option {
+	return func(s *source) {
+		s.format = format
+	}
+}
+
+// WithTimeout bounds the time spent downloading the document, it defaults to 10 seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(s *source) {
+		s.timeout = d
+	}
+}
+
+type source struct {
+	client  Client
+	prefix  string
+	url     string
+	format  string
+	timeout time.Duration
+}
+
+// New returns a config.Source that looks up the keys of the config document at the s3://bucket/key URL,
+// downloaded with client, usually an *s3.Client. Keys are prefixed with prefix the same way
+// config.ReadValues does, so with the prefix "app" the key "host" in the "db" map maps to APP_DB_HOST.
+// The document is downloaded on the first lookup.
+func New(client Client, prefix, url string, opts ...Option) config.Source {
+	s := &source{
+		client:  client,
+		prefix:  prefix,
+		url:     url,
+		timeout: 10 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return config.LoadOnce(s.load)
+}
+
+// load downloads and decodes the document.
+func (s *source) load() (config.MapSource, error) {
+	u, err := url.Parse(s.url)
+	if err != nil || u.Scheme != "s3" || u.Host == "" || strings.Trim(u.Path, "/") == "" {
+		return nil, fmt.Errorf("s3: invalid URL %q, expected s3://bucket/key", s.url)
+	}
+	key := strings.TrimPrefix(u.Path, "/")
+
+	format := s.format
+	if format == "" {
+		var ok bool
+		if format, ok = config.DetectFormat(key); !ok {
+			return nil, fmt.Errorf("s3: unknown format of %s, use WithFormat", s.url)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	out, err := s.client.GetObject(ctx, &awss3.GetObjectInput{
+		Bucket: aws.String(u.Host),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s3: downloading %s: %w", s.url, err)
+	}
+	defer out.Body.Close()
+
+	values, err := config.ReadValues(s.prefix, out.Body, format)
+	if err != nil {
+		return nil, fmt.Errorf("s3: decoding %s: %w", s.url, err)
+	}
+	return values, nil
+}
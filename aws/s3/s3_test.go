@@ -0,0 +1,74 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/josemukorivo/config"
+)
+
+// fakeClient serves objects from a map of bucket/key to content.
+type fakeClient map[string]string
+
+func (c fakeClient) GetObject(ctx context.Context, in *awss3.GetObjectInput, optFns ...func(*awss3.Options)) (*awss3.GetObjectOutput, error) {
+	content, ok := c[aws.ToString(in.Bucket)+"/"+aws.ToString(in.Key)]
+	if !ok {
+		return nil, errors.New("NoSuchKey")
+	}
+	return &awss3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(content))}, nil
+}
+
+type Config struct {
+	Host string
+	DB   struct {
+		Port int
+	}
+}
+
+func TestSource(t *testing.T) {
+	client := fakeClient{
+		"my-bucket/app/config.yaml": "host: s3.example.com\ndb:\n  port: 5432\n",
+		"my-bucket/app/config":      `{"db": {"port": 6543}}`,
+	}
+
+	os.Clearenv()
+	os.Setenv("APP_HOST", "example.com")
+
+	var cfg Config
+	source := New(client, "app", "s3://my-bucket/app/config.yaml")
+	if err := config.ParseSources("app", &cfg, source, config.EnvSource()); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Host != "example.com" {
+		t.Fatalf("expected host to be example.com, got %s", cfg.Host)
+	}
+	if cfg.DB.Port != 5432 {
+		t.Fatalf("expected db port to be 5432, got %d", cfg.DB.Port)
+	}
+
+	source = New(client, "app", "s3://my-bucket/app/config", WithFormat(config.FormatJSON))
+	if err := config.ParseSources("app", &cfg, source); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DB.Port != 6543 {
+		t.Fatalf("expected db port to be 6543, got %d", cfg.DB.Port)
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	client := fakeClient{"my-bucket/app/config": "host: example.com"}
+
+	for _, url := range []string{"https://my-bucket/app/config.yaml", "s3://my-bucket", "s3://my-bucket/app/config", "s3://my-bucket/missing.yaml"} {
+		var cfg Config
+		if err := config.ParseSources("app", &cfg, New(client, "app", url)); err == nil {
+			t.Fatalf("expected error for %s, got nil", url)
+		}
+	}
+}
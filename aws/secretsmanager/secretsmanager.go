@@ -0,0 +1,153 @@
+// Package secretsmanager provides a config.Source backed by a JSON secret stored in AWS Secrets Manager.
+//
+//	awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	source := secretsmanager.New(awssm.NewFromConfig(awsCfg), "app", "prod/app",
+//		secretsmanager.WithRefreshInterval(time.Hour),
+//	)
+//	err = config.ParseSources("app", &cfg, config.EnvSource(), source)
+package secretsmanager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awssm "github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/josemukorivo/config"
+)
+
+// Client is the part of *secretsmanager.Client used by the Source.
+type Client interface {
+	GetSecretValue(ctx context.Context, params *awssm.GetSecretValueInput, optFns ...func(*awssm.Options)) (*awssm.GetSecretValueOutput, error)
+}
+
+// Option configures the Source.
+type Option func(*Source)
+
+// WithRefreshInterval fetches the secret again on the first parse after d has elapsed since it was last
+// fetched. By default the secret is fetched once and cached for the lifetime of the Source.
+func WithRefreshInterval(d time.Duration) Option {
+	return func(s *Source) {
+		s.refresh = d
+	}
+}
+
+// WithVersionStage fetches the given version stage of the secret instead of AWSCURRENT.
+func WithVersionStage(stage string) Option {
+	return func(s *Source) {
+		s.stage = stage
+	}
+}
+
+// WithTimeout bounds the time spent fetching the secret, it defaults to 10 seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(s *Source) {
+		s.timeout = d
+	}
+}
+
+// Source is a config.Source that looks up the keys of a JSON secret. It is a config.SnapshotSource, a
+// parse looks up all the keys in the same version of the secret, and a config.KeySource. It is safe
+// for concurrent use.
+type Source struct {
+	client   Client
+	prefix   string
+	secretID string
+	stage    string
+	refresh  time.Duration
+	timeout  time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	values  config.KeySource
+	fetched time.Time
+}
+
+// New returns a Source that looks up the keys of the JSON object stored in the secret secretID, the
+// name or ARN of the secret. Nested objects map to nested structs and the keys are prefixed with
+// prefix the same way config.ReadValues does, so with the prefix "app" the key db_password maps to
+// APP_DB_PASSWORD. The items of arrays are kept and joined with the separator of the field they are
+// assigned to. The secret is fetched on the first parse and cached.
+func New(client Client, prefix, secretID string, opts ...Option) *Source {
+	s := &Source{
+		client:   client,
+		prefix:   prefix,
+		secretID: secretID,
+		timeout:  10 * time.Second,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Snapshot returns the values of the secret, fetching the secret if it is not cached or the refresh
+// interval has elapsed. The parse functions call it once and look up all the keys in its values.
+func (s *Source) Snapshot() (config.Source, error) {
+	return s.snapshot()
+}
+
+// Lookup returns the value of key in the current snapshot of the secret.
+func (s *Source) Lookup(key string) (string, bool, error) {
+	values, err := s.snapshot()
+	if err != nil {
+		return "", false, err
+	}
+	return values.Lookup(key)
+}
+
+// Keys returns the keys of the current snapshot of the secret.
+func (s *Source) Keys() ([]string, error) {
+	values, err := s.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return values.Keys()
+}
+
+// snapshot returns the cached values of the secret, fetching them again when they are missing or the
+// refresh interval has elapsed.
+func (s *Source) snapshot() (config.KeySource, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.values == nil || (s.refresh > 0 && s.now().Sub(s.fetched) >= s.refresh) {
+		values, err := s.fetch()
+		if err != nil {
+			return nil, err
+		}
+		s.values, s.fetched = values, s.now()
+	}
+	return s.values, nil
+}
+
+// fetch fetches and decodes the secret.
+func (s *Source) fetch() (config.KeySource, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	in := &awssm.GetSecretValueInput{SecretId: aws.String(s.secretID)}
+	if s.stage != "" {
+		in.VersionStage = aws.String(s.stage)
+	}
+	out, err := s.client.GetSecretValue(ctx, in)
+	if err != nil {
+		return nil, fmt.Errorf("secretsmanager: fetching secret %s: %w", s.secretID, err)
+	}
+	if out.SecretString == nil {
+		return nil, fmt.Errorf("secretsmanager: secret %s is not a JSON string", s.secretID)
+	}
+
+	values, err := config.ReadDocument(s.prefix, strings.NewReader(*out.SecretString), config.FormatJSON)
+	if err != nil {
+		return nil, fmt.Errorf("secretsmanager: secret %s: %w", s.secretID, err)
+	}
+	return values, nil
+}
@@ -0,0 +1,153 @@
+package secretsmanager
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awssm "github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/josemukorivo/config"
+)
+
+type fakeClient struct {
+	secret string
+	calls  int
+}
+
+func (c *fakeClient) GetSecretValue(ctx context.Context, in *awssm.GetSecretValueInput, optFns ...func(*awssm.Options)) (*awssm.GetSecretValueOutput, error) {
+	c.calls++
+	return &awssm.GetSecretValueOutput{Name: in.SecretId, SecretString: aws.String(c.secret)}, nil
+}
+
+type Config struct {
+	Host string
+	DB   struct {
+		Password string
+		Port     int
+	}
+}
+
+func TestSource(t *testing.T) {
+	client := &fakeClient{secret: `{"db": {"password": "s3cr3t", "port": 5432}}`}
+	os.Clearenv()
+	os.Setenv("APP_HOST", "example.com")
+
+	var cfg Config
+	source := New(client, "app", "prod/app")
+	if err := config.ParseSources("app", &cfg, config.EnvSource(), source); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Host != "example.com" {
+		t.Fatalf("expected host to be example.com, got %s", cfg.Host)
+	}
+	if cfg.DB.Password != "s3cr3t" {
+		t.Fatalf("expected db password to be s3cr3t, got %s", cfg.DB.Password)
+	}
+	if cfg.DB.Port != 5432 {
+		t.Fatalf("expected db port to be 5432, got %d", cfg.DB.Port)
+	}
+
+	if err := config.ParseSources("app", &cfg, source); err != nil {
+		t.Fatal(err)
+	}
+	if client.calls != 1 {
+		t.Fatalf("expected the secret to be fetched once, got %d", client.calls)
+	}
+}
+
+func TestSourceSnapshot(t *testing.T) {
+	client := &fakeClient{secret: `{"user": "old", "password": "old"}`}
+	now := time.Now()
+	source := New(client, "app", "prod/app", WithRefreshInterval(time.Minute))
+	source.now = func() time.Time { return now }
+
+	var cfg struct {
+		User     string
+		Password string
+	}
+	// The secret is rotated while the config is parsed, after the user is looked up.
+	parse := config.SourceFunc(func(key string) (string, bool, error) {
+		if key == "APP_PASSWORD" {
+			client.secret = `{"user": "new", "password": "new"}`
+			now = now.Add(2 * time.Minute)
+		}
+		return "", false, nil
+	})
+	if err := config.ParseSources("app", &cfg, source, parse); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.User != "old" || cfg.Password != "old" {
+		t.Fatalf("expected both values from the same version of the secret, got %+v", cfg)
+	}
+
+	if err := config.ParseSources("app", &cfg, source); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.User != "new" || cfg.Password != "new" {
+		t.Fatalf("expected the next parse to see the new version, got %+v", cfg)
+	}
+}
+
+func TestSourceKeysAndLists(t *testing.T) {
+	client := &fakeClient{secret: `{
+		"hosts": ["a.example.com", "b.example.com"],
+		"dsns": ["postgres://db1/app?options=a,b", "postgres://db2/app"],
+		"tenants": {"acme": {"token": "t1"}, "globex": {"token": "t2"}}
+	}`}
+
+	var cfg struct {
+		Hosts   []string
+		DSNs    []string `sep:"|"`
+		Tenants map[string]struct {
+			Token string
+		}
+	}
+	if err := config.ParseSources("app", &cfg, New(client, "app", "prod/app")); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg.Hosts, []string{"a.example.com", "b.example.com"}) {
+		t.Fatalf("expected hosts to be a.example.com and b.example.com, got %q", cfg.Hosts)
+	}
+	if !reflect.DeepEqual(cfg.DSNs, []string{"postgres://db1/app?options=a,b", "postgres://db2/app"}) {
+		t.Fatalf("expected the dsns to keep their commas, got %q", cfg.DSNs)
+	}
+	if len(cfg.Tenants) != 2 || cfg.Tenants["acme"].Token != "t1" || cfg.Tenants["globex"].Token != "t2" {
+		t.Fatalf("expected the tenants acme and globex, got %+v", cfg.Tenants)
+	}
+}
+
+func TestSourceRefresh(t *testing.T) {
+	client := &fakeClient{secret: `{"db_password": "old"}`}
+	now := time.Now()
+	source := New(client, "app", "prod/app", WithRefreshInterval(time.Minute))
+	source.now = func() time.Time { return now }
+
+	if value, _, _ := source.Lookup("APP_DB_PASSWORD"); value != "old" {
+		t.Fatalf("expected old, got %s", value)
+	}
+
+	client.secret = `{"db_password": "new"}`
+	now = now.Add(30 * time.Second)
+	if value, _, _ := source.Lookup("APP_DB_PASSWORD"); value != "old" {
+		t.Fatalf("expected the cached value old, got %s", value)
+	}
+
+	now = now.Add(time.Minute)
+	if value, _, _ := source.Lookup("APP_DB_PASSWORD"); value != "new" {
+		t.Fatalf("expected the refreshed value new, got %s", value)
+	}
+	if client.calls != 2 {
+		t.Fatalf("expected the secret to be fetched twice, got %d", client.calls)
+	}
+}
+
+func TestSourceInvalidSecret(t *testing.T) {
+	client := &fakeClient{secret: "not json"}
+	if _, _, err := New(client, "app", "prod/app").Lookup("APP_HOST"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,77 @@
+// Package ssm provides a config.Source backed by AWS Systems Manager Parameter Store.
+//
+//	awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	source := ssm.New(awsssm.NewFromConfig(awsCfg), "/")
+//	err = config.ParseSources("app", &cfg, source, config.EnvSource())
+package ssm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsssm "github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/josemukorivo/config"
+)
+
+// Option configures the Source.
+type Option func(*source)
+
+// WithTimeout bounds the time spent fetching the parameters, it defaults to 10 seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(s *source) {
+		s.timeout = d
+	}
+}
+
+type source struct {
+	client  awsssm.GetParametersByPathAPIClient
+	path    string
+	timeout time.Duration
+}
+
+// New returns a config.Source that looks up the parameters stored under path, usually an *ssm.Client.
+// The name of a parameter relative to path is mapped to the key of a field with config.NormalizeKey,
+// for example with the path "/" the parameter /app/db/host maps to APP_DB_HOST and with the path
+// "/prod" the parameter /prod/app/db/host does. SecureString parameters are decrypted. All the
+// parameters under path are fetched on the first lookup.
+func New(client awsssm.GetParametersByPathAPIClient, path string, opts ...Option) config.Source {
+	s := &source{
+		client:  client,
+		path:    path,
+		timeout: 10 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return config.LoadOnce(s.load)
+}
+
+// load fetches all the parameters under the path.
+func (s *source) load() (config.MapSource, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	values := make(config.MapSource)
+	paginator := awsssm.NewGetParametersByPathPaginator(s.client, &awsssm.GetParametersByPathInput{
+		Path:           aws.String(s.path),
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(true),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("ssm: fetching parameters under %s: %w", s.path, err)
+		}
+		for _, p := range page.Parameters {
+			name := strings.TrimPrefix(aws.ToString(p.Name), s.path)
+			values[config.NormalizeKey(name)] = aws.ToString(p.Value)
+		}
+	}
+	return values, nil
+}
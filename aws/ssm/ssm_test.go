@@ -0,0 +1,86 @@
+package ssm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsssm "github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/josemukorivo/config"
+)
+
+// fakeClient serves the parameters one per page.
+type fakeClient struct {
+	t          *testing.T
+	parameters []types.Parameter
+	err        error
+}
+
+func (c *fakeClient) GetParametersByPath(ctx context.Context, in *awsssm.GetParametersByPathInput, optFns ...func(*awsssm.Options)) (*awsssm.GetParametersByPathOutput, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if !aws.ToBool(in.Recursive) || !aws.ToBool(in.WithDecryption) {
+		c.t.Errorf("expected a recursive request with decryption, got %+v", in)
+	}
+
+	i := 0
+	if in.NextToken != nil {
+		i = int((*in.NextToken)[0] - '0')
+	}
+	out := &awsssm.GetParametersByPathOutput{Parameters: c.parameters[i : i+1]}
+	if i+1 < len(c.parameters) {
+		out.NextToken = aws.String(string(rune('0' + i + 1)))
+	}
+	return out, nil
+}
+
+func TestSource(t *testing.T) {
+	client := &fakeClient{
+		t: t,
+		parameters: []types.Parameter{
+			{Name: aws.String("/prod/app/host"), Value: aws.String("example.com")},
+			{Name: aws.String("/prod/app/db/host"), Value: aws.String("db.example.com")},
+			{Name: aws.String("/prod/app/db/password"), Value: aws.String("s3cr3t"), Type: types.ParameterTypeSecureString},
+		},
+	}
+
+	os.Clearenv()
+	os.Setenv("APP_HOST", "env.example.com")
+
+	spec := struct {
+		Host string
+		DB   struct {
+			Host     string
+			Password string
+		}
+	}{}
+
+	if err := config.ParseSources("app", &spec, New(client, "/prod"), config.EnvSource()); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Host != "env.example.com" {
+		t.Fatalf("expected host to be env.example.com, got %s", spec.Host)
+	}
+	if spec.DB.Host != "db.example.com" {
+		t.Fatalf("expected db host to be db.example.com, got %s", spec.DB.Host)
+	}
+	if spec.DB.Password != "s3cr3t" {
+		t.Fatalf("expected db password to be s3cr3t, got %s", spec.DB.Password)
+	}
+}
+
+func TestSourceError(t *testing.T) {
+	client := &fakeClient{t: t, err: errors.New("access denied")}
+
+	spec := struct {
+		Host string
+	}{}
+	if err := config.ParseSources("app", &spec, New(client, "/")); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,241 @@
+// Package keyvault provides a config.Source backed by Azure Key Vault secrets. Requests are
+// authenticated with DefaultAzureCredential, which picks up environment credentials, workload and
+// managed identities or the Azure CLI login.
+//
+//	source := keyvault.New("https://my-vault.vault.azure.net", "app")
+//	err := config.ParseSources("app", &cfg, config.EnvSource(), source)
+package keyvault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azsecrets"
+	"github.com/josemukorivo/config"
+)
+
+// Client is the part of *azsecrets.Client used by the Source.
+type Client interface {
+	GetSecret(ctx context.Context, name, version string, options *azsecrets.GetSecretOptions) (azsecrets.GetSecretResponse, error)
+	NewListSecretPropertiesPager(options *azsecrets.ListSecretPropertiesOptions) *runtime.Pager[azsecrets.ListSecretPropertiesResponse]
+}
+
+// Option configures the Source.
+type Option func(*source)
+
+// WithClient uses the given client instead of one authenticated with DefaultAzureCredential.
+func WithClient(client Client) Option {
+	return func(s *source) {
+		s.client = client
+	}
+}
+
+// WithTimeout bounds the time spent listing the secrets or reading a secret, it defaults to 10 seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(s *source) {
+		s.timeout = d
+	}
+}
+
+type source struct {
+	vaultURL string
+	prefix   string
+	client   Client
+	timeout  time.Duration
+
+	mu      sync.Mutex
+	names   *secrets          // The secrets listed by the last snapshot.
+	secrets map[string]string // The values of the secrets read so far, by name.
+}
+
+// New returns a config.Source that looks up keys in the secrets of the vault at vaultURL. Secret names
+// may only contain letters, digits and dashes, so a key is mapped to a secret name by removing the
+// prefix, lowercasing it and replacing underscores with dashes. With the prefix "app" the key
+// APP_DB_PASSWORD maps to the secret db-password, keys without the prefix are not found.
+//
+// The Source is a config.SnapshotSource: every parse lists the names of the enabled secrets of the
+// vault once, so the identity needs the List permission on secrets, and only the keys of the listed
+// secrets are read. A secret created later is found by the next parse. The values are read on their
+// first lookup and cached.
+func New(vaultURL, prefix string, opts ...Option) config.Source {
+	s := &source{
+		vaultURL: vaultURL,
+		prefix:   strings.ToUpper(prefix),
+		timeout:  10 * time.Second,
+		secrets:  make(map[string]string),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Snapshot lists the secrets of the vault and returns a Source looking up their keys.
+func (s *source) Snapshot() (config.Source, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names, err := s.list()
+	if err != nil {
+		return nil, err
+	}
+	s.names = names
+	return names, nil
+}
+
+// Lookup reads the latest version of the secret the key maps to, if the last snapshot listed it. The
+// secrets are listed on the first lookup if no snapshot was taken.
+func (s *source) Lookup(key string) (string, bool, error) {
+	names, err := s.current()
+	if err != nil {
+		return "", false, err
+	}
+	return names.Lookup(key)
+}
+
+// Keys returns the keys of the secrets listed by the last snapshot.
+func (s *source) Keys() ([]string, error) {
+	names, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	return names.Keys()
+}
+
+// current returns the secrets listed by the last snapshot, listing them if there is none.
+func (s *source) current() (*secrets, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.names == nil {
+		names, err := s.list()
+		if err != nil {
+			return nil, err
+		}
+		s.names = names
+	}
+	return s.names, nil
+}
+
+// list lists the names of the enabled secrets of the vault.
+func (s *source) list() (*secrets, error) {
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	names := make(map[string]bool)
+	pager := s.client.NewListSecretPropertiesPager(nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("keyvault: listing secrets: %w", err)
+		}
+		for _, props := range page.Value {
+			if props == nil || props.ID == nil {
+				continue
+			}
+			if props.Attributes != nil && props.Attributes.Enabled != nil && !*props.Attributes.Enabled {
+				continue
+			}
+			names[strings.ToLower(props.ID.Name())] = true
+		}
+	}
+	return &secrets{source: s, names: names}, nil
+}
+
+// connect creates a client authenticated with DefaultAzureCredential if none was given.
+func (s *source) connect() error {
+	if s.client != nil {
+		return nil
+	}
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return fmt.Errorf("keyvault: creating default credential: %w", err)
+	}
+	client, err := azsecrets.NewClient(s.vaultURL, cred, nil)
+	if err != nil {
+		return fmt.Errorf("keyvault: creating client: %w", err)
+	}
+	s.client = client
+	return nil
+}
+
+// read returns the value of the latest version of the secret name, reading it if it is not cached. A
+// secret deleted since it was listed is not found and not cached.
+func (s *source) read(name string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if value, ok := s.secrets[name]; ok {
+		return value, true, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	resp, err := s.client.GetSecret(ctx, name, "", nil)
+	var respErr *azcore.ResponseError
+	switch {
+	case errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound:
+		return "", false, nil
+	case err != nil:
+		return "", false, fmt.Errorf("keyvault: reading secret %s: %w", name, err)
+	case resp.Value == nil:
+		return "", false, nil
+	}
+	s.secrets[name] = *resp.Value
+	return *resp.Value, true, nil
+}
+
+// secrets is a snapshot of the names of the secrets of a vault.
+type secrets struct {
+	source *source
+	names  map[string]bool
+}
+
+// Lookup reads the secret the key maps to if it was listed.
+func (s *secrets) Lookup(key string) (string, bool, error) {
+	name, ok := s.source.secretName(key)
+	if !ok || !s.names[name] {
+		return "", false, nil
+	}
+	return s.source.read(name)
+}
+
+// Keys returns the keys the listed secrets map to.
+func (s *secrets) Keys() ([]string, error) {
+	keys := make([]string, 0, len(s.names))
+	for name := range s.names {
+		key := strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		if s.source.prefix != "" {
+			key = s.source.prefix + "_" + key
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// secretName maps key to the name of a secret.
+func (s *source) secretName(key string) (string, bool) {
+	if s.prefix != "" {
+		var ok bool
+		if key, ok = strings.CutPrefix(key, s.prefix+"_"); !ok {
+			return "", false
+		}
+	}
+	if key == "" {
+		return "", false
+	}
+	return strings.ToLower(strings.ReplaceAll(key, "_", "-")), true
+}
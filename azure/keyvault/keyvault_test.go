@@ -0,0 +1,185 @@
+package keyvault
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azsecrets"
+	"github.com/josemukorivo/config"
+)
+
+type fakeClient struct {
+	secrets  map[string]string
+	disabled map[string]bool
+	calls    map[string]int
+	lists    int
+	err      error
+}
+
+func (c *fakeClient) NewListSecretPropertiesPager(options *azsecrets.ListSecretPropertiesOptions) *runtime.Pager[azsecrets.ListSecretPropertiesResponse] {
+	return runtime.NewPager(runtime.PagingHandler[azsecrets.ListSecretPropertiesResponse]{
+		More: func(page azsecrets.ListSecretPropertiesResponse) bool {
+			return false
+		},
+		Fetcher: func(ctx context.Context, page *azsecrets.ListSecretPropertiesResponse) (azsecrets.ListSecretPropertiesResponse, error) {
+			c.lists++
+			if c.err != nil {
+				return azsecrets.ListSecretPropertiesResponse{}, c.err
+			}
+			var resp azsecrets.ListSecretPropertiesResponse
+			for name := range c.secrets {
+				id := azsecrets.ID("https://my-vault.vault.azure.net/secrets/" + name)
+				enabled := !c.disabled[name]
+				resp.Value = append(resp.Value, &azsecrets.SecretProperties{
+					ID:         &id,
+					Attributes: &azsecrets.SecretAttributes{Enabled: &enabled},
+				})
+			}
+			return resp, nil
+		},
+	})
+}
+
+func (c *fakeClient) GetSecret(ctx context.Context, name, version string, options *azsecrets.GetSecretOptions) (azsecrets.GetSecretResponse, error) {
+	c.calls[name]++
+	if c.err != nil {
+		return azsecrets.GetSecretResponse{}, c.err
+	}
+	value, ok := c.secrets[name]
+	if !ok {
+		return azsecrets.GetSecretResponse{}, &azcore.ResponseError{StatusCode: http.StatusNotFound, ErrorCode: "SecretNotFound"}
+	}
+	return azsecrets.GetSecretResponse{Secret: azsecrets.Secret{Value: &value}}, nil
+}
+
+type Config struct {
+	Host string
+	DB   struct {
+		Password string
+		Port     int `default:"5432"`
+	}
+}
+
+func TestSource(t *testing.T) {
+	client := &fakeClient{
+		secrets: map[string]string{"db-password": "s3cr3t", "host": "vault.example.com"},
+		calls:   make(map[string]int),
+	}
+
+	os.Clearenv()
+	os.Setenv("APP_HOST", "example.com")
+
+	source := New("https://my-vault.vault.azure.net", "app", WithClient(client))
+	for i := 0; i < 2; i++ {
+		var cfg Config
+		if err := config.ParseSources("app", &cfg, source, config.EnvSource()); err != nil {
+			t.Fatal(err)
+		}
+
+		if cfg.Host != "example.com" {
+			t.Fatalf("expected host to be example.com, got %s", cfg.Host)
+		}
+		if cfg.DB.Password != "s3cr3t" {
+			t.Fatalf("expected db password to be s3cr3t, got %s", cfg.DB.Password)
+		}
+		if cfg.DB.Port != 5432 {
+			t.Fatalf("expected db port to be 5432, got %d", cfg.DB.Port)
+		}
+	}
+
+	if len(client.calls) != 1 || client.calls["db-password"] != 1 {
+		t.Fatalf("expected only the db-password secret to be read, once, got %v", client.calls)
+	}
+	if client.lists != 2 {
+		t.Fatalf("expected the secrets to be listed once per parse, got %d", client.lists)
+	}
+}
+
+func TestSourceNewSecret(t *testing.T) {
+	client := &fakeClient{
+		secrets:  map[string]string{"host": "vault.example.com", "db-port": "6543"},
+		disabled: map[string]bool{"db-port": true},
+		calls:    make(map[string]int),
+	}
+
+	os.Clearenv()
+	source := New("https://my-vault.vault.azure.net", "app", WithClient(client))
+	var cfg Config
+	if err := config.ParseSources("app", &cfg, source); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DB.Password != "" || cfg.DB.Port != 5432 {
+		t.Fatalf("expected the missing and disabled secrets not to be found, got %+v", cfg.DB)
+	}
+	if client.calls["db-password"] != 0 || client.calls["db-port"] != 0 {
+		t.Fatalf("expected the unlisted secrets not to be read, got %v", client.calls)
+	}
+
+	client.secrets["db-password"] = "s3cr3t"
+	if err := config.ParseSources("app", &cfg, source); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DB.Password != "s3cr3t" {
+		t.Fatalf("expected the secret created after the first parse to be found, got %q", cfg.DB.Password)
+	}
+}
+
+func TestSourceKeys(t *testing.T) {
+	client := &fakeClient{
+		secrets: map[string]string{"tenants-acme-token": "t1", "tenants-globex-token": "t2"},
+		calls:   make(map[string]int),
+	}
+
+	var cfg struct {
+		Tenants map[string]struct {
+			Token string
+		}
+	}
+	source := New("https://my-vault.vault.azure.net", "app", WithClient(client))
+	if err := config.ParseSources("app", &cfg, source); err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Tenants) != 2 || cfg.Tenants["acme"].Token != "t1" || cfg.Tenants["globex"].Token != "t2" {
+		t.Fatalf("expected the tenants acme and globex, got %+v", cfg.Tenants)
+	}
+}
+
+func TestSecretName(t *testing.T) {
+	tests := []struct {
+		description string
+		prefix      string
+		key         string
+		name        string
+		ok          bool
+	}{
+		{"prefixed key", "app", "APP_DB_PASSWORD", "db-password", true},
+		{"key without prefix", "app", "DB_PASSWORD", "", false},
+		{"prefix only", "app", "APP_", "", false},
+		{"no prefix", "", "APP_DB_PASSWORD", "app-db-password", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			s := New("https://my-vault.vault.azure.net", tc.prefix).(*source)
+			name, ok := s.secretName(tc.key)
+			if name != tc.name || ok != tc.ok {
+				t.Fatalf("expected %s to map to %q %v, got %q %v", tc.key, tc.name, tc.ok, name, ok)
+			}
+		})
+	}
+}
+
+func TestSourceError(t *testing.T) {
+	client := &fakeClient{err: errors.New("forbidden"), calls: make(map[string]int)}
+
+	var cfg Config
+	source := New("https://my-vault.vault.azure.net", "app", WithClient(client))
+	if err := config.ParseSources("app", &cfg, source); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,144 @@
+// Package command provides a config.Source that runs a command to get the value of a key, so secret
+// managers that are only reachable through a CLI, like 1Password or gopass, can provide config values.
+//
+//	source := command.New("op read op://prod/app/{{.Key | lower}}", []string{"APP_DB_PASSWORD"})
+//	err := config.ParseSources("app", &cfg, config.EnvSource(), source)
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/josemukorivo/config"
+)
+
+// Option configures the Source.
+type Option func(*source)
+
+// WithTimeout bounds the time a command runs, it defaults to 10 seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(s *source) {
+		s.timeout = d
+	}
+}
+
+// WithDir runs the commands in the directory dir instead of the working directory.
+func WithDir(dir string) Option {
+	return func(s *source) {
+		s.dir = dir
+	}
+}
+
+// funcs are the functions available in the command template.
+var funcs = template.FuncMap{
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
+	"replace": func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+}
+
+type value struct {
+	value string
+	ok    bool
+}
+
+type source struct {
+	tmpl    *template.Template
+	keys    map[string]bool
+	timeout time.Duration
+	dir     string
+	err     error
+
+	mu     sync.Mutex
+	values map[string]value
+}
+
+// New returns a config.Source that runs the command template to look up the given keys, other keys
+// are not found without running the command, so that it does not run for every key of the config. The
+// template is a text/template executed with the key as .Key and the functions lower, upper and
+// replace, for example {{.Key | lower | replace "_" "-"}}, the result is split into arguments on white
+// space. The command is not run by a shell. The output of the command, without trailing newlines, is
+// the value of the key, an empty output means the key is not found and a failing command aborts
+// parsing. Each key is looked up once and cached. An empty list of keys is reported when parsing.
+func New(command string, keys []string, opts ...Option) config.Source {
+	s := &source{
+		timeout: 10 * time.Second,
+		values:  make(map[string]value),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.keys = make(map[string]bool, len(keys))
+	for _, key := range keys {
+		s.keys[strings.ToUpper(key)] = true
+	}
+	if len(s.keys) == 0 {
+		s.err = errors.New("command: no keys to look up")
+		return s
+	}
+
+	s.tmpl, s.err = template.New("command").Funcs(funcs).Parse(command)
+	if s.err != nil {
+		s.err = fmt.Errorf("command: parsing %q: %w", command, s.err)
+	}
+	return s
+}
+
+// Lookup runs the command for key, unless key is not one of the keys of the Source.
+func (s *source) Lookup(key string) (string, bool, error) {
+	if s.err != nil {
+		return "", false, s.err
+	}
+	if !s.keys[key] {
+		return "", false, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if v, ok := s.values[key]; ok {
+		return v.value, v.ok, nil
+	}
+	out, err := s.run(key)
+	if err != nil {
+		return "", false, err
+	}
+	out = strings.TrimRight(out, "\r\n")
+	v := value{value: out, ok: out != ""}
+	s.values[key] = v
+	return v.value, v.ok, nil
+}
+
+// run runs the command for key and returns its output.
+func (s *source) run(key string) (string, error) {
+	var buf strings.Builder
+	if err := s.tmpl.Execute(&buf, struct{ Key string }{key}); err != nil {
+		return "", fmt.Errorf("command: %s: %w", key, err)
+	}
+	args := strings.Fields(buf.String())
+	if len(args) == 0 {
+		return "", fmt.Errorf("command: %s: empty command", key)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = s.dir
+	cmd.WaitDelay = time.Second
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return "", fmt.Errorf("command: %s: running %s: %w: %s", key, args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,95 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/josemukorivo/config"
+)
+
+type Config struct {
+	Host string
+	DB   struct {
+		Password string
+		User     string `default:"admin"`
+	}
+}
+
+// writeScript writes a shell script printing the value of the secret named by its argument.
+func writeScript(t *testing.T, body string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not supported on windows")
+	}
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSource(t *testing.T) {
+	dir := t.TempDir()
+	script := writeScript(t, `echo run >> `+filepath.Join(dir, "runs")+`
+case "$1" in
+  vault/app-db-password) echo s3cr3t ;;
+esac
+`)
+
+	os.Clearenv()
+	os.Setenv("APP_HOST", "example.com")
+
+	source := New(script+` vault/{{.Key | lower | replace "_" "-"}}`, []string{"APP_DB_PASSWORD", "APP_DB_USER"})
+	for i := 0; i < 2; i++ {
+		var cfg Config
+		if err := config.ParseSources("app", &cfg, config.EnvSource(), source); err != nil {
+			t.Fatal(err)
+		}
+
+		if cfg.Host != "example.com" {
+			t.Fatalf("expected host to be example.com, got %s", cfg.Host)
+		}
+		if cfg.DB.Password != "s3cr3t" {
+			t.Fatalf("expected db password to be s3cr3t, got %q", cfg.DB.Password)
+		}
+		if cfg.DB.User != "admin" {
+			t.Fatalf("expected db user to be admin, got %s", cfg.DB.User)
+		}
+	}
+
+	runs, err := os.ReadFile(filepath.Join(dir, "runs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(runs) != "run\nrun\n" {
+		t.Fatalf("expected the command to run once per key, got %q", runs)
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	failing := writeScript(t, "echo 'not signed in' >&2\nexit 1\n")
+	slow := writeScript(t, "exec sleep 5\n")
+
+	tests := []struct {
+		description string
+		source      config.Source
+	}{
+		{"failing command", New(failing, []string{"APP_DB_PASSWORD"})},
+		{"timeout", New(slow, []string{"APP_HOST"}, WithTimeout(50*time.Millisecond))},
+		{"invalid template", New("op read {{.Key", []string{"APP_HOST"})},
+		{"empty command", New(" ", []string{"APP_HOST"})},
+		{"no keys", New("op read {{.Key}}", nil)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var cfg Config
+			if err := config.ParseSources("app", &cfg, tc.source); err == nil {
+				t.Fatal("expected error, got nil")
+			}
+		})
+	}
+}
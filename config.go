@@ -4,8 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"os"
-
-	env "github.com/joho/godotenv"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
 )
 
 // ErrInvalidConfig is returned when the config is not a pointer to struct.
@@ -13,30 +16,137 @@ var (
 	ErrInvalidConfig = errors.New("config: invalid config must be a pointer to struct")
 )
 
+// errSecretValue replaces the error of a field holding a secret in its FieldError.
+var errSecretValue = errors.New("the value of a secret field is not shown")
+
 // Parse parses the config, the config must be a pointer to struct and the struct can contain nested structs.
 // The prefix is used to prefix the environment variables. For example, if the prefix is "app" and the struct
 // contains a field named "Host", the environment variable will be "APP_HOST". If the struct contains a nested
 // struct, the prefix will be the original prefix plus the nested struct name. For example, if the prefix is "app"
-// and the nested struct is named "DB", the environment variable will be "APP_DB_HOST". Parse take an optional
-// list of .env files to load. If the .env file exists, it will be loaded before parsing the config. By default,
-// Parse will look for a .env file and parse it.
+// and the nested struct is named "DB", the environment variable will be "APP_DB_HOST". A pointer to a nested
+// struct is allocated only if one of its variables is set, otherwise it is left nil. A map of structs,
+// map[string]Endpoint named Endpoints, gets an entry for every name of the variables like
+// APP_ENDPOINTS_<NAME>_URL. Parse take an optional list of .env files to load. If the .env file exists,
+// it will be loaded before parsing the config. By default, Parse will look for a .env file and parse
+// it, see DotEnvSource for the syntax of the files. Docker secrets, systemd credentials and files named
+// by *_FILE environment variables are only resolved when asked for, with ParseSources and
+// DockerSecretsSource, CredentialsSource and FileRefSource.
 func Parse(prefix string, cfg any, envFiles ...string) error {
 	// Load the .env file if it exists.
-	env.Load(envFiles...)
-	fields, err := extractFields(prefix, cfg)
+	if err := loadEnvFiles(envFiles...); err != nil {
+		return err
+	}
+	return parse(prefix, cfg, EnvSource())
+}
+
+// ParseSources parses the config from the sources instead of the environment. The sources are listed
+// from the lowest to the highest precedence, a value found in a source overrides the values of the
+// sources before it. For example, to override the values of a config file with environment variables:
+//
+//	config.ParseSources("app", &cfg, config.FileSource("app", "config.yaml"), config.EnvSource())
+//
+// Unlike Parse, ParseSources does not load any .env file, use DotEnvSource for that.
+func ParseSources(prefix string, cfg any, sources ...Source) error {
+	return parse(prefix, cfg, sources...)
+}
+
+// ParseLayers is like ParseSources but resolves the fields through named layers, listed from the lowest
+// to the highest precedence, and reports the layer each value came from. Values set from the default
+// tag have the origin OriginDefault and values set from flags bound with BindFlags the origin
+// OriginFlags. For example:
+//
+//	origins, err := config.ParseLayers("app", &cfg,
+//		config.Layer{Name: "file", Source: config.FileSource("app", "config.yaml")},
+//		config.Layer{Name: "env", Source: config.EnvSource()},
+//		config.Layer{Name: "overrides", Source: overrides},
+//	)
+//	fmt.Println(origins["APP_DB_HOST"]) // "env"
+func ParseLayers(prefix string, cfg any, layers ...Layer) (Origins, error) {
+	layers, err := snapshotLayers(layers)
+	if err != nil {
+		return nil, err
+	}
+	return parseLayers(prefix, cfg, layers)
+}
+
+// MustParse parses the config and panics if an error occurs.
+// See Parse for more information. MustParse is a wrapper around Parse.
+func MustParse(prefix string, cfg any, envFiles ...string) {
+	if err := Parse(prefix, cfg, envFiles...); err != nil {
+		panic(err)
+	}
+}
+
+// parse assigns the values found in sources to the fields of cfg. The sources are ordered from the
+// lowest to the highest precedence. Flags bound to cfg with BindFlags take precedence over the sources.
+func parse(prefix string, cfg any, sources ...Source) error {
+	layers := make([]Layer, len(sources))
+	for i, source := range sources {
+		layers[i] = Layer{Source: source}
+	}
+	layers, err := snapshotLayers(layers)
 	if err != nil {
 		return err
 	}
+	_, err = parseLayers(prefix, cfg, layers)
+	return err
+}
 
-	for _, field := range fields {
-		value, ok := os.LookupEnv(field.EnvKey)
+// snapshotLayers returns a copy of layers where the sources implementing SnapshotSource are replaced
+// by their snapshot, taken once for the whole parse.
+func snapshotLayers(layers []Layer) ([]Layer, error) {
+	snapshots := slices.Clone(layers)
+	for i, layer := range snapshots {
+		s, ok := layer.Source.(SnapshotSource)
 		if !ok {
-			value, ok = os.LookupEnv(field.Key)
+			continue
 		}
+		source, err := s.Snapshot()
+		if err != nil {
+			return nil, err
+		}
+		snapshots[i].Source = source
+	}
+	return snapshots, nil
+}
+
+// parseLayers is like parse but resolves the fields through named layers and records the layer each
+// value came from.
+func parseLayers(prefix string, cfg any, layers []Layer) (Origins, error) {
+	fields, err := extractFields(prefix, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if source := flagSource(prefix, cfg); source != nil {
+		layers = append(layers[:len(layers):len(layers)], Layer{Name: OriginFlags, Source: source})
+	}
+
+	origins := make(Origins)
+	for _, field := range fields {
+		if isStructMap(field) {
+			if err := parseStructMap(field, layers, origins); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if isStructPtr(field) {
+			if err := parseStructPtr(field, layers, origins); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		value, layer, err := lookupField(field, layers)
+		if err != nil {
+			return nil, err
+		}
+		ok := layer >= 0
 
 		def := field.Default
 		if def != "" && !ok {
-			value = def
+			if value, err = resolveDefault(field); err != nil {
+				return nil, err
+			}
 		}
 
 		if !ok && field.Required && def == "" {
@@ -44,26 +154,352 @@ func Parse(prefix string, cfg any, envFiles ...string) error {
 			if field.EnvKey != "" {
 				key = field.EnvKey
 			}
-			return fmt.Errorf("config: required key %s missing value", key)
+			return nil, fmt.Errorf("config: required key %s missing value", key)
+		}
+		if !ok && def == "" {
+			// Nothing to assign, leave the field untouched.
+			continue
+		}
+		if isTrue(field.Tags.Get("expand")) {
+			if value, err = expandValue(value, layers); err != nil {
+				return nil, err
+			}
+		}
+		if isTrue(field.Tags.Get("fromFile")) {
+			if value, err = readValueFile(value); err != nil {
+				return nil, fmt.Errorf("config: reading the value of field %s: %w", field.Name, err)
+			}
+		}
+		value, err = normalizeValue(value, field.Tags)
+		if err == nil {
+			err = checkPattern(value, field.Tags)
+		}
+		decoded := value
+		if err == nil {
+			decoded, err = decodeValue(value, field.Tags)
+		}
+		if err == nil {
+			err = parseField(decoded, field.Field, field.Tags)
+		}
+		if err == nil {
+			err = validateField(decoded, field.Field, field.Tags)
 		}
-		err := parseField(value, field.Field)
 		if err != nil {
-			return &FieldError{
+			fieldErr := &FieldError{
 				fieldName:  field.Name,
 				fieldType:  field.Field.Type().String(),
 				fieldValue: value,
 				fieldErr:   err,
 			}
+			if field.Secret {
+				// The errors of the parsers and validators can quote the value in any form, keep both out.
+				fieldErr.fieldValue, fieldErr.fieldErr = redacted, errSecretValue
+			}
+			return nil, fieldErr
+		}
+
+		origins[field.Key] = OriginDefault
+		if ok {
+			origins[field.Key] = layers[layer].Name
+			if message := field.Tags.Get("deprecated"); message != "" {
+				key := field.Key
+				if field.EnvKey != "" {
+					key = field.EnvKey
+				}
+				warnDeprecated(key, message)
+			}
+		}
+		if isTrue(field.Tags.Get("unset")) {
+			var source Source
+			if ok {
+				source = layers[layer].Source
+			}
+			unsetField(field, source)
+		}
+
+		// Parse the fields of the implementation selected by an interface field, see Register.
+		if impl, ok := configurable(field.Field); ok {
+			implOrigins, err := parseLayers(field.Key, impl.Interface(), layers)
+			if err != nil {
+				return nil, err
+			}
+			for key, origin := range implOrigins {
+				origins[key] = origin
+			}
+		}
+	}
+	return origins, nil
+}
+
+// parseStructMap fills a field of type map[string]Struct, see isStructMap, from the keys of the layers
+// that are KeySources. A key made of the key of the field, a name and the key of a field of the struct,
+// like APP_ENDPOINTS_<NAME>_URL, adds the entry name, lower cased, to the map. The entries are parsed
+// like nested structs prefixed with the key of the field and their name. The field is left untouched
+// if there is no entry.
+func parseStructMap(field Field, layers []Layer, origins Origins) error {
+	t := field.Field.Type()
+	subFields, err := extractFields("", reflect.New(t.Elem()).Interface())
+	if err != nil {
+		return err
+	}
+
+	prefix := field.Key + "_"
+	names := make(map[string]bool)
+	for _, layer := range layers {
+		source, ok := layer.Source.(KeySource)
+		if !ok {
+			continue
+		}
+		keys, err := source.Keys()
+		if err != nil {
+			return fmt.Errorf("config: listing keys for %s: %w", field.Key, err)
+		}
+		for _, key := range keys {
+			rest, ok := strings.CutPrefix(key, prefix)
+			if !ok {
+				continue
+			}
+			for _, subField := range subFields {
+				if name, ok := strings.CutSuffix(rest, "_"+subField.Key); ok && name != "" {
+					names[name] = true
+				}
+			}
 		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
 
+	m := reflect.MakeMapWithSize(t, len(names))
+	for _, name := range sorted {
+		entry := reflect.New(t.Elem())
+		entryOrigins, err := parseLayers(prefix+name, entry.Interface(), layers)
+		if err != nil {
+			return err
+		}
+		for key, origin := range entryOrigins {
+			origins[key] = origin
+		}
+		m.SetMapIndex(reflect.ValueOf(strings.ToLower(name)).Convert(t.Key()), entry.Elem())
 	}
+	field.Field.Set(m)
 	return nil
 }
 
-// MustParse parses the config and panics if an error occurs.
-// See Parse for more information. MustParse is a wrapper around Parse.
-func MustParse(prefix string, cfg any, envFiles ...string) {
-	if err := Parse(prefix, cfg, envFiles...); err != nil {
-		panic(err)
+// parseStructPtr parses a pointer to a nested struct, see isStructPtr. The fields are prefixed with
+// the key of the field like the fields of a nested struct. A nil pointer is allocated only if a key of
+// the struct is set in the layers, otherwise it is left nil unless the field is required.
+func parseStructPtr(field Field, layers []Layer, origins Origins) error {
+	target := field.Field
+	if target.IsNil() {
+		ok, err := hasKeys(field.Key, target.Type().Elem(), layers, nil)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if field.Required {
+				return fmt.Errorf("config: required key %s missing value", field.Key)
+			}
+			return nil
+		}
+		target = reflect.New(target.Type().Elem())
+	}
+
+	subOrigins, err := parseLayers(field.Key, target.Interface(), layers)
+	if err != nil {
+		return err
+	}
+	for key, origin := range subOrigins {
+		origins[key] = origin
+	}
+	field.Field.Set(target)
+	return nil
+}
+
+// hasKeys reports whether a key of the fields of a struct of type t, prefixed with prefix, is set in
+// the layers. The keys of nested struct pointers are looked up too, except for the types in seen,
+// which stops the lookup of recursive types like a linked list.
+func hasKeys(prefix string, t reflect.Type, layers []Layer, seen map[reflect.Type]bool) (bool, error) {
+	if seen[t] {
+		return false, nil
+	}
+	if seen == nil {
+		seen = make(map[reflect.Type]bool)
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	fields, err := extractFields(prefix, reflect.New(t).Interface())
+	if err != nil {
+		return false, err
+	}
+
+	for _, field := range fields {
+		if isStructPtr(field) {
+			if ok, err := hasKeys(field.Key, field.Field.Type().Elem(), layers, seen); ok || err != nil {
+				return ok, err
+			}
+			continue
+		}
+		if _, layer, err := lookupField(field, layers); layer >= 0 || err != nil {
+			return layer >= 0, err
+		}
+	}
+	return false, nil
+}
+
+// lookupField looks up the value of a field in the layers, starting with the layer with the highest
+// precedence. The alternate env key is tried before the prefixed key. It returns the index of the
+// layer the value was found in or -1 if it was not found.
+func lookupField(field Field, layers []Layer) (string, int, error) {
+	keys := append(append([]string{field.EnvKey}, field.EnvAliases...), field.Key)
+	for i := len(layers) - 1; i >= 0; i-- {
+		for _, key := range keys {
+			if key == "" {
+				continue
+			}
+			var (
+				value string
+				ok    bool
+				err   error
+			)
+			if fs, isField := layers[i].Source.(FieldSource); isField {
+				value, ok, err = fs.LookupField(field, key)
+			} else {
+				value, ok, err = layers[i].Source.Lookup(key)
+			}
+			if err != nil {
+				return "", -1, fmt.Errorf("config: looking up %s: %w", key, err)
+			}
+			if !ok {
+				continue
+			}
+			if ls, isList := layers[i].Source.(listSource); isList {
+				items, isList, err := ls.lookupList(key)
+				if err != nil {
+					return "", -1, fmt.Errorf("config: looking up %s: %w", key, err)
+				}
+				if isList {
+					value = joinList(slices.Clone(items), fieldSeparator(field))
+				}
+			}
+			return value, i, nil
+		}
+	}
+	return "", -1, nil
+}
+
+// fieldSeparator returns the separator of the items of the lists assigned to field: the separator of
+// the pairs for a map, the separator of the items for any other type.
+func fieldSeparator(field Field) string {
+	t := field.Field.Type()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Map {
+		return pairSeparator(field.Tags)
+	}
+	return listSeparator(field.Tags)
+}
+
+// expandValue replaces the $NAME and ${NAME} references in the value of a field tagged with expand:"true"
+// by the values of the keys NAME, looked up in the layers like the fields and then in the environment.
+// The references to keys that are not set are replaced by an empty string.
+func expandValue(value string, layers []Layer) (string, error) {
+	var err error
+	expanded := os.Expand(value, func(name string) string {
+		for i := len(layers) - 1; i >= 0 && err == nil; i-- {
+			v, ok, lookupErr := layers[i].Source.Lookup(name)
+			if lookupErr != nil {
+				err = fmt.Errorf("config: looking up %s: %w", name, lookupErr)
+			}
+			if ok {
+				return v
+			}
+		}
+		return os.Getenv(name)
+	})
+	return expanded, err
+}
+
+// normalizeValue applies the comma separated operations of the normalize tag to the value of a field,
+// in order: trim removes the leading and trailing white space, lower and upper change the case.
+func normalizeValue(value string, tags reflect.StructTag) (string, error) {
+	ops := tags.Get("normalize")
+	if ops == "" {
+		return value, nil
+	}
+	for _, op := range strings.Split(ops, ",") {
+		switch strings.TrimSpace(op) {
+		case "trim":
+			value = strings.TrimSpace(value)
+		case "lower":
+			value = strings.ToLower(value)
+		case "upper":
+			value = strings.ToUpper(value)
+		default:
+			return "", fmt.Errorf("unknown normalize operation %q, expected trim, lower or upper", op)
+		}
+	}
+	return value, nil
+}
+
+// defaultReference matches the references to the fields of the same struct in a default value, like
+// {.Host} in {.Host}:8080.
+var defaultReference = regexp.MustCompile(`\{\.([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// resolveDefault returns the default value of field with the references to the fields of the same
+// struct, like {.Host}, replaced by their values formatted the way they are parsed. The fields are
+// parsed in order, so a default can only reference the fields declared before it.
+func resolveDefault(field Field) (string, error) {
+	var err error
+	value := defaultReference.ReplaceAllStringFunc(field.Default, func(ref string) string {
+		name := defaultReference.FindStringSubmatch(ref)[1]
+		sf, ok := field.parent.Type().FieldByName(name)
+		if !ok || !sf.IsExported() {
+			if err == nil {
+				err = fmt.Errorf("config: the default of field %s references an unknown field %s", field.Name, name)
+			}
+			return ref
+		}
+		s, formatErr := formatField(field.parent.FieldByIndex(sf.Index), fieldTags(sf))
+		if formatErr != nil && err == nil {
+			err = fmt.Errorf("config: the default of field %s references field %s: %w", field.Name, name, formatErr)
+		}
+		return s
+	})
+	return value, err
+}
+
+// unsetField removes the environment variables of a field tagged with unset:"true" once it is set, so
+// that secrets are not inherited by child processes. When source is a FileRefSource that read the value
+// from a file, the *_FILE variable naming the file is removed too. The environment the process was
+// started with, as shown in /proc/<pid>/environ, is not changed.
+func unsetField(field Field, source Source) {
+	for _, key := range append([]string{field.EnvKey, field.Key}, field.EnvAliases...) {
+		if key == "" {
+			continue
+		}
+		if fs, ok := source.(fileRefSource); ok && fs.fromFile(key) {
+			os.Unsetenv(key + "_FILE")
+		}
+		os.Unsetenv(key)
+	}
+}
+
+// readValueFile returns the content of the file at path, without trailing newlines, for a field tagged
+// with fromFile:"true" whose value is the path of the file holding the actual value, like a Docker or
+// Kubernetes secret mounted in the container.
+func readValueFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
 	}
+	return strings.TrimRight(string(data), "\r\n"), nil
 }
@@ -1,7 +1,13 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -114,6 +120,45 @@ func TestAlternateWithoutPrefix(t *testing.T) {
 	}
 }
 
+func TestAlternateEnvAliases(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("LEGACY_DB_HOST", "legacy.example.com")
+	os.Setenv("OLD_DB_PORT", "5433")
+	os.Setenv("LEGACY_DB_PORT", "5434")
+
+	spec := struct {
+		Host string `env:"db_host,old_db_host,legacy_db_host"`
+		Port int    `env:"db_port,old_db_port,legacy_db_port"`
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Host != "legacy.example.com" {
+		t.Fatalf("expected host to be legacy.example.com, got %s", spec.Host)
+	}
+	if spec.Port != 5433 {
+		t.Fatalf("expected port to be 5433, got %d", spec.Port)
+	}
+
+	os.Setenv("DB_HOST", "db.example.com")
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+	if spec.Host != "db.example.com" {
+		t.Fatalf("expected host to be db.example.com, got %s", spec.Host)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["DB_HOST"] != "db.example.com" || values["DB_PORT"] != "5433" {
+		t.Fatalf("expected the values to be keyed by the first name, got %v", values)
+	}
+}
+
 func TestDefault(t *testing.T) {
 	os.Clearenv()
 	spec := struct {
@@ -128,6 +173,49 @@ func TestDefault(t *testing.T) {
 	}
 }
 
+func TestDefaultReference(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_HOST", "10.0.0.5")
+	os.Setenv("APP_DB_HOST", "db.internal")
+
+	spec := struct {
+		Host      string `default:"0.0.0.0"`
+		Port      int    `default:"8080"`
+		Advertise string `default:"{.Host}:{.Port}"`
+		DB        struct {
+			Host string
+			DSN  string `default:"postgres://{.Host}/app"`
+		}
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Advertise != "10.0.0.5:8080" {
+		t.Fatalf("expected advertise to be 10.0.0.5:8080, got %s", spec.Advertise)
+	}
+	if spec.DB.DSN != "postgres://db.internal/app" {
+		t.Fatalf("expected dsn to be postgres://db.internal/app, got %s", spec.DB.DSN)
+	}
+
+	os.Setenv("APP_ADVERTISE", "app.example.com:443")
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+	if spec.Advertise != "app.example.com:443" {
+		t.Fatalf("expected advertise to be app.example.com:443, got %s", spec.Advertise)
+	}
+
+	os.Clearenv()
+	invalid := struct {
+		Advertise string `default:"{.Hostname}:8080"`
+	}{}
+	if err := Parse("app", &invalid); err == nil {
+		t.Fatal("expected an error for a reference to an unknown field, got nil")
+	}
+}
+
 func TestRequired(t *testing.T) {
 
 	spec := struct {
@@ -149,6 +237,42 @@ func TestParseDuration(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+func TestParseDurationSlice(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_BACKOFF", "1s, 5s,30s")
+
+	spec := struct {
+		Backoff []time.Duration
+		Retries []time.Duration `default:"100ms,1s"`
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(spec.Backoff, []time.Duration{time.Second, 5 * time.Second, 30 * time.Second}) {
+		t.Fatalf("expected backoff to be 1s, 5s and 30s, got %v", spec.Backoff)
+	}
+	if !reflect.DeepEqual(spec.Retries, []time.Duration{100 * time.Millisecond, time.Second}) {
+		t.Fatalf("expected retries to be 100ms and 1s, got %v", spec.Retries)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_BACKOFF"] != "1s,5s,30s" {
+		t.Fatalf("expected backoff to be marshaled to 1s,5s,30s, got %s", values["APP_BACKOFF"])
+	}
+
+	os.Setenv("APP_BACKOFF", "1s,5")
+	err = Parse("app", &spec)
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || !strings.Contains(err.Error(), "item 1") {
+		t.Fatalf("expected a FieldError for item 1, got %v", err)
+	}
+}
+
 func TestNestedStruct(t *testing.T) {
 
 	spec := struct {
@@ -206,3 +330,1029 @@ func TestMustParse(t *testing.T) {
 	MustParse("app", m)
 
 }
+
+func TestParseSlice(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_HOSTS", "a.example.com, b.example.com,c.example.com")
+	os.Setenv("APP_PORTS", "80;443")
+	os.Setenv("APP_RATIOS", "0.5,1.5")
+	os.Setenv("APP_TIMEOUTS", "1s,2m")
+	os.Setenv("APP_TAGS", `a\,b,c\\d,e\f`)
+	os.Setenv("APP_KEY", "secret")
+	os.Setenv("APP_EMPTY", "")
+	os.Setenv("APP_DSNS", "postgres://db1/app?sslmode=require&application_name=a,b|postgres://db2/app")
+
+	spec := struct {
+		Hosts    []string
+		Ports    []int    `sep:";"`
+		DSNs     []string `delim:"|"`
+		Ratios   []float64
+		Timeouts []time.Duration
+		Tags     []string
+		Key      []byte
+		Empty    []string
+		Default  []string `default:"x,y"`
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(spec.Hosts, []string{"a.example.com", "b.example.com", "c.example.com"}) {
+		t.Fatalf("expected hosts to be a, b and c.example.com, got %v", spec.Hosts)
+	}
+	if !reflect.DeepEqual(spec.Ports, []int{80, 443}) {
+		t.Fatalf("expected ports to be 80 and 443, got %v", spec.Ports)
+	}
+	if !reflect.DeepEqual(spec.DSNs, []string{"postgres://db1/app?sslmode=require&application_name=a,b", "postgres://db2/app"}) {
+		t.Fatalf("expected dsns to be split around |, got %q", spec.DSNs)
+	}
+	if !reflect.DeepEqual(spec.Ratios, []float64{0.5, 1.5}) {
+		t.Fatalf("expected ratios to be 0.5 and 1.5, got %v", spec.Ratios)
+	}
+	if !reflect.DeepEqual(spec.Timeouts, []time.Duration{time.Second, 2 * time.Minute}) {
+		t.Fatalf("expected timeouts to be 1s and 2m, got %v", spec.Timeouts)
+	}
+	if !reflect.DeepEqual(spec.Tags, []string{"a,b", `c\d`, `e\f`}) {
+		t.Fatalf(`expected tags to be "a,b", "c\d" and "e\f", got %q`, spec.Tags)
+	}
+	if string(spec.Key) != "secret" {
+		t.Fatalf("expected key to be secret, got %s", spec.Key)
+	}
+	if spec.Empty == nil || len(spec.Empty) != 0 {
+		t.Fatalf("expected empty to be an empty slice, got %#v", spec.Empty)
+	}
+	if !reflect.DeepEqual(spec.Default, []string{"x", "y"}) {
+		t.Fatalf("expected default to be x and y, got %v", spec.Default)
+	}
+
+	os.Setenv("APP_PORTS", "80;http")
+	err := Parse("app", &spec)
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) {
+		t.Fatalf("expected a FieldError, got %v", err)
+	}
+}
+
+func TestParseMap(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_LABELS", "team:core, env:prod,url:http://example.com")
+	os.Setenv("APP_LIMITS", "cpu=2;memory=512")
+	os.Setenv("APP_WEIGHTS", "1:0.5,2:1.5")
+	os.Setenv("APP_PARAMS", "a=1,2;b=3")
+	os.Setenv("APP_EMPTY", "")
+
+	spec := struct {
+		Labels  map[string]string
+		Limits  map[string]int    `sep:";" kvsep:"="`
+		Params  map[string]string `pairsep:";" kvsep:"="`
+		Weights map[int]float64
+		Empty   map[string]string
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(spec.Labels, map[string]string{"team": "core", "env": "prod", "url": "http://example.com"}) {
+		t.Fatalf("expected labels to be team:core, env:prod and url:http://example.com, got %v", spec.Labels)
+	}
+	if !reflect.DeepEqual(spec.Limits, map[string]int{"cpu": 2, "memory": 512}) {
+		t.Fatalf("expected limits to be cpu=2 and memory=512, got %v", spec.Limits)
+	}
+	if !reflect.DeepEqual(spec.Params, map[string]string{"a": "1,2", "b": "3"}) {
+		t.Fatalf("expected params to be a=1,2 and b=3, got %v", spec.Params)
+	}
+	if !reflect.DeepEqual(spec.Weights, map[int]float64{1: 0.5, 2: 1.5}) {
+		t.Fatalf("expected weights to be 1:0.5 and 2:1.5, got %v", spec.Weights)
+	}
+	if spec.Empty == nil || len(spec.Empty) != 0 {
+		t.Fatalf("expected empty to be an empty map, got %#v", spec.Empty)
+	}
+
+	tests := []struct {
+		description string
+		value       string
+	}{
+		{description: "missing separator", value: "cpu=2;memory"},
+		{description: "invalid value", value: "cpu=two"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			os.Setenv("APP_LIMITS", tc.value)
+			var fieldErr *FieldError
+			if err := Parse("app", &spec); !errors.As(err, &fieldErr) {
+				t.Fatalf("expected a FieldError, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseJSONFormat(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_UPSTREAMS", `[{"host": "a.example.com", "port": 80}, {"host": "b.example.com", "port": 8080}]`)
+	os.Setenv("APP_TLS", `{"cert": "/etc/tls/cert.pem", "key": "/etc/tls/key.pem"}`)
+	os.Setenv("APP_LIMITS", `{"cpu": 2, "memory": 512}`)
+	os.Setenv("APP_TLS_CERT", "ignored.pem")
+
+	type upstream struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+	spec := struct {
+		Upstreams []upstream `format:"json"`
+		TLS       struct {
+			Cert string `json:"cert"`
+			Key  string `json:"key"`
+		} `format:"json"`
+		Limits map[string]int `format:"json"`
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(spec.Upstreams, []upstream{{"a.example.com", 80}, {"b.example.com", 8080}}) {
+		t.Fatalf("expected upstreams to be a.example.com:80 and b.example.com:8080, got %v", spec.Upstreams)
+	}
+	if spec.TLS.Cert != "/etc/tls/cert.pem" || spec.TLS.Key != "/etc/tls/key.pem" {
+		t.Fatalf("expected tls to be set from APP_TLS, got %+v", spec.TLS)
+	}
+	if !reflect.DeepEqual(spec.Limits, map[string]int{"cpu": 2, "memory": 512}) {
+		t.Fatalf("expected limits to be cpu 2 and memory 512, got %v", spec.Limits)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_LIMITS"] != `{"cpu":2,"memory":512}` {
+		t.Fatalf(`expected limits to be marshaled to {"cpu":2,"memory":512}, got %s`, values["APP_LIMITS"])
+	}
+
+	os.Setenv("APP_LIMITS", `{"cpu": "two"}`)
+	var fieldErr *FieldError
+	if err := Parse("app", &spec); !errors.As(err, &fieldErr) {
+		t.Fatalf("expected a FieldError, got %v", err)
+	}
+}
+
+func TestParsePointer(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_PORT", "0")
+	os.Setenv("APP_DEBUG", "false")
+	os.Setenv("APP_HOSTS", "a.example.com,b.example.com")
+	os.Setenv("APP_TIMEOUT", "5s")
+	os.Setenv("APP_LEVEL", "debug")
+
+	spec := struct {
+		Port    *int
+		Debug   *bool
+		Hosts   *[]string
+		Timeout *time.Duration
+		Level   *level
+		Name    *string
+		Retries *int `default:"3"`
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Port == nil || *spec.Port != 0 {
+		t.Fatalf("expected port to be set to 0, got %v", spec.Port)
+	}
+	if spec.Debug == nil || *spec.Debug {
+		t.Fatalf("expected debug to be set to false, got %v", spec.Debug)
+	}
+	if spec.Hosts == nil || len(*spec.Hosts) != 2 {
+		t.Fatalf("expected hosts to be a.example.com and b.example.com, got %v", spec.Hosts)
+	}
+	if spec.Timeout == nil || *spec.Timeout != 5*time.Second {
+		t.Fatalf("expected timeout to be 5s, got %v", spec.Timeout)
+	}
+	if spec.Level == nil || *spec.Level != 0 {
+		t.Fatalf("expected level to be debug, got %v", spec.Level)
+	}
+	if spec.Name != nil {
+		t.Fatalf("expected name to be nil, got %s", *spec.Name)
+	}
+	if spec.Retries == nil || *spec.Retries != 3 {
+		t.Fatalf("expected retries to be 3, got %v", spec.Retries)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_PORT"] != "0" || values["APP_NAME"] != "" || values["APP_LEVEL"] != "debug" {
+		t.Fatalf("expected the pointers to be marshaled, got %v", values)
+	}
+
+	os.Setenv("APP_PORT", "http")
+	var fieldErr *FieldError
+	if err := Parse("app", &spec); !errors.As(err, &fieldErr) {
+		t.Fatalf("expected a FieldError, got %v", err)
+	}
+}
+
+func TestParseUint(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_WORKERS", "8")
+	os.Setenv("APP_TTL", "255")
+	os.Setenv("APP_PORT", "8080")
+	os.Setenv("APP_MASK", "0x1f")
+	os.Setenv("APP_SIZE", "18446744073709551615")
+
+	spec := struct {
+		Workers uint
+		TTL     uint8
+		Port    uint16
+		Mask    uint32
+		Size    uint64
+		Addr    uintptr
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Workers != 8 || spec.TTL != 255 || spec.Port != 8080 || spec.Mask != 0x1f || spec.Size != 1<<64-1 {
+		t.Fatalf("expected the unsigned integers to be set, got %+v", spec)
+	}
+
+	tests := []struct {
+		description string
+		key         string
+		value       string
+	}{
+		{description: "overflow", key: "APP_TTL", value: "256"},
+		{description: "negative", key: "APP_PORT", value: "-1"},
+		{description: "not a number", key: "APP_WORKERS", value: "eight"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			os.Clearenv()
+			os.Setenv(tc.key, tc.value)
+			var fieldErr *FieldError
+			if err := Parse("app", &spec); !errors.As(err, &fieldErr) {
+				t.Fatalf("expected a FieldError, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseComplex(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_GAIN", "1+2i")
+	os.Setenv("APP_COEFFICIENTS", "(0.5-1i),2,-3i")
+
+	spec := struct {
+		Gain         complex64
+		Coefficients []complex128
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Gain != 1+2i {
+		t.Fatalf("expected gain to be (1+2i), got %v", spec.Gain)
+	}
+	if len(spec.Coefficients) != 3 || spec.Coefficients[0] != 0.5-1i || spec.Coefficients[2] != -3i {
+		t.Fatalf("expected coefficients to be [(0.5-1i) (2+0i) (0-3i)], got %v", spec.Coefficients)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_GAIN"] != "(1+2i)" {
+		t.Fatalf("expected gain to be marshaled to (1+2i), got %s", values["APP_GAIN"])
+	}
+
+	os.Setenv("APP_GAIN", "1+")
+	err = Parse("app", &spec)
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) {
+		t.Fatalf("expected a FieldError for the invalid complex number, got %v", err)
+	}
+}
+
+func TestParseUnsupportedType(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_VALUE", "1")
+
+	spec := struct {
+		Value chan int
+	}{}
+
+	err := Parse("app", &spec)
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || !strings.Contains(err.Error(), "unsupported type chan int") {
+		t.Fatalf("expected a FieldError for the unsupported type, got %v", err)
+	}
+}
+
+func TestParseStructMap(t *testing.T) {
+	type endpoint struct {
+		URL     string        `required:"true"`
+		Timeout time.Duration `default:"5s"`
+		TLS     struct {
+			Insecure bool
+		}
+	}
+	type gateway struct {
+		Endpoints map[string]endpoint
+		Empty     map[string]endpoint
+	}
+
+	os.Clearenv()
+	os.Setenv("APP_ENDPOINTS_USERS_URL", "http://users")
+	os.Setenv("APP_ENDPOINTS_USERS_TIMEOUT", "1s")
+	os.Setenv("APP_ENDPOINTS_BILLING_API_URL", "http://billing")
+	os.Setenv("APP_ENDPOINTS_BILLING_API_TLS_INSECURE", "true")
+	os.Setenv("APP_ENDPOINTS_UNKNOWN", "ignored")
+
+	var cfg gateway
+	err := ParseSources("app", &cfg,
+		FileRefSource(EnvSource()),
+		MapSource{"APP_ENDPOINTS_ORDERS_URL": "http://orders"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]endpoint{
+		"users":       {URL: "http://users", Timeout: time.Second},
+		"billing_api": {URL: "http://billing", Timeout: 5 * time.Second},
+		"orders":      {URL: "http://orders", Timeout: 5 * time.Second},
+	}
+	billing := expected["billing_api"]
+	billing.TLS.Insecure = true
+	expected["billing_api"] = billing
+	if !reflect.DeepEqual(cfg.Endpoints, expected) {
+		t.Fatalf("expected the endpoints to be %+v, got %+v", expected, cfg.Endpoints)
+	}
+	if cfg.Empty != nil {
+		t.Fatalf("expected empty to be nil, got %+v", cfg.Empty)
+	}
+
+	values, err := Marshal("app", &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_ENDPOINTS_BILLING_API_TLS_INSECURE"] != "true" || values["APP_ENDPOINTS_ORDERS_TIMEOUT"] != "5s" {
+		t.Fatalf("expected the endpoints to be marshaled, got %v", values)
+	}
+
+	// An entry misses its required URL.
+	os.Setenv("APP_ENDPOINTS_ADMIN_TIMEOUT", "1s")
+	if err := Parse("app", &cfg); err == nil || !strings.Contains(err.Error(), "APP_ENDPOINTS_ADMIN_URL") {
+		t.Fatalf("expected the required key APP_ENDPOINTS_ADMIN_URL to be missing, got %v", err)
+	}
+}
+
+func TestParseArray(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_REPLICAS", "a.example.com, b.example.com, c.example.com")
+	os.Setenv("APP_WEIGHTS", "1;2")
+
+	spec := struct {
+		Replicas [3]string
+		Weights  [2]int `sep:";"`
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Replicas != [3]string{"a.example.com", "b.example.com", "c.example.com"} {
+		t.Fatalf("expected replicas to be a, b and c.example.com, got %v", spec.Replicas)
+	}
+	if spec.Weights != [2]int{1, 2} {
+		t.Fatalf("expected weights to be 1 and 2, got %v", spec.Weights)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_WEIGHTS"] != "1;2" {
+		t.Fatalf("expected weights to be marshaled to 1;2, got %s", values["APP_WEIGHTS"])
+	}
+
+	tests := []struct {
+		description string
+		key         string
+		value       string
+	}{
+		{description: "too few items", key: "APP_REPLICAS", value: "a.example.com,b.example.com"},
+		{description: "too many items", key: "APP_WEIGHTS", value: "1;2;3"},
+		{description: "empty", key: "APP_REPLICAS", value: ""},
+		{description: "invalid item", key: "APP_WEIGHTS", value: "1;two"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			os.Clearenv()
+			os.Setenv(tc.key, tc.value)
+			var fieldErr *FieldError
+			if err := Parse("app", &spec); !errors.As(err, &fieldErr) {
+				t.Fatalf("expected a FieldError, got %v", err)
+			}
+		})
+	}
+}
+
+type HTTPConfig struct {
+	Addr    string `default:":8080"`
+	Timeout time.Duration
+}
+
+type logConfig struct {
+	Level string
+}
+
+func TestEmbeddedStruct(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_ADDR", ":9090")
+	os.Setenv("APP_LEVEL", "debug")
+	os.Setenv("APP_ADMIN_HTTPCONFIG_ADDR", ":9091")
+	os.Setenv("APP_DB_HOST", "db.example.com")
+
+	type admin struct {
+		HTTPConfig `prefix:"true"`
+	}
+	spec := struct {
+		HTTPConfig
+		logConfig
+		Admin admin
+		DB    struct {
+			Host string
+		}
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Addr != ":9090" {
+		t.Fatalf("expected addr to be :9090, got %s", spec.Addr)
+	}
+	if spec.Level != "debug" {
+		t.Fatalf("expected level to be debug, got %s", spec.Level)
+	}
+	if spec.Admin.Addr != ":9091" {
+		t.Fatalf("expected admin addr to be :9091, got %s", spec.Admin.Addr)
+	}
+	if spec.DB.Host != "db.example.com" {
+		t.Fatalf("expected db host to be db.example.com, got %s", spec.DB.Host)
+	}
+}
+
+func TestNestedStructPointer(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_DB_HOST", "db.example.com")
+
+	type server struct {
+		Port int
+	}
+	type database struct {
+		Host    string `required:"true"`
+		Port    int    `default:"5432"`
+		Primary *server
+	}
+	spec := struct {
+		DB    *database
+		Cache *database
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.DB == nil || spec.DB.Host != "db.example.com" || spec.DB.Port != 5432 {
+		t.Fatalf("expected db to be allocated with host db.example.com and port 5432, got %+v", spec.DB)
+	}
+	if spec.DB.Primary != nil {
+		t.Fatalf("expected db primary to be nil, got %+v", spec.DB.Primary)
+	}
+	if spec.Cache != nil {
+		t.Fatalf("expected cache to be nil, got %+v", spec.Cache)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_DB_HOST"] != "db.example.com" || len(values) != 2 {
+		t.Fatalf("expected only the db fields to be marshaled, got %v", values)
+	}
+
+	// A key of a nested struct allocates the pointers leading to it.
+	os.Setenv("APP_CACHE_PRIMARY_PORT", "6379")
+	err = Parse("app", &spec)
+	if err == nil || !strings.Contains(err.Error(), "required key APP_CACHE_HOST missing value") {
+		t.Fatalf("expected the cache to be allocated and its host required, got %v", err)
+	}
+}
+
+func TestPrefixTag(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_PG_HOST", "pg.example.com")
+	os.Setenv("APP_REPLICA_HOST", "replica.example.com")
+	os.Setenv("APP_TIMEOUT", "5s")
+	os.Setenv("APP_WEB_ADDR", ":9090")
+	os.Setenv("APP_CACHES_SESSIONS_HOST", "sessions.example.com")
+
+	type database struct {
+		Host string
+	}
+	spec := struct {
+		HTTPConfig `prefix:"WEB"`
+		Database   database  `prefix:"PG"`
+		Standby    *database `prefix:"REPLICA"`
+		Limits     struct {
+			Timeout time.Duration
+		} `prefix:"false"`
+		Redis map[string]database `prefix:"CACHES"`
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Database.Host != "pg.example.com" {
+		t.Fatalf("expected database host to be pg.example.com, got %s", spec.Database.Host)
+	}
+	if spec.Standby == nil || spec.Standby.Host != "replica.example.com" {
+		t.Fatalf("expected standby host to be replica.example.com, got %+v", spec.Standby)
+	}
+	if spec.Limits.Timeout != 5*time.Second {
+		t.Fatalf("expected limits timeout to be 5s, got %s", spec.Limits.Timeout)
+	}
+	if spec.Addr != ":9090" {
+		t.Fatalf("expected addr to be :9090, got %s", spec.Addr)
+	}
+	if spec.Redis["sessions"].Host != "sessions.example.com" {
+		t.Fatalf("expected the sessions cache host to be sessions.example.com, got %v", spec.Redis)
+	}
+}
+
+func TestNoPrefixTag(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("AWS_REGION", "eu-west-1")
+	os.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+	os.Setenv("APP_AWS_REGION", "us-east-1")
+
+	spec := struct {
+		AWSRegion string `env:"aws_region" noprefix:"true"`
+		Cluster   struct {
+			KubernetesServiceHost string `env:"kubernetes_service_host" noprefix:"true"`
+		}
+		Home string `noprefix:"true" default:"/root"`
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.AWSRegion != "eu-west-1" {
+		t.Fatalf("expected aws region to be eu-west-1, got %s", spec.AWSRegion)
+	}
+	if spec.Cluster.KubernetesServiceHost != "10.0.0.1" {
+		t.Fatalf("expected kubernetes service host to be 10.0.0.1, got %s", spec.Cluster.KubernetesServiceHost)
+	}
+	if spec.Home != "/root" {
+		t.Fatalf("expected home to be /root, got %s", spec.Home)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["HOME"] != "/root" || values["AWS_REGION"] != "eu-west-1" {
+		t.Fatalf("expected the values to be keyed without prefix, got %v", values)
+	}
+}
+
+func TestExpandTag(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("HOME", "/home/app")
+	os.Setenv("APP_PASSWORD", "pa$$word")
+
+	spec := struct {
+		DataDir  string `env:"data_dir"`
+		CacheDir string `expand:"true"`
+		LogDir   string `expand:"true" default:"${HOME}/logs"`
+		Password string
+	}{}
+
+	source := MapSource{"DATA_DIR": "/var/lib/app", "APP_CACHEDIR": "${DATA_DIR}/cache"}
+	if err := ParseSources("app", &spec, EnvSource(), source); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.CacheDir != "/var/lib/app/cache" {
+		t.Fatalf("expected cache dir to be /var/lib/app/cache, got %s", spec.CacheDir)
+	}
+	if spec.LogDir != "/home/app/logs" {
+		t.Fatalf("expected log dir to be /home/app/logs, got %s", spec.LogDir)
+	}
+	if spec.Password != "pa$$word" {
+		t.Fatalf("expected password not to be expanded, got %s", spec.Password)
+	}
+}
+
+func TestNormalizeTag(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_TOKEN", " s3cr3t \n")
+	os.Setenv("APP_ENV", " Production")
+	os.Setenv("APP_REGION", "eu-west-1")
+	os.Setenv("APP_PORT", " 8080 ")
+
+	spec := struct {
+		Token  string `normalize:"trim"`
+		Env    string `normalize:"trim,lower" oneof:"development production"`
+		Region string `normalize:"upper"`
+		Port   int    `normalize:"trim"`
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Token != "s3cr3t" {
+		t.Fatalf("expected token to be s3cr3t, got %q", spec.Token)
+	}
+	if spec.Env != "production" {
+		t.Fatalf("expected env to be production, got %q", spec.Env)
+	}
+	if spec.Region != "EU-WEST-1" {
+		t.Fatalf("expected region to be EU-WEST-1, got %q", spec.Region)
+	}
+	if spec.Port != 8080 {
+		t.Fatalf("expected port to be 8080, got %d", spec.Port)
+	}
+
+	invalid := struct {
+		Token string `normalize:"title"`
+	}{}
+	err := Parse("app", &invalid)
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) {
+		t.Fatalf("expected a FieldError, got %v", err)
+	}
+}
+
+func TestUnsetTag(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_TOKEN", "s3cr3t")
+	os.Setenv("DB_PASSWORD", "hunter2")
+	os.Setenv("APP_USER", "admin")
+
+	spec := struct {
+		Token    string `unset:"true"`
+		Password string `env:"db_password" unset:"true"`
+		User     string
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Token != "s3cr3t" || spec.Password != "hunter2" {
+		t.Fatalf("expected token and password to be read, got %s and %s", spec.Token, spec.Password)
+	}
+	for _, key := range []string{"APP_TOKEN", "DB_PASSWORD"} {
+		if _, ok := os.LookupEnv(key); ok {
+			t.Fatalf("expected %s to be unset", key)
+		}
+	}
+	if os.Getenv("APP_USER") != "admin" {
+		t.Fatalf("expected APP_USER to be kept, got %s", os.Getenv("APP_USER"))
+	}
+
+	// The *_FILE variable a value was read from is unset too.
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("APP_TOKEN_FILE", path)
+	if err := ParseSources("app", &spec, FileRefSource(EnvSource())); err != nil {
+		t.Fatal(err)
+	}
+	if spec.Token != "from-file" {
+		t.Fatalf("expected token to be from-file, got %s", spec.Token)
+	}
+	if _, ok := os.LookupEnv("APP_TOKEN_FILE"); ok {
+		t.Fatal("expected APP_TOKEN_FILE to be unset")
+	}
+
+	// A *_FILE variable is kept when the value is not read from it, it can be the key of another field.
+	os.Clearenv()
+	os.Setenv("APP_PASSWORD", "hunter2")
+	os.Setenv("APP_PASSWORD_FILE", path)
+	fileSpec := struct {
+		Password     string `unset:"true"`
+		PasswordFile string `split_words:"true"`
+	}{}
+	if err := ParseSources("app", &fileSpec, FileRefSource(EnvSource())); err != nil {
+		t.Fatal(err)
+	}
+	if fileSpec.Password != "hunter2" || fileSpec.PasswordFile != path {
+		t.Fatalf("expected password and password file to be read, got %s and %s", fileSpec.Password, fileSpec.PasswordFile)
+	}
+	if _, ok := os.LookupEnv("APP_PASSWORD"); ok {
+		t.Fatal("expected APP_PASSWORD to be unset")
+	}
+	if os.Getenv("APP_PASSWORD_FILE") != path {
+		t.Fatalf("expected APP_PASSWORD_FILE to be kept, got %s", os.Getenv("APP_PASSWORD_FILE"))
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		description string
+		name        string
+		expected    string
+	}{
+		{description: "single word", name: "Port", expected: "Port"},
+		{description: "camel case", name: "MaxIdleConns", expected: "Max_Idle_Conns"},
+		{description: "leading acronym", name: "APIKey", expected: "API_Key"},
+		{description: "acronym in the middle", name: "UseHTTPProxy", expected: "Use_HTTP_Proxy"},
+		{description: "trailing acronym", name: "ServerURL", expected: "Server_URL"},
+		{description: "digits", name: "S3Bucket", expected: "S3_Bucket"},
+		{description: "acronym with digits", name: "HTTP2Enabled", expected: "HTTP2_Enabled"},
+		{description: "mixed case acronym", name: "OAuthClientID", expected: "OAuth_Client_ID"},
+		{description: "mixed case acronym with digits", name: "BindIPv6Addr", expected: "Bind_IPv6_Addr"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			if words := strings.Join(splitWords(tt.name), "_"); words != tt.expected {
+				t.Fatalf("expected %s to be split into %s, got %s", tt.name, tt.expected, words)
+			}
+		})
+	}
+}
+
+func TestSplitWordsTag(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_API_KEY", "key")
+	os.Setenv("APP_HTTP_PORT", "8080")
+	os.Setenv("APP_OAUTH_CLIENT_ID", "client")
+	os.Setenv("APP_REDIS_CACHE_MAX_SIZE", "64")
+	os.Setenv("APP_SERVERURL", "http://localhost")
+
+	spec := struct {
+		APIKey   string `split_words:"true"`
+		HTTPPort int    `config:"split_words"`
+		OAuth    struct {
+			ClientID string `split_words:"true"`
+		} `split_words:"true"`
+		RedisCache struct {
+			MaxSize int `split_words:"true"`
+		} `split_words:"true"`
+		ServerURL string
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.APIKey != "key" {
+		t.Fatalf("expected api key to be key, got %s", spec.APIKey)
+	}
+	if spec.HTTPPort != 8080 {
+		t.Fatalf("expected http port to be 8080, got %d", spec.HTTPPort)
+	}
+	if spec.OAuth.ClientID != "client" {
+		t.Fatalf("expected client id to be client, got %s", spec.OAuth.ClientID)
+	}
+	if spec.RedisCache.MaxSize != 64 {
+		t.Fatalf("expected max size to be 64, got %d", spec.RedisCache.MaxSize)
+	}
+	if spec.ServerURL != "http://localhost" {
+		t.Fatalf("expected server url to be http://localhost, got %s", spec.ServerURL)
+	}
+}
+
+func TestNameTag(t *testing.T) {
+	SetNameTag("json")
+	defer SetNameTag("")
+
+	os.Clearenv()
+	os.Setenv("APP_LISTEN_ADDR", ":8080")
+	os.Setenv("APP_APIKEY", "key")
+	os.Setenv("APP_MAX_CONNS", "10")
+	os.Setenv("APP_STORAGE_BUCKET_NAME", "assets")
+	os.Setenv("APP_TIMEOUT", "5s")
+	os.Setenv("DEBUG", "true")
+
+	spec := struct {
+		Addr     string `json:"listen_addr"`
+		APIKey   string `json:"apiKey"`
+		MaxConns int    `json:"max-conns,omitempty"`
+		Storage  struct {
+			Bucket string `json:"bucket.name"`
+		} `json:"storage"`
+		Timeout time.Duration `json:",omitempty"`
+		Debug   bool          `json:"verbose" env:"debug"`
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Addr != ":8080" {
+		t.Fatalf("expected addr to be :8080, got %s", spec.Addr)
+	}
+	if spec.APIKey != "key" {
+		t.Fatalf("expected api key to be key, got %s", spec.APIKey)
+	}
+	if spec.MaxConns != 10 {
+		t.Fatalf("expected max conns to be 10, got %d", spec.MaxConns)
+	}
+	if spec.Storage.Bucket != "assets" {
+		t.Fatalf("expected bucket to be assets, got %s", spec.Storage.Bucket)
+	}
+	if spec.Timeout != 5*time.Second {
+		t.Fatalf("expected timeout to be 5s, got %s", spec.Timeout)
+	}
+	if !spec.Debug {
+		t.Fatal("expected debug to be true, got false")
+	}
+}
+
+func TestEncodingTag(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_SIGNINGKEY", "AAEC/w==")
+	os.Setenv("APP_TOKEN", "c2VjcmV0")
+	os.Setenv("APP_SEED", "-_8")
+	os.Setenv("APP_HMACKEY", "00ff10AB")
+
+	spec := struct {
+		SigningKey []byte `encoding:"base64"`
+		Token      string `encoding:"base64"`
+		Seed       []byte `encoding:"base64"`
+		HMACKey    []byte `encoding:"hex"`
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(spec.SigningKey, []byte{0, 1, 2, 255}) {
+		t.Fatalf("expected signing key to be 00 01 02 ff, got % x", spec.SigningKey)
+	}
+	if spec.Token != "secret" {
+		t.Fatalf("expected token to be secret, got %s", spec.Token)
+	}
+	if !bytes.Equal(spec.Seed, []byte{251, 255}) {
+		t.Fatalf("expected seed to be fb ff, got % x", spec.Seed)
+	}
+	if !bytes.Equal(spec.HMACKey, []byte{0, 255, 16, 171}) {
+		t.Fatalf("expected hmac key to be 00 ff 10 ab, got % x", spec.HMACKey)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_SIGNINGKEY"] != "AAEC/w==" || values["APP_TOKEN"] != "c2VjcmV0" || values["APP_HMACKEY"] != "00ff10ab" {
+		t.Fatalf("expected the values to be encoded, got %v", values)
+	}
+
+	tests := []struct {
+		description string
+		key         string
+		value       string
+		expected    string
+	}{
+		{description: "invalid base64", key: "APP_TOKEN", value: "not base64!", expected: "invalid base64 value"},
+		{description: "odd length hex", key: "APP_HMACKEY", value: "abc", expected: "odd length hex string"},
+		{description: "invalid hex", key: "APP_HMACKEY", value: "zz", expected: "invalid byte"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			os.Setenv(tt.key, tt.value)
+			defer os.Setenv(tt.key, values[tt.key])
+
+			err := Parse("app", &spec)
+			var fieldErr *FieldError
+			if !errors.As(err, &fieldErr) {
+				t.Fatalf("expected a FieldError, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Fatalf("expected the error to contain %q, got %s", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestFromFileTag(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "token"), []byte("s3cr3t\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "port"), []byte("5432\r\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Clearenv()
+	os.Setenv("DIR", dir)
+	os.Setenv("APP_TOKEN", filepath.Join(dir, "token"))
+
+	spec := struct {
+		Token string `fromFile:"true"`
+		Port  int    `fromFile:"true" expand:"true" default:"${DIR}/port"`
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Token != "s3cr3t" {
+		t.Fatalf("expected token to be s3cr3t, got %q", spec.Token)
+	}
+	if spec.Port != 5432 {
+		t.Fatalf("expected port to be 5432, got %d", spec.Port)
+	}
+
+	os.Setenv("APP_TOKEN", filepath.Join(dir, "missing"))
+	err := Parse("app", &spec)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected a missing file error, got %v", err)
+	}
+}
+
+func TestIgnoredField(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_HOST", "localhost")
+	os.Setenv("APP_CLIENT", "set")
+	os.Setenv("APP_STARTED", "set")
+	os.Setenv("APP_CACHE_SIZE", "10")
+
+	spec := struct {
+		Host    string
+		Client  string `config:"-"`
+		Started string `env:"-" required:"true"`
+		Cache   struct {
+			Size int
+		} `config:"-"`
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+	if spec.Host != "localhost" || spec.Client != "" || spec.Started != "" || spec.Cache.Size != 0 {
+		t.Fatalf("expected only host to be set, got %+v", spec)
+	}
+
+	fields, err := Fields("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 1 {
+		t.Fatalf("expected the ignored fields to be left out, got %d fields", len(fields))
+	}
+}
+
+func TestConfigTag(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("DB_HOST", "db.example.com")
+
+	spec := struct {
+		Host    string   `config:"env=db_host,required"`
+		Port    int      `config:"default=5432, required=true"`
+		Hosts   []string `config:"default=a;b,sep=;"`
+		Options string   `config:"default=sslmode\\=require\\,connect_timeout\\=5"`
+		Name    string   `config:"default=fallback" default:"app"`
+		Missing string   `config:"required"`
+	}{}
+
+	err := Parse("app", &spec)
+	if err == nil || !strings.Contains(err.Error(), "required key APP_MISSING missing value") {
+		t.Fatalf("expected an error for the missing required key, got %v", err)
+	}
+
+	os.Setenv("APP_MISSING", "set")
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Host != "db.example.com" || spec.Port != 5432 {
+		t.Fatalf("expected host to be db.example.com and port 5432, got %s and %d", spec.Host, spec.Port)
+	}
+	if !reflect.DeepEqual(spec.Hosts, []string{"a", "b"}) {
+		t.Fatalf("expected hosts to be a and b, got %v", spec.Hosts)
+	}
+	if spec.Options != "sslmode=require,connect_timeout=5" {
+		t.Fatalf("expected options to be sslmode=require,connect_timeout=5, got %s", spec.Options)
+	}
+	if spec.Name != "app" {
+		t.Fatalf("expected the default tag to take precedence, got %s", spec.Name)
+	}
+}
@@ -0,0 +1,64 @@
+// Package consul provides a config.Source backed by the Consul KV store.
+//
+//	client, err := api.NewClient(api.DefaultConfig())
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	err = config.ParseSources("app", &cfg,
+//		consul.New(client, "", consul.WithDatacenter("dc1")),
+//		config.EnvSource(),
+//	)
+package consul
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/josemukorivo/config"
+)
+
+// Option configures the queries made by the Source.
+type Option func(*api.QueryOptions)
+
+// WithDatacenter queries the given datacenter instead of the datacenter of the agent.
+func WithDatacenter(dc string) Option {
+	return func(q *api.QueryOptions) {
+		q.Datacenter = dc
+	}
+}
+
+// WithToken uses the given ACL token instead of the token of the client.
+func WithToken(token string) Option {
+	return func(q *api.QueryOptions) {
+		q.Token = token
+	}
+}
+
+// New returns a config.Source that looks up the keys stored in the Consul KV store under prefix. The
+// path of a key relative to prefix is mapped to the key of a field with config.NormalizeKey, for example
+// with an empty prefix "app/db/host" maps to APP_DB_HOST. All the keys under prefix are fetched with a
+// single request on the first lookup.
+func New(client *api.Client, prefix string, opts ...Option) config.Source {
+	q := &api.QueryOptions{}
+	for _, opt := range opts {
+		opt(q)
+	}
+
+	return config.LoadOnce(func() (config.MapSource, error) {
+		pairs, _, err := client.KV().List(prefix, q)
+		if err != nil {
+			return nil, fmt.Errorf("consul: listing keys under %q: %w", prefix, err)
+		}
+
+		values := make(config.MapSource, len(pairs))
+		for _, pair := range pairs {
+			// Skip folders.
+			if strings.HasSuffix(pair.Key, "/") {
+				continue
+			}
+			values[config.NormalizeKey(strings.TrimPrefix(pair.Key, prefix))] = string(pair.Value)
+		}
+		return values, nil
+	})
+}
@@ -0,0 +1,78 @@
+package consul
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/josemukorivo/config"
+)
+
+func newClient(t *testing.T, handler http.HandlerFunc) *api.Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := api.NewClient(&api.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestSource(t *testing.T) {
+	client := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/kv/config/" {
+			t.Errorf("expected path /v1/kv/config/, got %s", r.URL.Path)
+		}
+		if dc := r.URL.Query().Get("dc"); dc != "dc2" {
+			t.Errorf("expected datacenter to be dc2, got %s", dc)
+		}
+		if token := r.Header.Get("X-Consul-Token"); token != "secret" {
+			t.Errorf("expected token to be secret, got %s", token)
+		}
+		json.NewEncoder(w).Encode(api.KVPairs{
+			{Key: "config/app/"},
+			{Key: "config/app/host", Value: []byte("example.com")},
+			{Key: "config/app/db/port", Value: []byte("5432")},
+		})
+	})
+
+	os.Clearenv()
+	os.Setenv("APP_HOST", "env.example.com")
+
+	spec := struct {
+		Host string
+		DB   struct {
+			Port int
+		}
+	}{}
+
+	source := New(client, "config/", WithDatacenter("dc2"), WithToken("secret"))
+	if err := config.ParseSources("app", &spec, source, config.EnvSource()); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Host != "env.example.com" {
+		t.Fatalf("expected host to be env.example.com, got %s", spec.Host)
+	}
+	if spec.DB.Port != 5432 {
+		t.Fatalf("expected db port to be 5432, got %d", spec.DB.Port)
+	}
+}
+
+func TestSourceError(t *testing.T) {
+	client := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "permission denied", http.StatusForbidden)
+	})
+
+	spec := struct {
+		Host string
+	}{}
+	if err := config.ParseSources("app", &spec, New(client, "")); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
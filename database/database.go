@@ -0,0 +1,106 @@
+// Package database provides a config.Source backed by key/value rows of a database table, read with
+// database/sql, so settings stored in a database hydrate the same struct as environment variables.
+//
+//	db, err := sql.Open("pgx", dsn)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	source := database.New(db, "app", "settings")
+//	err = config.ParseSources("app", &cfg, source, config.EnvSource())
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/josemukorivo/config"
+)
+
+// Option configures the Source.
+type Option func(*source)
+
+// WithColumns sets the columns holding the keys and the values, they default to name and value.
+func WithColumns(key, value string) Option {
+	return func(s *source) {
+		s.query = fmt.Sprintf("SELECT %s, %s FROM %s", key, value, s.table)
+	}
+}
+
+// WithQuery reads the rows returned by query instead of the whole table, for example to select the
+// settings of an environment. The query must return the keys and the values in its first two columns.
+func WithQuery(query string, args ...any) Option {
+	return func(s *source) {
+		s.query = query
+		s.args = args
+	}
+}
+
+// WithTimeout bounds the time spent querying the table, it defaults to 5 seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(s *source) {
+		s.timeout = d
+	}
+}
+
+type source struct {
+	db      *sql.DB
+	prefix  string
+	table   string
+	query   string
+	args    []any
+	timeout time.Duration
+}
+
+// New returns a config.Source that looks up the rows of table. The keys are mapped to the keys of the
+// fields with config.NormalizeKey and prefixed with prefix, so with the prefix "app" the key db.host
+// maps to APP_DB_HOST. Rows with a NULL value are skipped. The table name is not escaped and must not
+// come from untrusted input. The rows are read on the first lookup.
+func New(db *sql.DB, prefix, table string, opts ...Option) config.Source {
+	s := &source{
+		db:      db,
+		prefix:  prefix,
+		table:   table,
+		query:   fmt.Sprintf("SELECT name, value FROM %s", table),
+		timeout: 5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return config.LoadOnce(s.load)
+}
+
+// load reads the rows.
+func (s *source) load() (config.MapSource, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, s.query, s.args...)
+	if err != nil {
+		return nil, fmt.Errorf("database: querying %s: %w", s.table, err)
+	}
+	defer rows.Close()
+
+	values := make(config.MapSource)
+	for rows.Next() {
+		var (
+			key   string
+			value sql.NullString
+		)
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("database: reading %s: %w", s.table, err)
+		}
+		if !value.Valid {
+			continue
+		}
+		if s.prefix != "" {
+			key = s.prefix + "_" + key
+		}
+		values[config.NormalizeKey(key)] = value.String
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("database: reading %s: %w", s.table, err)
+	}
+	return values, nil
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/josemukorivo/config"
+)
+
+// fakeDriver answers the queries in its map with rows of keys and values.
+type fakeDriver map[string][][2]any
+
+func (d fakeDriver) Open(name string) (driver.Conn, error) { return fakeConn(d), nil }
+
+type fakeConn fakeDriver
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	rows, ok := c[query]
+	if !ok {
+		return nil, errors.New("no such table")
+	}
+	return fakeStmt(rows), nil
+}
+
+func (c fakeConn) Close() error              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+type fakeStmt [][2]any
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &fakeRows{rows: s}, nil
+}
+
+type fakeRows struct {
+	rows [][2]any
+}
+
+func (r *fakeRows) Columns() []string { return []string{"name", "value"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	dest[0], dest[1] = r.rows[0][0], r.rows[0][1]
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func init() {
+	sql.Register("fake", fakeDriver{
+		"SELECT name, value FROM settings": {
+			{"host", "example.com"},
+			{"db.host", "db.example.com"},
+			{"db.port", "5432"},
+			{"db.user", nil},
+		},
+		"SELECT setting, val FROM settings": {
+			{"db_port", "6543"},
+		},
+	})
+}
+
+type Config struct {
+	Host string
+	DB   struct {
+		Host string
+		Port int
+		User string `default:"admin"`
+	}
+}
+
+func TestSource(t *testing.T) {
+	db, err := sql.Open("fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	os.Clearenv()
+	os.Setenv("APP_HOST", "env.example.com")
+
+	var cfg Config
+	if err := config.ParseSources("app", &cfg, New(db, "app", "settings"), config.EnvSource()); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Host != "env.example.com" {
+		t.Fatalf("expected host to be env.example.com, got %s", cfg.Host)
+	}
+	if cfg.DB.Host != "db.example.com" {
+		t.Fatalf("expected db host to be db.example.com, got %s", cfg.DB.Host)
+	}
+	if cfg.DB.Port != 5432 {
+		t.Fatalf("expected db port to be 5432, got %d", cfg.DB.Port)
+	}
+	if cfg.DB.User != "admin" {
+		t.Fatalf("expected db user to be admin, got %s", cfg.DB.User)
+	}
+
+	var other Config
+	source := New(db, "app", "settings", WithColumns("setting", "val"))
+	if err := config.ParseSources("app", &other, source); err != nil {
+		t.Fatal(err)
+	}
+	if other.DB.Port != 6543 {
+		t.Fatalf("expected db port to be 6543, got %d", other.DB.Port)
+	}
+}
+
+func TestSourceError(t *testing.T) {
+	db, err := sql.Open("fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var cfg Config
+	if err := config.ParseSources("app", &cfg, New(db, "app", "missing")); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,263 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// loadEnvFiles loads the variables defined in the .env files into the environment, it defaults to the
+// .env file in the working directory. Variables that are already set are not overridden, so when a
+// variable is defined in more than one file the first one wins. Files that do not exist are skipped.
+func loadEnvFiles(files ...string) error {
+	if len(files) == 0 {
+		files = []string{".env"}
+	}
+	for _, file := range files {
+		vars, keys, err := readEnvFile(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if _, ok := os.LookupEnv(key); !ok {
+				os.Setenv(key, vars[key])
+			}
+		}
+	}
+	return nil
+}
+
+// readEnvFile reads the variables defined in the .env file at path, see parseEnv. It also returns the
+// names of the variables in the order they are defined.
+func readEnvFile(path string) (map[string]string, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	vars, keys, err := parseEnv(string(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("config: parsing %s: %w", path, err)
+	}
+	return vars, keys, nil
+}
+
+// parseEnv parses the content of a .env file, see DotEnvSource for the syntax. White space around
+// unquoted values and comments following them, starting with " #", are removed. ${NAME-default}
+// expands to default only if NAME is unset, not if it is empty.
+func parseEnv(data string) (map[string]string, []string, error) {
+	p := &envParser{data: strings.ReplaceAll(data, "\r\n", "\n"), vars: make(map[string]string)}
+	if err := p.parse(); err != nil {
+		return nil, nil, fmt.Errorf("line %d: %w", p.line(), err)
+	}
+	return p.vars, p.keys, nil
+}
+
+type envParser struct {
+	data string
+	pos  int
+	vars map[string]string
+	keys []string
+}
+
+// line returns the line number of the current position.
+func (p *envParser) line() int {
+	return strings.Count(p.data[:p.pos], "\n") + 1
+}
+
+func (p *envParser) parse() error {
+	for {
+		p.skip(" \t\n")
+		if p.pos >= len(p.data) {
+			return nil
+		}
+		if p.data[p.pos] == '#' {
+			p.skipLine()
+			continue
+		}
+
+		if rest := p.data[p.pos:]; strings.HasPrefix(rest, "export ") || strings.HasPrefix(rest, "export\t") {
+			p.pos += len("export")
+			p.skip(" \t")
+		}
+		key := p.name()
+		if key == "" {
+			return errors.New("invalid variable name")
+		}
+		p.skip(" \t")
+		if p.pos >= len(p.data) || (p.data[p.pos] != '=' && p.data[p.pos] != ':') {
+			return fmt.Errorf("missing = after %s", key)
+		}
+		p.pos++
+		p.skip(" \t")
+
+		value, err := p.value()
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		if _, ok := p.vars[key]; !ok {
+			p.keys = append(p.keys, key)
+		}
+		p.vars[key] = value
+	}
+}
+
+// name reads a variable name.
+func (p *envParser) name() string {
+	start := p.pos
+	for p.pos < len(p.data) && isNameChar(p.data[p.pos], p.pos > start) {
+		p.pos++
+	}
+	return p.data[start:p.pos]
+}
+
+// value reads the value of a variable up to the end of its line.
+func (p *envParser) value() (string, error) {
+	if p.pos >= len(p.data) {
+		return "", nil
+	}
+
+	quote := p.data[p.pos]
+	if quote != '\'' && quote != '"' {
+		start := p.pos
+		p.skipLine()
+		value := p.data[start:p.pos]
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = value[:i]
+		}
+		if i := strings.Index(value, "\t#"); i >= 0 {
+			value = value[:i]
+		}
+		return p.expand(strings.TrimSpace(value), false)
+	}
+
+	p.pos++
+	start := p.pos
+	for p.pos < len(p.data) && p.data[p.pos] != quote {
+		if quote == '"' && p.data[p.pos] == '\\' {
+			p.pos++
+		}
+		p.pos++
+	}
+	if p.pos >= len(p.data) {
+		p.pos = start - 1
+		return "", fmt.Errorf("unterminated %c quoted value", quote)
+	}
+	value := p.data[start:p.pos]
+	p.pos++
+
+	// Only a comment can follow the closing quote.
+	p.skip(" \t")
+	if p.pos < len(p.data) && p.data[p.pos] != '\n' && p.data[p.pos] != '#' {
+		return "", errors.New("unexpected characters after the closing quote")
+	}
+	p.skipLine()
+
+	if quote == '\'' {
+		return value, nil
+	}
+	return p.expand(value, true)
+}
+
+// expand expands the variables referenced in s, and interprets the escape sequences if escapes is
+// true. Otherwise only \$ is interpreted, as a literal $.
+func (p *envParser) expand(s string, escapes bool) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			next := s[i+1]
+			switch {
+			case next == '$':
+				b.WriteByte('$')
+			case !escapes:
+				b.WriteByte(c)
+				continue
+			case next == 'n':
+				b.WriteByte('\n')
+			case next == 'r':
+				b.WriteByte('\r')
+			case next == 't':
+				b.WriteByte('\t')
+			case next == '"' || next == '\\':
+				b.WriteByte(next)
+			default:
+				b.WriteByte(c)
+				continue
+			}
+			i++
+		case c == '$' && i+1 < len(s) && s[i+1] == '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return "", errors.New("unterminated ${")
+			}
+			b.WriteString(p.reference(s[i+2 : i+end]))
+			i += end
+		case c == '$' && i+1 < len(s) && isNameChar(s[i+1], false):
+			j := i + 1
+			for j < len(s) && isNameChar(s[j], true) {
+				j++
+			}
+			value, _ := p.lookup(s[i+1 : j])
+			b.WriteString(value)
+			i = j - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// reference returns the value of the reference between ${ and }.
+func (p *envParser) reference(ref string) string {
+	if name, def, ok := strings.Cut(ref, ":-"); ok {
+		if value, _ := p.lookup(name); value != "" {
+			return value
+		}
+		return def
+	}
+	if name, def, ok := strings.Cut(ref, "-"); ok {
+		if value, ok := p.lookup(name); ok {
+			return value
+		}
+		return def
+	}
+	value, _ := p.lookup(ref)
+	return value
+}
+
+// lookup returns the value of the variable name in the environment, which takes precedence over the
+// file as it is not overridden when the file is loaded, or in the variables defined before.
+func (p *envParser) lookup(name string) (string, bool) {
+	if value, ok := os.LookupEnv(name); ok {
+		return value, true
+	}
+	value, ok := p.vars[name]
+	return value, ok
+}
+
+// skip skips the characters in chars.
+func (p *envParser) skip(chars string) {
+	for p.pos < len(p.data) && strings.IndexByte(chars, p.data[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// skipLine skips to the end of the line.
+func (p *envParser) skipLine() {
+	if i := strings.IndexByte(p.data[p.pos:], '\n'); i >= 0 {
+		p.pos += i
+	} else {
+		p.pos = len(p.data)
+	}
+}
+
+// isNameChar reports whether c can be part of a variable name, digits are not allowed first.
+func isNameChar(c byte, notFirst bool) bool {
+	return c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || notFirst && (c >= '0' && c <= '9' || c == '.')
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEnv(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("HOME", "/home/app")
+	os.Setenv("EMPTY", "")
+
+	tests := []struct {
+		description string
+		data        string
+		expected    map[string]string
+	}{
+		{
+			description: "unquoted values",
+			data:        "# comment\nHOST=example.com\n\n  PORT = 8080  # port\nEMPTY_VALUE=\nURL=http://example.com/#anchor\n",
+			expected:    map[string]string{"HOST": "example.com", "PORT": "8080", "EMPTY_VALUE": "", "URL": "http://example.com/#anchor"},
+		},
+		{
+			description: "export prefix",
+			data:        "export HOST=example.com\nexport\tPORT=8080",
+			expected:    map[string]string{"HOST": "example.com", "PORT": "8080"},
+		},
+		{
+			description: "windows line endings",
+			data:        "HOST=example.com\r\nPORT=8080\r\n",
+			expected:    map[string]string{"HOST": "example.com", "PORT": "8080"},
+		},
+		{
+			description: "single quoted values",
+			data:        "PASSWORD='p@ss $HOME \\n' # comment\nKEY='line 1\nline 2'\n",
+			expected:    map[string]string{"PASSWORD": "p@ss $HOME \\n", "KEY": "line 1\nline 2"},
+		},
+		{
+			description: "double quoted values",
+			data:        "GREETING=\"hello\\n\\t\\\"world\\\" \\$HOME\" # comment\nKEY=\"line 1\nline 2\"\n",
+			expected:    map[string]string{"GREETING": "hello\n\t\"world\" $HOME", "KEY": "line 1\nline 2"},
+		},
+		{
+			description: "interpolation",
+			data:        "DIR=$HOME/app\nDATA=\"${DIR}/data\"\nLOGS=${LOGS_DIR:-/var/log}\nCACHE=${EMPTY:-/tmp}\nTMP=${EMPTY-/tmp}\nPRICE=\\$5\nMISSING=$MISSING\n",
+			expected: map[string]string{
+				"DIR":     "/home/app/app",
+				"DATA":    "/home/app/app/data",
+				"LOGS":    "/var/log",
+				"CACHE":   "/tmp",
+				"TMP":     "",
+				"PRICE":   "$5",
+				"MISSING": "",
+			},
+		},
+		{
+			description: "environment takes precedence in interpolation",
+			data:        "HOME=/root\nDIR=$HOME/app\n",
+			expected:    map[string]string{"HOME": "/root", "DIR": "/home/app/app"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			vars, _, err := parseEnv(tc.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(vars, tc.expected) {
+				t.Fatalf("expected the variables to be %v, got %v", tc.expected, vars)
+			}
+		})
+	}
+}
+
+func TestParseEnvError(t *testing.T) {
+	os.Clearenv()
+
+	tests := []struct {
+		description string
+		data        string
+		err         string
+	}{
+		{description: "invalid name", data: "HOST=example.com\n1HOST=example.com\n", err: "line 2: invalid variable name"},
+		{description: "missing equal sign", data: "HOST\n", err: "line 1: missing = after HOST"},
+		{description: "unterminated quote", data: "HOST=example.com\nKEY=\"value\n", err: "line 2: KEY: unterminated \" quoted value"},
+		{description: "text after quote", data: "KEY='value' text\n", err: "line 1: KEY: unexpected characters after the closing quote"},
+		{description: "unterminated reference", data: "KEY=${HOME\n", err: "line 1: KEY: unterminated ${"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			_, _, err := parseEnv(tc.data)
+			if err == nil || err.Error() != tc.err {
+				t.Fatalf("expected error to be %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestParseEnvFiles(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_PORT", "9090")
+	first := writeFile(t, ".env", "APP_HOST=example.com\nAPP_PORT=8080\n")
+	second := writeFile(t, ".env.local", "APP_HOST=local.example.com\nAPP_DEBUG=true\n")
+
+	var cfg fileConfig
+	if err := Parse("app", &cfg, first, "missing.env", second); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != "example.com" {
+		t.Fatalf("expected host to be example.com, got %s", cfg.Host)
+	}
+	if cfg.Port != 9090 {
+		t.Fatalf("expected port to be 9090, got %d", cfg.Port)
+	}
+	if !cfg.Debug {
+		t.Fatal("expected debug to be true")
+	}
+
+	invalid := writeFile(t, ".env", "APP_HOST='example.com\n")
+	err := Parse("app", &cfg, invalid)
+	if err == nil || !strings.Contains(err.Error(), invalid+": line 1") {
+		t.Fatalf("expected error to name the file and line, got %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+// Package encrypted decrypts config values encrypted with age or PGP, so secrets can live in otherwise
+// plaintext environment variables and .env files:
+//
+//	APP_DB_PASSWORD=ENC[age:YWdlLWVuY3J5cHRpb24ub3JnL3YxCi0+IFgyNTUxOSB...]
+//
+// Values are encrypted with Encrypt, or with the age command and base64:
+//
+//	echo -n 's3cr3t' | age -r age1... | base64 -w0
+//
+// Wrap a source with Resolve to decrypt its values:
+//
+//	source := encrypted.Resolve(config.DotEnvSource(), encrypted.WithAgeKeyFile("key.txt"))
+//	err := config.ParseSources("app", &cfg, source)
+package encrypted
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+
+	"filippo.io/age"
+	"github.com/josemukorivo/config"
+)
+
+// value matches an encrypted value, the scheme and the base64 encoded ciphertext.
+var value = regexp.MustCompile(`^ENC\[(age|pgp):([A-Za-z0-9+/=\s]+)\]$`)
+
+// Option configures the decryption.
+type Option func(*source)
+
+// WithAgeIdentities decrypts the age values with the given identities.
+func WithAgeIdentities(identities ...age.Identity) Option {
+	return func(s *source) {
+		s.identities = append(s.identities, identities...)
+	}
+}
+
+// WithAgeKeyFile decrypts the age values with the identities of the key file at path, as generated by
+// age-keygen.
+func WithAgeKeyFile(path string) Option {
+	return func(s *source) {
+		s.keyFiles = append(s.keyFiles, path)
+	}
+}
+
+type source struct {
+	source     config.Source
+	identities []age.Identity
+	keyFiles   []string
+
+	once sync.Once
+	err  error
+}
+
+// Resolve returns a config.Source that looks up keys in inner and decrypts the encrypted values, of
+// the form ENC[age:<base64>] or ENC[pgp:<base64>]. Other values are returned as is. age values are
+// decrypted with the identities of the options, or the identities in the AGE_KEY environment variable
+// or the key file named by AGE_KEY_FILE if there are none. PGP values are decrypted with the gpg
+// command. An encrypted value that cannot be decrypted is an error.
+func Resolve(inner config.Source, opts ...Option) config.Source {
+	s := &source{source: inner}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Lookup looks up key in the wrapped source and decrypts the value if it is encrypted.
+func (s *source) Lookup(key string) (string, bool, error) {
+	v, ok, err := s.source.Lookup(key)
+	if err != nil || !ok {
+		return v, ok, err
+	}
+	m := value.FindStringSubmatch(strings.TrimSpace(v))
+	if m == nil {
+		return v, ok, nil
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(m[2]), ""))
+	if err != nil {
+		return "", false, fmt.Errorf("encrypted: decoding %s: %w", key, err)
+	}
+	var plaintext []byte
+	switch m[1] {
+	case "age":
+		plaintext, err = s.decryptAge(ciphertext)
+	case "pgp":
+		plaintext, err = decryptPGP(ciphertext)
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("encrypted: decrypting %s: %w", key, err)
+	}
+	return string(plaintext), true, nil
+}
+
+// decryptAge decrypts an age ciphertext.
+func (s *source) decryptAge(ciphertext []byte) ([]byte, error) {
+	s.once.Do(func() {
+		s.err = s.loadIdentities()
+	})
+	if s.err != nil {
+		return nil, s.err
+	}
+	if len(s.identities) == 0 {
+		return nil, errors.New("no age identity configured")
+	}
+
+	r, err := age.Decrypt(bytes.NewReader(ciphertext), s.identities...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// loadIdentities reads the identities of the key files, or of the environment if no identity is
+// configured.
+func (s *source) loadIdentities() error {
+	files := s.keyFiles
+	if len(s.identities) == 0 && len(files) == 0 {
+		if key := os.Getenv("AGE_KEY"); key != "" {
+			identities, err := age.ParseIdentities(strings.NewReader(key))
+			if err != nil {
+				return fmt.Errorf("parsing AGE_KEY: %w", err)
+			}
+			s.identities = identities
+			return nil
+		}
+		if file := os.Getenv("AGE_KEY_FILE"); file != "" {
+			files = []string{file}
+		}
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		identities, err := age.ParseIdentities(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", file, err)
+		}
+		s.identities = append(s.identities, identities...)
+	}
+	return nil
+}
+
+// decryptPGP decrypts a PGP message with the gpg command.
+func decryptPGP(ciphertext []byte) ([]byte, error) {
+	cmd := exec.Command("gpg", "--batch", "--quiet", "--decrypt")
+	cmd.Stdin = bytes.NewReader(ciphertext)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// Encrypt encrypts plaintext for the age recipients and returns it in the ENC[age:<base64>] form
+// Resolve decrypts.
+func Encrypt(plaintext string, recipients ...age.Recipient) (string, error) {
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, recipients...)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.WriteString(w, plaintext); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return "ENC[age:" + base64.StdEncoding.EncodeToString(buf.Bytes()) + "]", nil
+}
@@ -0,0 +1,114 @@
+package encrypted
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/josemukorivo/config"
+)
+
+type Config struct {
+	Host string
+	DB   struct {
+		Password string
+	}
+}
+
+func TestResolve(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	password, err := Encrypt("s3cr3t", identity.Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "key.txt")
+	if err := os.WriteFile(keyFile, []byte(identity.String()+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		description string
+		env         map[string]string
+		opts        []Option
+	}{
+		{
+			description: "identities",
+			opts:        []Option{WithAgeIdentities(identity)},
+		},
+		{
+			description: "key file",
+			opts:        []Option{WithAgeKeyFile(keyFile)},
+		},
+		{
+			description: "AGE_KEY",
+			env:         map[string]string{"AGE_KEY": identity.String()},
+		},
+		{
+			description: "AGE_KEY_FILE",
+			env:         map[string]string{"AGE_KEY_FILE": keyFile},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			os.Clearenv()
+			for k, v := range tc.env {
+				os.Setenv(k, v)
+			}
+
+			var cfg Config
+			source := Resolve(config.MapSource{"APP_HOST": "example.com", "APP_DB_PASSWORD": password}, tc.opts...)
+			if err := config.ParseSources("app", &cfg, source); err != nil {
+				t.Fatal(err)
+			}
+
+			if cfg.Host != "example.com" {
+				t.Fatalf("expected host to be example.com, got %s", cfg.Host)
+			}
+			if cfg.DB.Password != "s3cr3t" {
+				t.Fatalf("expected db password to be s3cr3t, got %s", cfg.DB.Password)
+			}
+		})
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	password, err := Encrypt("s3cr3t", identity.Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Clearenv()
+
+	tests := []struct {
+		description string
+		value       string
+		opts        []Option
+	}{
+		{"no identity", password, nil},
+		{"wrong identity", password, []Option{WithAgeIdentities(other)}},
+		{"invalid base64", "ENC[age:not=base64]", []Option{WithAgeIdentities(identity)}},
+		{"missing key file", password, []Option{WithAgeKeyFile("missing.txt")}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var cfg Config
+			source := Resolve(config.MapSource{"APP_DB_PASSWORD": tc.value}, tc.opts...)
+			if err := config.ParseSources("app", &cfg, source); err == nil {
+				t.Fatal("expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+// Package etcd provides a config.Source backed by etcd v3. It talks to the JSON gateway of etcd so it
+// has no dependencies besides the standard library.
+//
+//	source := etcd.New("https://etcd.example.com:2379", "config/",
+//		etcd.WithTLS(tlsConfig),
+//		etcd.WithAuth("app", password),
+//	)
+//	err := config.ParseSources("app", &cfg, source, config.EnvSource())
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/josemukorivo/config"
+)
+
+// Option configures the Source.
+type Option func(*source)
+
+// WithTLS uses the given TLS configuration to connect to etcd, for example to present a client
+// certificate. It applies to a copy of the HTTP client, the client given to WithHTTPClient is not
+// modified. The transport of that client must be nil or an *http.Transport, any other RoundTripper
+// is reported by the lookups rather than replaced.
+func WithTLS(cfg *tls.Config) Option {
+	return func(s *source) {
+		s.tls = cfg
+	}
+}
+
+// WithAuth authenticates with etcd using the given user name and password.
+func WithAuth(username, password string) Option {
+	return func(s *source) {
+		s.username = username
+		s.password = password
+	}
+}
+
+// WithTimeout bounds the time spent fetching the keys, it defaults to 5 seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(s *source) {
+		s.timeout = d
+	}
+}
+
+// WithHTTPClient uses the given HTTP client to talk to etcd.
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *source) {
+		s.client = client
+	}
+}
+
+type source struct {
+	endpoint string
+	prefix   string
+	client   *http.Client
+	tls      *tls.Config
+	username string
+	password string
+	timeout  time.Duration
+	err      error
+}
+
+// New returns a config.Source that looks up the keys stored in etcd under prefix. The endpoint is the
+// URL of an etcd member, for example "http://localhost:2379". The key relative to prefix is mapped to the
+// key of a field with config.NormalizeKey, for example with an empty prefix "app/db/host" maps to
+// APP_DB_HOST. All the keys under prefix are fetched with a single range request on the first lookup.
+func New(endpoint, prefix string, opts ...Option) config.Source {
+	s := &source{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		prefix:   prefix,
+		client:   &http.Client{},
+		timeout:  5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.tls != nil {
+		var transport *http.Transport
+		switch t := s.client.Transport.(type) {
+		case nil:
+			transport = http.DefaultTransport.(*http.Transport).Clone()
+		case *http.Transport:
+			transport = t.Clone()
+		default:
+			s.err = fmt.Errorf("etcd: WithTLS needs an *http.Transport, the HTTP client uses a %T", t)
+			return config.LoadOnce(s.load)
+		}
+		transport.TLSClientConfig = s.tls
+		client := *s.client
+		client.Transport = transport
+		s.client = &client
+	}
+	return config.LoadOnce(s.load)
+}
+
+// load fetches all the keys under the prefix.
+func (s *source) load() (config.MapSource, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	var token string
+	if s.username != "" {
+		var resp struct {
+			Token string `json:"token"`
+		}
+		req := map[string]string{"name": s.username, "password": s.password}
+		if err := s.call(ctx, "/v3/auth/authenticate", "", req, &resp); err != nil {
+			return nil, fmt.Errorf("etcd: authenticating: %w", err)
+		}
+		token = resp.Token
+	}
+
+	key, end := []byte(s.prefix), prefixEnd([]byte(s.prefix))
+	if len(key) == 0 {
+		// An empty prefix ranges over all the keys.
+		key = []byte{0}
+	}
+	req := map[string]string{
+		"key":       base64.StdEncoding.EncodeToString(key),
+		"range_end": base64.StdEncoding.EncodeToString(end),
+	}
+	var resp struct {
+		KVs []struct {
+			Key   []byte `json:"key"`
+			Value []byte `json:"value"`
+		} `json:"kvs"`
+	}
+	if err := s.call(ctx, "/v3/kv/range", token, req, &resp); err != nil {
+		return nil, fmt.Errorf("etcd: fetching keys under %q: %w", s.prefix, err)
+	}
+
+	values := make(config.MapSource, len(resp.KVs))
+	for _, kv := range resp.KVs {
+		values[config.NormalizeKey(strings.TrimPrefix(string(kv.Key), s.prefix))] = string(kv.Value)
+	}
+	return values, nil
+}
+
+// call posts req to the gateway path and decodes the response into resp.
+func (s *source) call(ctx context.Context, path, token string, req, resp any) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		r.Header.Set("Authorization", token)
+	}
+
+	res, err := s.client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("unexpected status %s: %s", res.Status, bytes.TrimSpace(msg))
+	}
+	return json.NewDecoder(res.Body).Decode(resp)
+}
+
+// prefixEnd returns the end of the range of the keys starting with prefix, it is the prefix with its
+// last byte incremented. The range of an empty prefix, or a prefix of 0xff bytes, ends at "\x00",
+// which means all the keys from the start of the range.
+func prefixEnd(prefix []byte) []byte {
+	end := bytes.Clone(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return []byte{0}
+}
@@ -0,0 +1,146 @@
+package etcd
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/josemukorivo/config"
+)
+
+type Config struct {
+	Host string
+	DB   struct {
+		Port int
+	}
+}
+
+func TestSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/auth/authenticate":
+			var req map[string]string
+			json.NewDecoder(r.Body).Decode(&req)
+			if req["name"] != "app" || req["password"] != "secret" {
+				http.Error(w, "authentication failed", http.StatusUnauthorized)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"token": "token"})
+		case "/v3/kv/range":
+			if r.Header.Get("Authorization") != "token" {
+				http.Error(w, "user name is empty", http.StatusUnauthorized)
+				return
+			}
+			var req struct {
+				Key      []byte `json:"key"`
+				RangeEnd []byte `json:"range_end"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			if string(req.Key) != "config/" || string(req.RangeEnd) != "config0" {
+				t.Errorf("expected range [config/, config0), got [%s, %s)", req.Key, req.RangeEnd)
+			}
+			json.NewEncoder(w).Encode(map[string]any{
+				"kvs": []map[string][]byte{
+					{"key": []byte("config/app/host"), "value": []byte("example.com")},
+					{"key": []byte("config/app/db/port"), "value": []byte("5432")},
+				},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	os.Clearenv()
+	os.Setenv("APP_HOST", "env.example.com")
+
+	var cfg Config
+	source := New(server.URL, "config/", WithAuth("app", "secret"))
+	if err := config.ParseSources("app", &cfg, source, config.EnvSource()); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Host != "env.example.com" {
+		t.Fatalf("expected host to be env.example.com, got %s", cfg.Host)
+	}
+	if cfg.DB.Port != 5432 {
+		t.Fatalf("expected db port to be 5432, got %d", cfg.DB.Port)
+	}
+
+	var other Config
+	source = New(server.URL, "config/", WithAuth("app", "wrong"))
+	if err := config.ParseSources("app", &other, source); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestSourceTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"kvs": []map[string][]byte{{"key": []byte("config/app/host"), "value": []byte("example.com")}},
+		})
+	}))
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	client := &http.Client{}
+
+	os.Clearenv()
+	var cfg Config
+	source := New(server.URL, "config/", WithHTTPClient(client), WithTLS(&tls.Config{RootCAs: roots}))
+	if err := config.ParseSources("app", &cfg, source); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Host != "example.com" {
+		t.Fatalf("expected host to be example.com, got %s", cfg.Host)
+	}
+	if client.Transport != nil {
+		t.Fatal("expected WithTLS not to modify the client given to WithHTTPClient")
+	}
+}
+
+// roundTripper is a RoundTripper other than *http.Transport, like an instrumented transport.
+type roundTripper struct{}
+
+func (roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestSourceTLSCustomTransport(t *testing.T) {
+	client := &http.Client{Transport: roundTripper{}}
+
+	os.Clearenv()
+	var cfg Config
+	source := New("https://etcd.example.com:2379", "config/", WithHTTPClient(client), WithTLS(&tls.Config{}))
+	err := config.ParseSources("app", &cfg, source)
+	if err == nil || !strings.Contains(err.Error(), "WithTLS needs an *http.Transport") {
+		t.Fatalf("expected an error for the custom transport, got %v", err)
+	}
+	if _, ok := client.Transport.(roundTripper); !ok {
+		t.Fatalf("expected the transport of the client to be kept, got %T", client.Transport)
+	}
+}
+
+func TestPrefixEnd(t *testing.T) {
+	tests := []struct {
+		prefix, end []byte
+	}{
+		{[]byte("config/"), []byte("config0")},
+		{[]byte{'a', 0xff}, []byte{'b'}},
+		{[]byte{0xff}, []byte{0}},
+		{nil, []byte{0}},
+	}
+	for _, tc := range tests {
+		if end := prefixEnd(tc.prefix); !bytes.Equal(end, tc.end) {
+			t.Fatalf("expected the end of %q to be %q, got %q", tc.prefix, tc.end, end)
+		}
+	}
+}
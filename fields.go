@@ -1,11 +1,18 @@
 package config
 
 import (
+	"encoding"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"math"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 )
 
 // FieldError is returned when a field cannot be parsed.
@@ -39,6 +46,22 @@ type Field struct {
 	Tags     reflect.StructTag
 	Required bool
 	Default  string
+	// Secret tells whether the field holds a secret: a Secret, DatabaseURL or RedisURL, or a field
+	// tagged with secret:"true". Its value is left out of the FieldError messages and masked in the
+	// flag usage and the output of Marshal.
+	Secret bool
+	// EnvAliases are the alternate names of the environment variable listed after EnvKey in the env
+	// tag, looked up in order after EnvKey, for example the former names of a renamed variable.
+	EnvAliases []string
+
+	parent reflect.Value // The struct holding the field, whose fields the default can reference.
+}
+
+// Fields returns the fields of cfg as Parse sees them, cfg must be a pointer to struct. Nested structs
+// are flattened and the keys are prefixed with prefix. Fields is useful to build integrations, for
+// example to generate flags or documentation from a config.
+func Fields(prefix string, cfg any) ([]Field, error) {
+	return extractFields(prefix, cfg)
 }
 
 // extractFields extracts the fields from the struct and returns a slice of Fields.
@@ -50,61 +73,339 @@ func extractFields(prefix string, cfg any) ([]Field, error) {
 	if v.Kind() != reflect.Struct {
 		return nil, ErrInvalidConfig
 	}
-	t := v.Type()
+	return appendFields(nil, prefix, v), nil
+}
 
-	fields := make([]Field, 0, v.NumField())
+// appendFields appends the fields of the struct v to fields. Nested structs are flattened, their
+// fields are prefixed with the prefix plus the nested struct name, see structPrefix. The fields of
+// embedded structs are not prefixed.
+func appendFields(fields []Field, prefix string, v reflect.Value) []Field {
+	t := v.Type()
 	for i := range v.NumField() {
-		f := v.Field(i)
+		f, tags := v.Field(i), fieldTags(t.Field(i))
+		if !f.CanInterface() && !t.Field(i).Anonymous || isIgnored(t.Field(i)) {
+			continue
+		}
+		// The exported fields of an unexported embedded struct are settable too.
+		if t.Field(i).Anonymous && isStruct(f, tags) {
+			fields = appendFields(fields, structPrefix(prefix, t.Field(i), tags), f)
+			continue
+		}
 		if !f.CanSet() {
 			continue
 		}
-		if f.Kind() == reflect.Struct {
-			newPrefix := fmt.Sprintf("%s_%s", prefix, t.Field(i).Name)
-			err := Parse(newPrefix, f.Addr().Interface())
-			if err != nil {
-				return nil, err
-			}
+		if isStruct(f, tags) {
+			fields = appendFields(fields, structPrefix(prefix, t.Field(i), tags), f)
 			continue
 		}
 
-		envKey := strings.ToUpper(t.Field(i).Tag.Get("env"))
-		key := t.Field(i).Name
+		// The env tag lists the name of the variable and its aliases, like "new_name,old_name".
+		envKey, aliases, _ := strings.Cut(strings.ToUpper(tags.Get("env")), ",")
+		key := fieldName(t.Field(i), tags)
 
 		if envKey != "" {
 			key = envKey
+		} else if nested := (Field{Field: f, Tags: tags}); isStructPtr(nested) || isStructMap(nested) {
+			key = structPrefix("", t.Field(i), tags)
 		}
-		if prefix != "" {
-			key = strings.ToUpper(fmt.Sprintf("%s_%s", prefix, key))
+		if isTrue(tags.Get("noprefix")) {
+			key = strings.ToUpper(key)
+		} else {
+			key = strings.ToUpper(joinKey(prefix, key))
 		}
 
-		key = strings.ToUpper(key)
-		required := isTrue(t.Field(i).Tag.Get("required"))
-		def := t.Field(i).Tag.Get("default")
+		required := isTrue(tags.Get("required"))
+		def := tags.Get("default")
 
 		field := Field{
 			Name:     t.Field(i).Name,
 			Field:    f,
-			Tags:     t.Field(i).Tag,
+			Tags:     tags,
 			Key:      key,
 			Required: required,
 			Default:  def,
 			EnvKey:   envKey,
+			Secret:   isSecret(f.Type(), tags),
+			parent:   v,
+		}
+		if aliases != "" {
+			field.EnvAliases = strings.Split(aliases, ",")
 		}
 
 		fields = append(fields, field)
 	}
-	return fields, nil
+	return fields
+}
+
+// structPrefix returns the prefix of the fields of the nested struct sf: prefix joined with the name of
+// the field, or with the segment of its prefix tag like prefix:"PG". The fields of an embedded struct
+// are not prefixed unless it is tagged with prefix:"true" or a segment, and prefix:"false" leaves the
+// fields of a nested struct unprefixed.
+func structPrefix(prefix string, sf reflect.StructField, tags reflect.StructTag) string {
+	segment := tags.Get("prefix")
+	if b, err := strconv.ParseBool(segment); err == nil || segment == "" {
+		if !b && (sf.Anonymous || segment != "") {
+			return prefix
+		}
+		segment = fieldName(sf, tags)
+	}
+	return joinKey(prefix, segment)
 }
 
-// parseField parses a string value into a field.
-func parseField(value string, field reflect.Value) error {
+var (
+	nameTagMu sync.RWMutex
+	nameTag   string
+)
+
+// SetNameTag sets the struct tag, like json or yaml, whose name is used in the key of the fields that
+// have no env tag instead of the name of the field, so that structs already tagged for an API can be
+// reused as is. With SetNameTag("json"), the field below is read from APP_LISTEN_ADDR:
+//
+//	type Config struct {
+//		Addr string `json:"listen_addr"`
+//	}
+//
+// The dots and dashes of the names are replaced by underscores. The fields without a name in the tag,
+// like json:",omitempty" or json:"-", keep their name. An empty tag, the default, disables the fallback.
+// SetNameTag is usually called from an init function.
+func SetNameTag(tag string) {
+	nameTagMu.Lock()
+	defer nameTagMu.Unlock()
+	nameTag = tag
+}
+
+// fieldName returns the name of the field sf used in its key: the name in the tag set by SetNameTag, or
+// the name of the field. It is split into words joined with underscores if the field is tagged with
+// split_words:"true", so APIKey is read from API_KEY rather than APIKEY.
+func fieldName(sf reflect.StructField, tags reflect.StructTag) string {
+	nameTagMu.RLock()
+	tag := nameTag
+	nameTagMu.RUnlock()
+
+	name := sf.Name
+	if tag != "" {
+		if n, _, _ := strings.Cut(tags.Get(tag), ","); n != "" && n != "-" {
+			name = strings.NewReplacer(".", "_", "-", "_").Replace(n)
+		}
+	}
+	if !isTrue(tags.Get("split_words")) {
+		return name
+	}
+	return strings.Join(splitWords(name), "_")
+}
+
+// splitAcronyms are the acronyms written in mixed case that splitWords keeps in one word.
+var splitAcronyms = []string{"GraphQL", "GitHub", "IPv4", "IPv6", "MySQL", "OAuth", "PostgreSQL"}
+
+// splitWords splits a Go identifier in camel case into words. A new word starts at an upper case letter
+// following a lower case letter or a digit, and at the last letter of a run of upper case letters
+// followed by a lower case letter, so HTTPPort is split into HTTP and Port and S3Bucket into S3 and
+// Bucket. The acronyms of splitAcronyms, like OAuth, and the digits following them are kept in one word.
+func splitWords(name string) []string {
+	var (
+		words []string
+		runes = []rune(name)
+		start int
+	)
+	for i := 0; i < len(runes); {
+		if n := acronymLen(runes[i:]); n > 0 {
+			if i > start {
+				words = append(words, string(runes[start:i]))
+			}
+			end := i + n
+			for end < len(runes) && unicode.IsDigit(runes[end]) {
+				end++
+			}
+			words = append(words, string(runes[i:end]))
+			i, start = end, end
+			continue
+		}
+		if i > start && unicode.IsUpper(runes[i]) &&
+			(!unicode.IsUpper(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+		i++
+	}
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+// acronymLen returns the length of the acronym of splitAcronyms runes start with, if it is not followed
+// by a lower case letter, or 0.
+func acronymLen(runes []rune) int {
+	for _, acronym := range splitAcronyms {
+		a := []rune(acronym)
+		if len(runes) >= len(a) && string(runes[:len(a)]) == acronym &&
+			(len(runes) == len(a) || !unicode.IsLower(runes[len(a)])) {
+			return len(a)
+		}
+	}
+	return 0
+}
+
+// isIgnored reports whether the field sf is tagged with config:"-" or env:"-", to leave out a computed
+// or runtime-only field.
+func isIgnored(sf reflect.StructField) bool {
+	return sf.Tag.Get("config") == "-" || sf.Tag.Get("env") == "-"
+}
+
+// fieldTags returns the struct tags of the field sf with the options of its config tag added as separate
+// tags, so config:"default=8080,required" reads like default:"8080" required:"true". The separate tags
+// take precedence over the options of the config tag.
+func fieldTags(sf reflect.StructField) reflect.StructTag {
+	combined, ok := sf.Tag.Lookup("config")
+	if !ok {
+		return sf.Tag
+	}
+	tags := string(sf.Tag)
+	for _, option := range parseConfigTag(combined) {
+		tags += " " + option[0] + ":" + strconv.Quote(option[1])
+	}
+	return reflect.StructTag(tags)
+}
+
+// parseConfigTag parses the config tag, a comma separated list of name=value options like
+// default=8080,required=true,env=PORT. A backslash escapes a comma, an equals sign or a backslash in a
+// value and an option without a value, like required, is true. Options with an invalid name are
+// ignored.
+func parseConfigTag(tag string) [][2]string {
+	var options [][2]string
+	for rest := tag; rest != ""; {
+		var option string
+		option, rest, _ = cutUnescaped(rest, ',')
+		name, value, ok := cutUnescaped(option, '=')
+		if !ok {
+			value = "true"
+		}
+		name = strings.TrimSpace(name)
+		if name == "" || strings.IndexFunc(name, func(r rune) bool { return r <= ' ' || r == ':' || r == '"' || r == 0x7f }) >= 0 {
+			continue
+		}
+		value = strings.NewReplacer(`\,`, ",", `\=`, "=", `\\`, `\`).Replace(value)
+		options = append(options, [2]string{name, value})
+	}
+	return options
+}
+
+// cutUnescaped is like strings.Cut but skips the separators escaped with a backslash.
+func cutUnescaped(s string, sep byte) (string, string, bool) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
+
+// isStruct reports whether f is a nested struct whose fields are flattened, rather than a value parsed
+// as a whole like a Setter, an encoding.TextUnmarshaler, an encoding.BinaryUnmarshaler, a url.URL or
+// a field with a format or layout tag. tags are the struct tags of the field.
+func isStruct(f reflect.Value, tags reflect.StructTag) bool {
+	if _, ok := fieldTypes[f.Type()]; ok || tags.Get("format") != "" || tags.Get("layout") != "" {
+		return false
+	}
+	return f.Kind() == reflect.Struct && extractSetter(f) == nil && extractTextUnmarshaler(f) == nil &&
+		extractBinaryUnmarshaler(f) == nil
+}
+
+// isSecret reports whether a field of type t with the struct tags tags holds a secret, see Field.Secret.
+func isSecret(t reflect.Type, tags reflect.StructTag) bool {
+	if isTrue(tags.Get("secret")) {
+		return true
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t == reflect.TypeOf(Secret{}) || t == reflect.TypeOf(DatabaseURL{}) || t == reflect.TypeOf(RedisURL{})
+}
+
+// isStructMap reports whether field is a map with string keys and struct values, like
+// map[string]Endpoint, filled from the keys of the sources rather than parsed from a single value.
+func isStructMap(field Field) bool {
+	t := field.Field.Type()
+	if t.Kind() != reflect.Map || t.Key().Kind() != reflect.String || t.Elem().Kind() != reflect.Struct {
+		return false
+	}
+	return field.Tags.Get("format") == "" && isStruct(reflect.New(t.Elem()).Elem(), "")
+}
+
+// isStructPtr reports whether field is a pointer to a nested struct, like *DatabaseConfig, allocated
+// only when a key of its fields is set so that an optional section stays nil when it is not configured.
+func isStructPtr(field Field) bool {
+	t := field.Field.Type()
+	if _, ok := fieldTypes[t]; ok || t.Kind() != reflect.Ptr {
+		return false
+	}
+	return isStruct(reflect.New(t.Elem()).Elem(), field.Tags)
+}
+
+// joinKey joins the prefix and the key with an underscore. The key is returned as is if
+// the prefix is empty.
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "_" + key
+}
+
+// parseField parses a string value into a field, tags are the struct tags of the field.
+func parseField(value string, field reflect.Value, tags reflect.StructTag) error {
 	t := field.Type()
 
-	// If the field implements the Setter interface, use it to set it's value.
-	// Otherwise, use the default parser. This allows for custom types to be used.
+	if format := tags.Get("format"); format != "" {
+		return parseFormat(value, field, format)
+	}
+	if layout := tags.Get("layout"); layout != "" {
+		return parseTime(value, field, layout)
+	}
+	if ok, err := runDecodeHooks(value, field); ok || err != nil {
+		return err
+	}
+	if ok, err := parseEnum(value, field); ok || err != nil {
+		return err
+	}
+
+	if ft, ok := fieldTypes[t]; ok {
+		v, err := ft.parse(value, tags)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(v))
+		return nil
+	}
+
+	// Allocate pointers, so a nil pointer tells that no value was set.
+	if t.Kind() == reflect.Ptr {
+		p := reflect.New(t.Elem())
+		if err := parseField(value, p.Elem(), tags); err != nil {
+			return err
+		}
+		field.Set(p)
+		return nil
+	}
+
+	// If the field implements the Setter interface, use it to set it's value. Otherwise, use the
+	// default parser, which also supports encoding.TextUnmarshaler and encoding.BinaryUnmarshaler with
+	// base64 values. This allows for custom types to be used.
 	if setter := extractSetter(field); setter != nil {
 		return setter.Set(value)
 	}
+	if u := extractTextUnmarshaler(field); u != nil {
+		return u.UnmarshalText([]byte(value))
+	}
+	if u := extractBinaryUnmarshaler(field); u != nil {
+		data, err := decodeBase64(value)
+		if err != nil {
+			return err
+		}
+		return u.UnmarshalBinary(data)
+	}
 
 	switch t.Kind() {
 	case reflect.String:
@@ -118,6 +419,26 @@ func parseField(value string, field reflect.Value) error {
 			var d time.Duration
 			d, err = time.ParseDuration(value)
 			val = int64(d)
+		} else if tags.Get("unit") == "bytes" {
+			var size uint64
+			size, err = ParseByteSize(value)
+			val = int64(size)
+			if err == nil && (size > math.MaxInt64 || field.OverflowInt(val)) {
+				err = fmt.Errorf("byte size %q overflows %s", value, t)
+			}
+		} else if tags.Get("unit") == "si" {
+			var (
+				n        uint64
+				negative bool
+			)
+			n, negative, err = parseSI(value)
+			val = int64(n)
+			if negative {
+				val = -val
+			}
+			if err == nil && (n > math.MaxInt64 || field.OverflowInt(val)) {
+				err = fmt.Errorf("number %q overflows %s", value, t)
+			}
 		} else {
 			val, err = strconv.ParseInt(value, 0, field.Type().Bits())
 		}
@@ -125,6 +446,31 @@ func parseField(value string, field reflect.Value) error {
 			return err
 		}
 		field.SetInt(val)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var (
+			val uint64
+			err error
+		)
+		if tags.Get("unit") == "bytes" {
+			val, err = ParseByteSize(value)
+			if err == nil && field.OverflowUint(val) {
+				err = fmt.Errorf("byte size %q overflows %s", value, t)
+			}
+		} else if tags.Get("unit") == "si" {
+			var negative bool
+			val, negative, err = parseSI(value)
+			if err == nil && negative && val != 0 {
+				err = fmt.Errorf("negative number %q for %s", value, t)
+			} else if err == nil && field.OverflowUint(val) {
+				err = fmt.Errorf("number %q overflows %s", value, t)
+			}
+		} else {
+			val, err = strconv.ParseUint(value, 0, t.Bits())
+		}
+		if err != nil {
+			return err
+		}
+		field.SetUint(val)
 	case reflect.Bool:
 		boolValue, err := strconv.ParseBool(value)
 		if err != nil {
@@ -137,18 +483,165 @@ func parseField(value string, field reflect.Value) error {
 			return err
 		}
 		field.SetFloat(floatValue)
+	case reflect.Complex64, reflect.Complex128:
+		complexValue, err := strconv.ParseComplex(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetComplex(complexValue)
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			field.SetBytes([]byte(value))
+			return nil
+		}
+		var items []string
+		if value != "" {
+			items = splitList(value, listSeparator(tags))
+		}
+		slice := reflect.MakeSlice(t, len(items), len(items))
+		for i, item := range items {
+			if err := parseField(strings.TrimSpace(item), slice.Index(i), ""); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+		field.Set(slice)
+	case reflect.Array:
+		var items []string
+		if value != "" {
+			items = splitList(value, listSeparator(tags))
+		}
+		if len(items) != t.Len() {
+			return fmt.Errorf("expected %d items, got %d", t.Len(), len(items))
+		}
+		array := reflect.New(t).Elem()
+		for i, item := range items {
+			if err := parseField(strings.TrimSpace(item), array.Index(i), ""); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+		field.Set(array)
+	case reflect.Map:
+		var pairs []string
+		if value != "" {
+			pairs = splitList(value, pairSeparator(tags))
+		}
+		m := reflect.MakeMapWithSize(t, len(pairs))
+		for _, pair := range pairs {
+			k, v, ok := strings.Cut(pair, mapSeparator(tags))
+			if !ok {
+				return fmt.Errorf("missing %q between the key and the value in %q", mapSeparator(tags), pair)
+			}
+			key, val := reflect.New(t.Key()).Elem(), reflect.New(t.Elem()).Elem()
+			if err := parseField(strings.TrimSpace(k), key, ""); err != nil {
+				return fmt.Errorf("key %s: %w", k, err)
+			}
+			if err := parseField(strings.TrimSpace(v), val, ""); err != nil {
+				return fmt.Errorf("value of %s: %w", k, err)
+			}
+			m.SetMapIndex(key, val)
+		}
+		field.Set(m)
+	case reflect.Interface:
+		impl, err := newImplementation(t, value)
+		if err != nil {
+			return err
+		}
+		field.Set(impl)
+	default:
+		return fmt.Errorf("unsupported type %s", t)
+	}
+	return nil
+}
+
+// parseFormat parses a value encoded in format, set with the format tag, into a field of any type, for
+// example a whole nested struct, slice or map. Only FormatJSON is supported.
+func parseFormat(value string, field reflect.Value, format string) error {
+	if format != FormatJSON {
+		return fmt.Errorf("unsupported format %q", format)
+	}
+	v := reflect.New(field.Type())
+	if err := json.Unmarshal([]byte(value), v.Interface()); err != nil {
+		return err
 	}
+	field.Set(v.Elem())
 	return nil
 }
 
+// mapSeparator returns the separator of the keys and values of a map field, the kvsep tag or a colon.
+func mapSeparator(tags reflect.StructTag) string {
+	if sep := tags.Get("kvsep"); sep != "" {
+		return sep
+	}
+	return ":"
+}
+
+// pairSeparator returns the separator of the pairs of a map field, the pairsep tag or the list
+// separator.
+func pairSeparator(tags reflect.StructTag) string {
+	if sep := tags.Get("pairsep"); sep != "" {
+		return sep
+	}
+	return listSeparator(tags)
+}
+
+// listSeparator returns the separator of the items of a slice or array field, the sep tag, its alias
+// delim or a comma.
+func listSeparator(tags reflect.StructTag) string {
+	if sep := tags.Get("sep"); sep != "" {
+		return sep
+	}
+	if sep := tags.Get("delim"); sep != "" {
+		return sep
+	}
+	return ","
+}
+
+// splitList splits value around sep. A separator preceded by a backslash is part of the item, and two
+// backslashes stand for one, so that items can contain the separator. Other backslashes are kept.
+func splitList(value, sep string) []string {
+	var (
+		items []string
+		b     strings.Builder
+	)
+	for i := 0; i < len(value); {
+		switch {
+		case strings.HasPrefix(value[i:], `\`+sep):
+			b.WriteString(sep)
+			i += 1 + len(sep)
+		case strings.HasPrefix(value[i:], `\\`):
+			b.WriteByte('\\')
+			i += 2
+		case strings.HasPrefix(value[i:], sep):
+			items = append(items, b.String())
+			b.Reset()
+			i += len(sep)
+		default:
+			b.WriteByte(value[i])
+			i++
+		}
+	}
+	return append(items, b.String())
+}
+
+// joinList joins items with sep, escaping the separators and backslashes they contain the way
+// splitList expects.
+func joinList(items []string, sep string) string {
+	r := strings.NewReplacer(`\`, `\\`, sep, `\`+sep)
+	for i, item := range items {
+		items[i] = r.Replace(item)
+	}
+	return strings.Join(items, sep)
+}
+
 // extractInterface extracts the interface from a field. It checks if the field implements the interface
 // and if not, it checks if the field's address implements the interface. If the interface is found,
 // the ok parameter is set to true. Otherwise, it is set to false.
 func extractInterface(field reflect.Value, fn func(any, *bool)) {
 	var ok bool
-	if field.CanInterface() {
-		fn(field.Interface(), &ok)
+	if !field.CanInterface() {
+		return
 	}
+	fn(field.Interface(), &ok)
 	if !ok && field.CanAddr() {
 		fn(field.Addr().Interface(), &ok)
 	}
@@ -165,6 +658,74 @@ func extractSetter(field reflect.Value) Setter {
 	return s
 }
 
+// extractTextUnmarshaler returns an encoding.TextUnmarshaler if the field implements it. Otherwise, it
+// returns nil.
+func extractTextUnmarshaler(field reflect.Value) encoding.TextUnmarshaler {
+	var u encoding.TextUnmarshaler
+	extractInterface(field, func(v any, ok *bool) {
+		u, *ok = v.(encoding.TextUnmarshaler)
+	})
+	return u
+}
+
+// extractBinaryUnmarshaler returns an encoding.BinaryUnmarshaler if the field implements it. Otherwise,
+// it returns nil.
+func extractBinaryUnmarshaler(field reflect.Value) encoding.BinaryUnmarshaler {
+	var u encoding.BinaryUnmarshaler
+	extractInterface(field, func(v any, ok *bool) {
+		u, *ok = v.(encoding.BinaryUnmarshaler)
+	})
+	return u
+}
+
+// decodeValue decodes the value of a field with the encoding of its encoding tag, base64 or hex, before
+// it is parsed, for example to set a []byte field holding a binary key. The value is returned as is if
+// the field has no encoding tag.
+func decodeValue(value string, tags reflect.StructTag) (string, error) {
+	switch encoding := tags.Get("encoding"); encoding {
+	case "":
+		return value, nil
+	case "base64":
+		data, err := decodeBase64(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid base64 value: %w", err)
+		}
+		return string(data), nil
+	case "hex":
+		data, err := hex.DecodeString(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid hex value: %w", err)
+		}
+		return string(data), nil
+	default:
+		return "", fmt.Errorf("unsupported encoding %q, expected base64 or hex", encoding)
+	}
+}
+
+// encodeValue encodes the formatted value of a field with the encoding of its encoding tag, the
+// reverse of decodeValue.
+func encodeValue(value string, tags reflect.StructTag) (string, error) {
+	switch encoding := tags.Get("encoding"); encoding {
+	case "":
+		return value, nil
+	case "base64":
+		return base64.StdEncoding.EncodeToString([]byte(value)), nil
+	case "hex":
+		return hex.EncodeToString([]byte(value)), nil
+	default:
+		return "", fmt.Errorf("unsupported encoding %q, expected base64 or hex", encoding)
+	}
+}
+
+// decodeBase64 decodes a base64 value, with the standard or the URL alphabet, padded or not.
+func decodeBase64(value string) ([]byte, error) {
+	encoding := base64.StdEncoding
+	if strings.ContainsAny(value, "-_") {
+		encoding = base64.URLEncoding
+	}
+	return encoding.WithPadding(base64.NoPadding).DecodeString(strings.TrimRight(value, "="))
+}
+
 func isTrue(value string) bool {
 	b, _ := strconv.ParseBool(value)
 	return b
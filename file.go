@@ -0,0 +1,523 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/hashicorp/hcl"
+	"gopkg.in/yaml.v3"
+)
+
+// Supported config file formats.
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+	FormatTOML = "toml"
+	FormatINI  = "ini"
+	FormatHCL  = "hcl"
+
+	FormatProperties = "properties"
+)
+
+// decoders maps the supported config file formats to the functions used to decode them.
+var decoders = map[string]func(data []byte) (map[string]any, error){
+	FormatYAML: decodeYAML,
+	FormatJSON: decodeJSON,
+	FormatTOML: decodeTOML,
+	FormatINI:  decodeINI,
+	FormatHCL:  decodeHCL,
+
+	FormatProperties: decodeProperties,
+}
+
+// extensions maps config file extensions to their format.
+var extensions = map[string]string{
+	".yaml": FormatYAML,
+	".yml":  FormatYAML,
+	".json": FormatJSON,
+	".toml": FormatTOML,
+	".ini":  FormatINI,
+	".hcl":  FormatHCL,
+
+	".properties": FormatProperties,
+}
+
+// ParseFile parses the config from the file at path and the environment. The format of the file is
+// detected from its extension, YAML (.yaml, .yml), JSON (.json), TOML (.toml), INI (.ini), HCL (.hcl)
+// and Java properties (.properties) files are supported. Keys in the file map to fields the same way
+// environment variables do, minus the prefix, nested maps, tables, sections, blocks and dotted property
+// keys map to nested structs. For example, with the prefix "app", the key "host" in the "db" map is looked up as
+// "APP_DB_HOST". Environment variables always override the values from the file. ParseFile takes an
+// optional list of .env files to load, see Parse for more information.
+func ParseFile(prefix string, cfg any, path string, envFiles ...string) error {
+	values, err := readFile(prefix, path)
+	if err != nil {
+		return err
+	}
+
+	// Load the .env file if it exists.
+	if err := loadEnvFiles(envFiles...); err != nil {
+		return err
+	}
+	return parse(prefix, cfg, values, EnvSource())
+}
+
+// ParseReader is like ParseFile but reads the config document from r. The format is one of the
+// supported formats, for example FormatJSON.
+func ParseReader(prefix string, cfg any, r io.Reader, format string, envFiles ...string) error {
+	values, err := ReadValues(prefix, r, format)
+	if err != nil {
+		return err
+	}
+
+	// Load the .env file if it exists.
+	if err := loadEnvFiles(envFiles...); err != nil {
+		return err
+	}
+	return parse(prefix, cfg, values, EnvSource())
+}
+
+// ParseStdin is like ParseFile but reads the JSON or YAML config document piped on the standard input,
+// see StdinSource. It is opt-in, Parse never reads the standard input.
+func ParseStdin(prefix string, cfg any, envFiles ...string) error {
+	// Load the .env file if it exists.
+	if err := loadEnvFiles(envFiles...); err != nil {
+		return err
+	}
+	return parse(prefix, cfg, StdinSource(prefix), EnvSource())
+}
+
+// MustParseFile parses the config and panics if an error occurs.
+// See ParseFile for more information.
+func MustParseFile(prefix string, cfg any, path string, envFiles ...string) {
+	if err := ParseFile(prefix, cfg, path, envFiles...); err != nil {
+		panic(err)
+	}
+}
+
+// ParseFS is like ParseFile but reads the config file at path from fsys, for example an embed.FS, so a
+// default config file can be compiled into the binary:
+//
+//	//go:embed config.yaml
+//	var defaults embed.FS
+//
+//	err := config.ParseFS("app", &cfg, defaults, "config.yaml")
+func ParseFS(prefix string, cfg any, fsys fs.FS, path string, envFiles ...string) error {
+	values, err := readFSFile(prefix, fsys, path)
+	if err != nil {
+		return err
+	}
+
+	// Load the .env file if it exists.
+	if err := loadEnvFiles(envFiles...); err != nil {
+		return err
+	}
+	return parse(prefix, cfg, values, EnvSource())
+}
+
+// ParseUserConfig is like ParseFile but reads the config file of the application app from the
+// standard config directories, see FindConfigFile, so CLI tools get the usual config file behavior. If
+// there is no config file, the config is parsed from the environment like Parse does.
+func ParseUserConfig(prefix string, cfg any, app string, envFiles ...string) error {
+	if path, ok := FindConfigFile(app); ok {
+		return ParseFile(prefix, cfg, path, envFiles...)
+	}
+	return Parse(prefix, cfg, envFiles...)
+}
+
+// configNames are the names of the config files FindConfigFile looks for, in order of precedence.
+var configNames = []string{"config.yaml", "config.yml", "config.json", "config.toml"}
+
+// FindConfigFile returns the path of the config file of the application app, config.yaml, config.yml,
+// config.json or config.toml in the directory app of the first config directory holding one of them:
+//
+//   - $XDG_CONFIG_HOME, or ~/.config if it is not set, except on Windows
+//   - the platform's config directory, ~/Library/Application Support on macOS and %AppData% on Windows
+//   - the directories of $XDG_CONFIG_DIRS, or /etc/xdg if it is not set, except on Windows
+//
+// For example, the config file of the application "mytool" is usually ~/.config/mytool/config.yaml.
+func FindConfigFile(app string) (string, bool) {
+	for _, dir := range configDirs() {
+		for _, name := range configNames {
+			path := filepath.Join(dir, app, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path, true
+			}
+		}
+	}
+	return "", false
+}
+
+// configDirs returns the config directories searched by FindConfigFile, in order of precedence.
+func configDirs() []string {
+	var dirs []string
+	if dir := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(dir) {
+		dirs = append(dirs, dir)
+	} else if home, err := os.UserHomeDir(); err == nil && runtime.GOOS != "windows" {
+		dirs = append(dirs, filepath.Join(home, ".config"))
+	}
+	if dir, err := os.UserConfigDir(); err == nil && !slices.Contains(dirs, dir) {
+		dirs = append(dirs, dir)
+	}
+	if runtime.GOOS == "windows" {
+		return dirs
+	}
+
+	systemDirs := os.Getenv("XDG_CONFIG_DIRS")
+	if systemDirs == "" {
+		systemDirs = "/etc/xdg"
+	}
+	for _, dir := range filepath.SplitList(systemDirs) {
+		if filepath.IsAbs(dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// readFile reads and decodes the config file at path and flattens it under prefix.
+func readFile(prefix, path string) (documentValues, error) {
+	return decodeFile(prefix, path, os.ReadFile)
+}
+
+// readFSFile is like readFile but reads the file from fsys.
+func readFSFile(prefix string, fsys fs.FS, path string) (documentValues, error) {
+	return decodeFile(prefix, path, func(path string) ([]byte, error) {
+		return fs.ReadFile(fsys, path)
+	})
+}
+
+// decodeFile reads the config file at path with readFile, decodes it and flattens it under prefix.
+func decodeFile(prefix, path string, readFile func(string) ([]byte, error)) (documentValues, error) {
+	format, ok := DetectFormat(path)
+	if !ok {
+		return documentValues{}, fmt.Errorf("config: unsupported config file %s", path)
+	}
+	data, err := readFile(path)
+	if err != nil {
+		return documentValues{}, fmt.Errorf("config: reading config file: %w", err)
+	}
+	values, err := decodeValues(prefix, format, data)
+	if err != nil {
+		return documentValues{}, fmt.Errorf("config: decoding config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// DetectFormat returns the format of the config file at path from its extension, see ParseFile for
+// the supported extensions.
+func DetectFormat(path string) (string, bool) {
+	format, ok := extensions[strings.ToLower(filepath.Ext(path))]
+	return format, ok
+}
+
+// ReadValues reads the config document in the given format from r and returns its values keyed like
+// the fields, prefixed with prefix. See ParseFile for how the keys of the document map to fields. The
+// items of lists are joined with commas, a comma or a backslash in an item is escaped with a backslash.
+// ReadValues is useful to implement sources that fetch a config document.
+func ReadValues(prefix string, r io.Reader, format string) (MapSource, error) {
+	values, err := readDocument(prefix, r, format)
+	return values.MapSource, err
+}
+
+// ReadDocument is like ReadValues but the items of the lists of the document are kept, they are joined
+// with the separator of the field they are assigned to, like the lists of FileSource. The KeySource it
+// returns is useful to implement sources that fetch a config document with lists.
+func ReadDocument(prefix string, r io.Reader, format string) (KeySource, error) {
+	values, err := readDocument(prefix, r, format)
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// readDocument is like ReadValues but keeps the lists of the document, see documentValues.
+func readDocument(prefix string, r io.Reader, format string) (documentValues, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return documentValues{}, fmt.Errorf("config: reading config: %w", err)
+	}
+	values, err := decodeValues(prefix, format, data)
+	if err != nil {
+		return documentValues{}, fmt.Errorf("config: decoding config: %w", err)
+	}
+	return values, nil
+}
+
+// documentValues are the values of a decoded config document. The items of its lists are kept, so that
+// they are joined with the separator of the field they are assigned to rather than a comma, see
+// listSource.
+type documentValues struct {
+	MapSource                     // The values, with the items of the lists joined with commas.
+	lists     map[string][]string // The items of the lists.
+}
+
+func (d documentValues) lookupList(key string) ([]string, bool, error) {
+	items, ok := d.lists[key]
+	return items, ok, nil
+}
+
+// decodeValues decodes data in the given format and flattens it under prefix.
+func decodeValues(prefix, format string, data []byte) (documentValues, error) {
+	decode, ok := decoders[format]
+	if !ok {
+		return documentValues{}, fmt.Errorf("unsupported format %q", format)
+	}
+	doc, err := decode(data)
+	if err != nil {
+		return documentValues{}, err
+	}
+
+	values := documentValues{MapSource: make(MapSource), lists: make(map[string][]string)}
+	flatten(prefix, doc, values)
+	return values, nil
+}
+
+// flatten flattens the decoded document v into values. Nested keys are joined with an underscore
+// and upper cased so they match the keys of the fields. The maps of a list, like HCL blocks or a YAML
+// list of maps, are merged, its other items are kept as a list.
+func flatten(key string, v any, values documentValues) {
+	switch v := v.(type) {
+	case nil:
+	case map[string]any:
+		for k, e := range v {
+			flatten(joinKey(key, k), e, values)
+		}
+	case map[any]any:
+		for k, e := range v {
+			flatten(joinKey(key, fmt.Sprint(k)), e, values)
+		}
+	case []map[string]any:
+		for _, e := range v {
+			flatten(key, e, values)
+		}
+	case []any:
+		var items []string
+		for _, e := range v {
+			switch e.(type) {
+			case map[string]any, map[any]any:
+				flatten(key, e, values)
+			default:
+				items = append(items, fmt.Sprint(e))
+			}
+		}
+		if items != nil || len(v) == 0 {
+			values.lists[NormalizeKey(key)] = items
+			values.MapSource[NormalizeKey(key)] = joinList(slices.Clone(items), ",")
+		}
+	default:
+		values.MapSource[NormalizeKey(key)] = fmt.Sprint(v)
+	}
+}
+
+func decodeYAML(data []byte) (map[string]any, error) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func decodeJSON(data []byte) (map[string]any, error) {
+	var doc map[string]any
+	// Decode numbers as json.Number so large integers are not converted to floats.
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func decodeTOML(data []byte) (map[string]any, error) {
+	var doc map[string]any
+	if err := toml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// decodeINI decodes an INI document. Keys before the first section are top level keys, sections map
+// to nested maps and dotted section names, like [db.replica], to deeper nested maps. Lines starting
+// with ; or # are comments.
+func decodeINI(data []byte) (map[string]any, error) {
+	doc := make(map[string]any)
+	section := doc
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' {
+			if line[len(line)-1] != ']' {
+				return nil, fmt.Errorf("line %d: invalid section %s", n, line)
+			}
+			section = doc
+			for _, name := range strings.Split(line[1:len(line)-1], ".") {
+				name = strings.TrimSpace(name)
+				next, ok := section[name].(map[string]any)
+				if !ok {
+					next = make(map[string]any)
+					section[name] = next
+				}
+				section = next
+			}
+			continue
+		}
+
+		i := strings.IndexAny(line, "=:")
+		if i <= 0 {
+			return nil, fmt.Errorf("line %d: invalid key value pair %s", n, line)
+		}
+		key := strings.TrimSpace(line[:i])
+		section[key] = unquote(strings.TrimSpace(line[i+1:]))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// unquote removes matching single or double quotes surrounding value.
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+func decodeHCL(data []byte) (map[string]any, error) {
+	var doc map[string]any
+	if err := hcl.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// decodeProperties decodes a Java properties document. Dotted keys, like db.port, map to nested
+// structs. Keys and values are separated by =, : or white space, lines ending with a backslash
+// continue on the next line and lines starting with # or ! are comments.
+func decodeProperties(data []byte) (map[string]any, error) {
+	doc := make(map[string]any)
+	add := func(line string) error {
+		key, value, err := splitProperty(line)
+		if err != nil {
+			return err
+		}
+		doc[strings.ReplaceAll(key, ".", "_")] = value
+		return nil
+	}
+
+	var logical strings.Builder
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimLeft(scanner.Text(), " \t\f")
+		if logical.Len() == 0 && (line == "" || line[0] == '#' || line[0] == '!') {
+			continue
+		}
+
+		// An odd number of trailing backslashes continues the line.
+		trailing := len(line) - len(strings.TrimRight(line, "\\"))
+		if trailing%2 == 1 {
+			logical.WriteString(line[:len(line)-1])
+			continue
+		}
+		logical.WriteString(line)
+
+		if err := add(logical.String()); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		logical.Reset()
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if logical.Len() > 0 {
+		if err := add(logical.String()); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// splitProperty splits a logical properties line into its unescaped key and value.
+func splitProperty(line string) (string, string, error) {
+	i := 0
+	for ; i < len(line); i++ {
+		if line[i] == '\\' && i+1 < len(line) {
+			i++
+			continue
+		}
+		if strings.IndexByte("=: \t\f", line[i]) >= 0 {
+			break
+		}
+	}
+	// The separator is optional white space followed by an optional = or :.
+	key, rest := line[:i], strings.TrimLeft(line[i:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+
+	key, err := unescapeProperty(key)
+	if err != nil {
+		return "", "", err
+	}
+	value, err := unescapeProperty(rest)
+	if err != nil {
+		return "", "", err
+	}
+	return key, value, nil
+}
+
+// unescapeProperty replaces the escape sequences in s.
+func unescapeProperty(s string) (string, error) {
+	if !strings.Contains(s, "\\") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+4 >= len(s) {
+				return "", fmt.Errorf("invalid unicode escape in %s", s)
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("invalid unicode escape in %s", s)
+			}
+			b.WriteRune(rune(r))
+			i += 4
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
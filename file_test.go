@@ -0,0 +1,511 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// writeFile writes content to a file named name in a temporary directory and returns its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+type fileConfig struct {
+	Host  string
+	Port  int `default:"80"`
+	Debug bool
+	DB    struct {
+		Host string
+		Port int
+	}
+}
+
+func TestParseFileYAML(t *testing.T) {
+	os.Clearenv()
+	path := writeFile(t, "config.yaml", `
+host: example.com
+port: 8080
+db:
+  host: db.example.com
+  port: 5432
+`)
+
+	var cfg fileConfig
+	if err := ParseFile("app", &cfg, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Host != "example.com" {
+		t.Fatalf("expected host to be example.com, got %s", cfg.Host)
+	}
+	if cfg.Port != 8080 {
+		t.Fatalf("expected port to be 8080, got %d", cfg.Port)
+	}
+	if cfg.DB.Host != "db.example.com" {
+		t.Fatalf("expected db host to be db.example.com, got %s", cfg.DB.Host)
+	}
+	if cfg.DB.Port != 5432 {
+		t.Fatalf("expected db port to be 5432, got %d", cfg.DB.Port)
+	}
+}
+
+func TestParseFileEnvOverride(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_DB_PORT", "6543")
+	path := writeFile(t, "config.yml", `
+host: example.com
+db:
+  port: 5432
+`)
+
+	var cfg fileConfig
+	if err := ParseFile("app", &cfg, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.DB.Port != 6543 {
+		t.Fatalf("expected db port to be 6543, got %d", cfg.DB.Port)
+	}
+	if cfg.Port != 80 {
+		t.Fatalf("expected port to be 80, got %d", cfg.Port)
+	}
+}
+
+func TestParseFileErrors(t *testing.T) {
+	tests := []struct {
+		description string
+		path        string
+	}{
+		{
+			description: "unsupported extension",
+			path:        writeFile(t, "config.txt", "host: example.com"),
+		},
+		{
+			description: "missing file",
+			path:        filepath.Join(t.TempDir(), "config.yaml"),
+		},
+		{
+			description: "invalid yaml",
+			path:        writeFile(t, "config.yaml", "host: [example.com"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var cfg fileConfig
+			if err := ParseFile("app", &cfg, tc.path); err == nil {
+				t.Fatal("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestParseFileJSON(t *testing.T) {
+	os.Clearenv()
+	path := writeFile(t, "config.json", `{
+	"host": "example.com",
+	"debug": true,
+	"db": {"host": "db.example.com", "port": 5432}
+}`)
+
+	var cfg fileConfig
+	if err := ParseFile("app", &cfg, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Host != "example.com" {
+		t.Fatalf("expected host to be example.com, got %s", cfg.Host)
+	}
+	if !cfg.Debug {
+		t.Fatal("expected debug to be true, got false")
+	}
+	if cfg.DB.Port != 5432 {
+		t.Fatalf("expected db port to be 5432, got %d", cfg.DB.Port)
+	}
+}
+
+func TestParseFileLists(t *testing.T) {
+	os.Clearenv()
+	path := writeFile(t, "config.json", `{
+	"hosts": ["a", "b"],
+	"tags": ["x,y", "z"],
+	"params": ["mode=fast", "level=2"],
+	"ports": [80, 443],
+	"empty": []
+}`)
+
+	var cfg struct {
+		Hosts  []string `sep:";"`
+		Tags   []string
+		Params map[string]string `pairsep:";" kvsep:"="`
+		Ports  []int
+		Empty  []string
+	}
+	if err := ParseFile("app", &cfg, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(cfg.Hosts, []string{"a", "b"}) {
+		t.Fatalf("expected hosts to be [a b], got %q", cfg.Hosts)
+	}
+	if !reflect.DeepEqual(cfg.Tags, []string{"x,y", "z"}) {
+		t.Fatalf("expected tags to be [x,y z], got %q", cfg.Tags)
+	}
+	if !reflect.DeepEqual(cfg.Params, map[string]string{"mode": "fast", "level": "2"}) {
+		t.Fatalf("expected params to be map[level:2 mode:fast], got %v", cfg.Params)
+	}
+	if !reflect.DeepEqual(cfg.Ports, []int{80, 443}) {
+		t.Fatalf("expected ports to be [80 443], got %v", cfg.Ports)
+	}
+	if len(cfg.Empty) != 0 {
+		t.Fatalf("expected empty to be empty, got %q", cfg.Empty)
+	}
+
+	values, err := ReadValues("app", strings.NewReader(`{"tags": ["x,y", "z"]}`), "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_TAGS"] != `x\,y,z` {
+		t.Fatalf(`expected tags to be x\,y,z, got %s`, values["APP_TAGS"])
+	}
+
+	source, err := ReadDocument("app", strings.NewReader(`{"hosts": ["a", "b"], "tags": ["x,y", "z"]}`), "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Hosts []string `sep:";"`
+		Tags  []string
+	}
+	if err := ParseSources("app", &doc, source); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(doc.Hosts, []string{"a", "b"}) || !reflect.DeepEqual(doc.Tags, []string{"x,y", "z"}) {
+		t.Fatalf("expected ReadDocument to keep the lists, got %q and %q", doc.Hosts, doc.Tags)
+	}
+}
+
+func TestParseFileEscapedSeparator(t *testing.T) {
+	os.Clearenv()
+	path := writeFile(t, "config.yaml", `
+dsns: 'a\|b|c'
+paths:
+  - 'C:\data'
+  - 'x|y'
+`)
+
+	var cfg struct {
+		DSNs  []string `delim:"|"`
+		Paths []string `delim:"|"`
+	}
+	if err := ParseFile("app", &cfg, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(cfg.DSNs, []string{"a|b", "c"}) {
+		t.Fatalf("expected dsns to be [a|b c], got %q", cfg.DSNs)
+	}
+	if !reflect.DeepEqual(cfg.Paths, []string{`C:\data`, "x|y"}) {
+		t.Fatalf(`expected paths to be [C:\data x|y], got %q`, cfg.Paths)
+	}
+}
+
+func TestParseFileListOfMaps(t *testing.T) {
+	os.Clearenv()
+	path := writeFile(t, "config.yaml", `
+servers:
+  - host: example.com
+  - port: 8080
+`)
+
+	var cfg struct {
+		Servers struct {
+			Host string
+			Port int
+		}
+	}
+	if err := ParseFile("app", &cfg, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Servers.Host != "example.com" {
+		t.Fatalf("expected servers host to be example.com, got %s", cfg.Servers.Host)
+	}
+	if cfg.Servers.Port != 8080 {
+		t.Fatalf("expected servers port to be 8080, got %d", cfg.Servers.Port)
+	}
+}
+
+func TestParseReader(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_HOST", "env.example.com")
+
+	spec := struct {
+		Host string
+		Port int `required:"true"`
+	}{}
+
+	r := strings.NewReader(`{"host": "example.com", "port": 9000}`)
+	if err := ParseReader("app", &spec, r, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Host != "env.example.com" {
+		t.Fatalf("expected host to be env.example.com, got %s", spec.Host)
+	}
+	if spec.Port != 9000 {
+		t.Fatalf("expected port to be 9000, got %d", spec.Port)
+	}
+
+	if err := ParseReader("app", &spec, r, "xml"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestParseFS(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_DB_PORT", "6543")
+	fsys := fstest.MapFS{
+		"config/defaults.yaml": {Data: []byte("host: example.com\ndb:\n  port: 5432\n")},
+	}
+
+	var cfg fileConfig
+	if err := ParseFS("app", &cfg, fsys, "config/defaults.yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Host != "example.com" {
+		t.Fatalf("expected host to be example.com, got %s", cfg.Host)
+	}
+	if cfg.DB.Port != 6543 {
+		t.Fatalf("expected db port to be 6543, got %d", cfg.DB.Port)
+	}
+
+	if err := ParseSources("app", &cfg, FSSource("app", fsys, "config/missing.yaml")); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestParseUserConfig(t *testing.T) {
+	home, system := t.TempDir(), t.TempDir()
+	for path, content := range map[string]string{
+		filepath.Join(home, "mytool", "config.toml"):   "host = \"home.example.com\"\n",
+		filepath.Join(system, "mytool", "config.yaml"): "host: system.example.com\n",
+		filepath.Join(system, "other", "config.json"):  `{"host": "other.example.com", "port": 8080}`,
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	os.Clearenv()
+	os.Setenv("HOME", home)
+	os.Setenv("XDG_CONFIG_HOME", home)
+	os.Setenv("XDG_CONFIG_DIRS", system)
+
+	tests := []struct {
+		description string
+		app         string
+		host        string
+		port        int
+	}{
+		{description: "user config directory", app: "mytool", host: "home.example.com", port: 80},
+		{description: "system config directory", app: "other", host: "other.example.com", port: 8080},
+		{description: "no config file", app: "missing", host: "", port: 80},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var cfg fileConfig
+			if err := ParseUserConfig("app", &cfg, tc.app); err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Host != tc.host {
+				t.Fatalf("expected host to be %q, got %q", tc.host, cfg.Host)
+			}
+			if cfg.Port != tc.port {
+				t.Fatalf("expected port to be %d, got %d", tc.port, cfg.Port)
+			}
+		})
+	}
+}
+
+func TestParseFileTOML(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_DB_HOST", "env.example.com")
+	path := writeFile(t, "config.toml", `
+host = "example.com"
+port = 8080
+
+[db]
+host = "db.example.com"
+port = 5432
+`)
+
+	var cfg fileConfig
+	if err := ParseFile("app", &cfg, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Port != 8080 {
+		t.Fatalf("expected port to be 8080, got %d", cfg.Port)
+	}
+	if cfg.DB.Host != "env.example.com" {
+		t.Fatalf("expected db host to be env.example.com, got %s", cfg.DB.Host)
+	}
+	if cfg.DB.Port != 5432 {
+		t.Fatalf("expected db port to be 5432, got %d", cfg.DB.Port)
+	}
+}
+
+func TestParseFileINI(t *testing.T) {
+	os.Clearenv()
+	path := writeFile(t, "config.ini", `
+; global settings
+host = example.com
+debug = true
+
+[db]
+host = "db.example.com"
+port = 5432
+`)
+
+	var cfg fileConfig
+	if err := ParseFile("app", &cfg, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Host != "example.com" {
+		t.Fatalf("expected host to be example.com, got %s", cfg.Host)
+	}
+	if !cfg.Debug {
+		t.Fatal("expected debug to be true, got false")
+	}
+	if cfg.DB.Host != "db.example.com" {
+		t.Fatalf("expected db host to be db.example.com, got %s", cfg.DB.Host)
+	}
+	if cfg.DB.Port != 5432 {
+		t.Fatalf("expected db port to be 5432, got %d", cfg.DB.Port)
+	}
+}
+
+func TestDecodeINI(t *testing.T) {
+	doc, err := decodeINI([]byte("[db.replica]\nhost: replica.example.com\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := documentValues{MapSource: make(MapSource), lists: make(map[string][]string)}
+	flatten("app", doc, values)
+	if values.MapSource["APP_DB_REPLICA_HOST"] != "replica.example.com" {
+		t.Fatalf("expected replica host to be replica.example.com, got %s", values.MapSource["APP_DB_REPLICA_HOST"])
+	}
+
+	for _, data := range []string{"[db\nport=1", "port"} {
+		if _, err := decodeINI([]byte(data)); err == nil {
+			t.Fatalf("expected error decoding %q, got nil", data)
+		}
+	}
+}
+
+func TestParseFileHCL(t *testing.T) {
+	os.Clearenv()
+	path := writeFile(t, "config.hcl", `
+host = "example.com"
+
+db {
+  host = "db.example.com"
+  port = 5432
+}
+`)
+
+	spec := struct {
+		Host string
+		DB   struct {
+			Host string
+			Port int
+			User string `required:"true"`
+		}
+	}{}
+
+	err := ParseFile("app", &spec, path)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	os.Setenv("APP_DB_USER", "root")
+	if err := ParseFile("app", &spec, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Host != "example.com" {
+		t.Fatalf("expected host to be example.com, got %s", spec.Host)
+	}
+	if spec.DB.Port != 5432 {
+		t.Fatalf("expected db port to be 5432, got %d", spec.DB.Port)
+	}
+	if spec.DB.User != "root" {
+		t.Fatalf("expected db user to be root, got %s", spec.DB.User)
+	}
+}
+
+func TestParseFileProperties(t *testing.T) {
+	os.Clearenv()
+	path := writeFile(t, "application.properties", `
+# JVM style properties
+host = example.com
+debug: true
+db.host db.example.com
+db.port=\
+  5432
+`)
+
+	var cfg fileConfig
+	if err := ParseFile("app", &cfg, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Host != "example.com" {
+		t.Fatalf("expected host to be example.com, got %s", cfg.Host)
+	}
+	if !cfg.Debug {
+		t.Fatal("expected debug to be true, got false")
+	}
+	if cfg.DB.Host != "db.example.com" {
+		t.Fatalf("expected db host to be db.example.com, got %s", cfg.DB.Host)
+	}
+	if cfg.DB.Port != 5432 {
+		t.Fatalf("expected db port to be 5432, got %d", cfg.DB.Port)
+	}
+}
+
+func TestDecodePropertiesEscapes(t *testing.T) {
+	doc, err := decodeProperties([]byte(`path\ name=C:\\data
+greeting = caf\u00e9\tbar`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if doc["path name"] != `C:\data` {
+		t.Fatalf(`expected path name to be C:\data, got %s`, doc["path name"])
+	}
+	if doc["greeting"] != "café\tbar" {
+		t.Fatalf("expected greeting to be café\\tbar, got %s", doc["greeting"])
+	}
+
+	if _, err := decodeProperties([]byte(`key=\u00`)); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/spf13/pflag"
+)
+
+// boundFlags holds the flags bound by BindFlags and BindPFlags keyed by the config they were bound to.
+// The values are visitFunc functions visiting the flags set on the command line.
+var boundFlags sync.Map
+
+// visitFunc calls fn for every flag that was set on the command line.
+type visitFunc func(fn func(name string, value any))
+
+// BindFlags registers a flag on fs for every field of cfg, cfg must be a pointer to struct. The flag name
+// is the field key without the prefix, lower cased and with underscores replaced by dashes. For example,
+// the field Port in the nested struct DB is bound to the flag "db-port". The default tag is shown as the
+// default value of the flag and the usage tag as its help text. Maps of structs and pointers to structs
+// are not bound. Once fs is parsed, Parse resolves the fields of cfg in the order flag, environment
+// variable, default.
+func BindFlags(fs *flag.FlagSet, cfg any) error {
+	fields, err := extractFields("", cfg)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		if isStructMap(field) || isStructPtr(field) {
+			continue
+		}
+		value := newFlagValue(field)
+		fs.Var(value, flagName(field.Key), field.Tags.Get("usage"))
+	}
+	boundFlags.Store(cfg, visitFunc(func(fn func(string, any)) {
+		fs.Visit(func(f *flag.Flag) { fn(f.Name, f.Value) })
+	}))
+	return nil
+}
+
+// BindPFlags is like BindFlags but registers the flags on a pflag.FlagSet, for example the flags of a
+// Cobra command, so that --db-port overrides APP_DB_PORT.
+func BindPFlags(fs *pflag.FlagSet, cfg any) error {
+	fields, err := extractFields("", cfg)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		if isStructMap(field) || isStructPtr(field) {
+			continue
+		}
+		value := newFlagValue(field)
+		f := fs.VarPF(value, flagName(field.Key), "", field.Tags.Get("usage"))
+		if _, ok := value.(*boolFlagValue); ok {
+			f.NoOptDefVal = "true"
+		}
+	}
+	boundFlags.Store(cfg, visitFunc(func(fn func(string, any)) {
+		fs.Visit(func(f *pflag.Flag) { fn(f.Name, f.Value) })
+	}))
+	return nil
+}
+
+// flagName converts a field key without prefix to a flag name.
+func flagName(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+}
+
+// flagSource returns a Source that looks up the values of the flags bound to cfg that were set on
+// the command line. It returns nil if no flags are bound to cfg.
+func flagSource(prefix string, cfg any) Source {
+	visit, ok := boundFlags.Load(cfg)
+	if !ok {
+		return nil
+	}
+
+	set := make(map[string]string)
+	visit.(visitFunc)(func(name string, value any) {
+		switch v := value.(type) {
+		case *flagValue:
+			set[name] = v.value
+		case *boolFlagValue:
+			set[name] = v.value
+		}
+	})
+
+	if prefix != "" {
+		prefix = strings.ToUpper(prefix) + "_"
+	}
+	return SourceFunc(func(key string) (string, bool, error) {
+		value, ok := set[flagName(strings.TrimPrefix(key, prefix))]
+		return value, ok, nil
+	})
+}
+
+// flagValue is a flag.Value and pflag.Value holding the raw value of a field. The value is validated
+// when set but only assigned to the field when the config is parsed.
+type flagValue struct {
+	typ    reflect.Type
+	tags   reflect.StructTag
+	value  string
+	secret bool
+}
+
+// newFlagValue returns the flag value for field, the default tag is used as the initial value.
+func newFlagValue(field Field) pflag.Value {
+	value := flagValue{typ: field.Field.Type(), tags: field.Tags, value: field.Default, secret: field.Secret}
+	if t := field.Field.Type(); t.Kind() == reflect.Bool || t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Bool {
+		return &boolFlagValue{value}
+	}
+	return &value
+}
+
+// String returns the value, or **** if the field holds a secret, so that the default value of a secret
+// is not shown in the usage.
+func (v *flagValue) String() string {
+	if v.secret && v.value != "" {
+		return redacted
+	}
+	return v.value
+}
+
+func (v *flagValue) Set(value string) error {
+	// Parse into a scratch value so invalid input is reported by the flag package.
+	if err := parseField(value, reflect.New(v.typ).Elem(), v.tags); err != nil {
+		return err
+	}
+	v.value = value
+	return nil
+}
+
+// Type returns the name of the field type, it is shown in the pflag usage.
+func (v *flagValue) Type() string {
+	if v.typ == nil {
+		return ""
+	}
+	if name := v.typ.Name(); name != "" {
+		return strings.ToLower(name)
+	}
+	return v.typ.String()
+}
+
+// boolFlagValue is a flagValue for bool fields, it allows the flag to be set without a value.
+type boolFlagValue struct {
+	flagValue
+}
+
+func (v *boolFlagValue) IsBoolFlag() bool {
+	return true
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+type flagConfig struct {
+	Host  string `default:"localhost" usage:"address to listen on"`
+	Port  int    `default:"8080"`
+	Debug bool
+	DB    struct {
+		Port int `required:"true"`
+	}
+}
+
+func TestBindFlags(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_HOST", "env.example.com")
+	os.Setenv("APP_PORT", "9000")
+
+	var cfg flagConfig
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	if err := BindFlags(fs, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Parse([]string{"-port", "9090", "-debug", "-db-port", "5432"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Parse("app", &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Host != "env.example.com" {
+		t.Fatalf("expected host to be env.example.com, got %s", cfg.Host)
+	}
+	if cfg.Port != 9090 {
+		t.Fatalf("expected port to be 9090, got %d", cfg.Port)
+	}
+	if !cfg.Debug {
+		t.Fatal("expected debug to be true, got false")
+	}
+	if cfg.DB.Port != 5432 {
+		t.Fatalf("expected db port to be 5432, got %d", cfg.DB.Port)
+	}
+}
+
+func TestBindFlagsUsage(t *testing.T) {
+	var cfg flagConfig
+	var out bytes.Buffer
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	fs.SetOutput(&out)
+	if err := BindFlags(fs, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	fs.PrintDefaults()
+
+	usage := out.String()
+	if !strings.Contains(usage, "address to listen on (default localhost)") {
+		t.Fatalf("expected usage to describe host, got %s", usage)
+	}
+
+	if err := fs.Parse([]string{"-port", "not_a_number"}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestBindFlagsInvalidConfig(t *testing.T) {
+	var cfg flagConfig
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	if err := BindFlags(fs, cfg); err != ErrInvalidConfig {
+		t.Fatalf("expected ErrInvalidConfig, got %v", err)
+	}
+}
+
+func TestBindPFlags(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_DB_PORT", "5432")
+	os.Setenv("APP_PORT", "9000")
+
+	var cfg flagConfig
+	fs := pflag.NewFlagSet("app", pflag.ContinueOnError)
+	if err := BindPFlags(fs, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Parse([]string{"--db-port", "6543", "--debug"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Parse("app", &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Host != "localhost" {
+		t.Fatalf("expected host to be localhost, got %s", cfg.Host)
+	}
+	if cfg.Port != 9000 {
+		t.Fatalf("expected port to be 9000, got %d", cfg.Port)
+	}
+	if !cfg.Debug {
+		t.Fatal("expected debug to be true, got false")
+	}
+	if cfg.DB.Port != 6543 {
+		t.Fatalf("expected db port to be 6543, got %d", cfg.DB.Port)
+	}
+
+	if usage := fs.FlagUsages(); !strings.Contains(usage, "--port int") {
+		t.Fatalf("expected usage to describe port, got %s", usage)
+	}
+}
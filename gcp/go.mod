@@ -0,0 +1,16 @@
+module github.com/josemukorivo/config/gcp
+
+go 1.22
+
+require (
+	github.com/josemukorivo/config v0.0.0-20261016120655-98cbede698f8
+	golang.org/x/oauth2 v0.21.0
+)
+
+require (
+	cloud.google.com/go/compute/metadata v0.3.0 // indirect
+	github.com/BurntSushi/toml v1.4.0 // indirect
+	github.com/hashicorp/hcl v1.0.0 // indirect
+	github.com/spf13/pflag v1.0.5 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
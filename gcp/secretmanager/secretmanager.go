@@ -0,0 +1,186 @@
+// Package secretmanager resolves references to Google Cloud Secret Manager secrets in config values.
+// The value of a field marked as a secret, like "projects/my-project/secrets/db-password", is replaced
+// by the payload of the latest version of the secret, so secret values never pass through the
+// environment of the process. Requests are authenticated with Application Default Credentials.
+//
+//	source := secretmanager.Resolve(config.EnvSource())
+//	err := config.ParseSources("app", &cfg, source)
+package secretmanager
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/josemukorivo/config"
+	"golang.org/x/oauth2/google"
+)
+
+// scope is the OAuth2 scope of the Secret Manager API.
+const scope = "https://www.googleapis.com/auth/cloud-platform"
+
+// reference matches the resource name of a secret, optionally followed by a version.
+var reference = regexp.MustCompile(`^projects/[^/]+/secrets/[^/]+(/versions/[^/]+)?$`)
+
+// Option configures the Source.
+type Option func(*source)
+
+// WithHTTPClient uses the given HTTP client to call the Secret Manager API instead of a client
+// authenticated with Application Default Credentials.
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *source) {
+		s.client = client
+	}
+}
+
+// WithEndpoint sets the base URL of the Secret Manager API, it defaults to
+// https://secretmanager.googleapis.com.
+func WithEndpoint(endpoint string) Option {
+	return func(s *source) {
+		s.endpoint = strings.TrimSuffix(endpoint, "/")
+	}
+}
+
+// WithTimeout bounds the time spent accessing each secret, it defaults to 10 seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(s *source) {
+		s.timeout = d
+	}
+}
+
+type source struct {
+	source   config.Source
+	endpoint string
+	client   *http.Client
+	timeout  time.Duration
+
+	mu      sync.Mutex
+	secrets map[string]string
+}
+
+// Resolve returns a config.Source that looks up keys in inner and resolves the values of the secret
+// fields referencing a secret, the fields config.Field marks as secrets, including the fields of
+// nested struct pointers, maps of structs and registered implementations. A reference is the resource
+// name of a secret, projects/*/secrets/*, which resolves to its latest version, or of a secret version,
+// projects/*/secrets/*/versions/*. The values of other fields, and of the keys looked up outside of a
+// field like the references of the expand tag, are returned as is, so a value that only looks like a
+// reference is never sent to Secret Manager. Each secret is accessed once and cached.
+func Resolve(inner config.Source, opts ...Option) config.Source {
+	s := &source{
+		source:   inner,
+		endpoint: "https://secretmanager.googleapis.com",
+		timeout:  10 * time.Second,
+		secrets:  make(map[string]string),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Lookup looks up key in the wrapped source, the value is not resolved.
+func (s *source) Lookup(key string) (string, bool, error) {
+	return s.source.Lookup(key)
+}
+
+// Keys returns the keys of the wrapped source if it is a config.KeySource, so that maps of structs are
+// filled from it.
+func (s *source) Keys() ([]string, error) {
+	if ks, ok := s.source.(config.KeySource); ok {
+		return ks.Keys()
+	}
+	return nil, nil
+}
+
+// LookupField looks up key in the wrapped source and resolves the value if the field is a secret and
+// the value references a secret.
+func (s *source) LookupField(field config.Field, key string) (string, bool, error) {
+	value, ok, err := s.source.Lookup(key)
+	if err != nil || !ok || !field.Secret || !reference.MatchString(value) {
+		return value, ok, err
+	}
+
+	payload, err := s.access(value)
+	if err != nil {
+		return "", false, err
+	}
+	return payload, true, nil
+}
+
+// access returns the payload of the secret version referenced by name.
+func (s *source) access(name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if secret, ok := s.secrets[name]; ok {
+		return secret, nil
+	}
+
+	version := name
+	if reference.FindStringSubmatch(name)[1] == "" {
+		version += "/versions/latest"
+	}
+
+	if s.client == nil {
+		// The client refreshes its token with the context it is created with, which must outlive the
+		// request.
+		client, err := google.DefaultClient(context.Background(), scope)
+		if err != nil {
+			return "", fmt.Errorf("secretmanager: finding default credentials: %w", err)
+		}
+		s.client = client
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	data, err := s.call(ctx, version)
+	if err != nil {
+		return "", fmt.Errorf("secretmanager: accessing %s: %w", version, err)
+	}
+
+	secret := string(data)
+	s.secrets[name] = secret
+	return secret, nil
+}
+
+// call accesses the secret version and returns its decoded payload.
+func (s *source) call(ctx context.Context, version string) ([]byte, error) {
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, s.endpoint+"/v1/"+version+":access", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var e struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.NewDecoder(io.LimitReader(res.Body, 4096)).Decode(&e)
+		return nil, fmt.Errorf("unexpected status %s: %s", res.Status, e.Error.Message)
+	}
+
+	var resp struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(resp.Payload.Data)
+}
@@ -0,0 +1,110 @@
+package secretmanager
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/josemukorivo/config"
+)
+
+type Config struct {
+	Host string
+	DB   struct {
+		Password config.Secret
+		User     string `secret:"true"`
+		Name     string
+	}
+	Replica *struct {
+		Password config.Secret
+	}
+	Tenants map[string]struct {
+		Token string `secret:"true"`
+	}
+}
+
+func newServer(t *testing.T, calls *int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		switch r.URL.Path {
+		case "/v1/projects/my-project/secrets/db-password/versions/latest:access":
+			json.NewEncoder(w).Encode(map[string]any{
+				"name":    "projects/123/secrets/db-password/versions/2",
+				"payload": map[string]string{"data": base64.StdEncoding.EncodeToString([]byte("s3cr3t"))},
+			})
+		case "/v1/projects/my-project/secrets/db-user/versions/1:access":
+			json.NewEncoder(w).Encode(map[string]any{
+				"name":    "projects/123/secrets/db-user/versions/1",
+				"payload": map[string]string{"data": base64.StdEncoding.EncodeToString([]byte("admin"))},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": 404, "message": "Secret not found"}})
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestResolve(t *testing.T) {
+	var calls int
+	server := newServer(t, &calls)
+
+	os.Clearenv()
+	os.Setenv("APP_HOST", "example.com")
+	os.Setenv("APP_DB_PASSWORD", "projects/my-project/secrets/db-password")
+	os.Setenv("APP_DB_USER", "projects/my-project/secrets/db-user/versions/1")
+	os.Setenv("APP_DB_NAME", "projects/my-project/secrets/db-name")
+	os.Setenv("APP_REPLICA_PASSWORD", "projects/my-project/secrets/db-password")
+	os.Setenv("APP_TENANTS_ACME_TOKEN", "projects/my-project/secrets/db-user/versions/1")
+
+	var cfg Config
+	source := Resolve(config.EnvSource(), WithEndpoint(server.URL), WithHTTPClient(server.Client()))
+	for i := 0; i < 2; i++ {
+		cfg = Config{}
+		if err := config.ParseSources("app", &cfg, source); err != nil {
+			t.Fatal(err)
+		}
+
+		if cfg.Host != "example.com" {
+			t.Fatalf("expected host to be example.com, got %s", cfg.Host)
+		}
+		if cfg.DB.Password.Value() != "s3cr3t" {
+			t.Fatalf("expected db password to be s3cr3t, got %s", cfg.DB.Password.Value())
+		}
+		if cfg.DB.User != "admin" {
+			t.Fatalf("expected db user to be admin, got %s", cfg.DB.User)
+		}
+		if cfg.DB.Name != "projects/my-project/secrets/db-name" {
+			t.Fatalf("expected db name not to be resolved, got %s", cfg.DB.Name)
+		}
+		if cfg.Replica == nil || cfg.Replica.Password.Value() != "s3cr3t" {
+			t.Fatalf("expected the replica password in the struct pointer to be s3cr3t, got %+v", cfg.Replica)
+		}
+		if cfg.Tenants["acme"].Token != "admin" {
+			t.Fatalf("expected the acme token in the map of structs to be admin, got %+v", cfg.Tenants)
+		}
+	}
+
+	if calls != 2 {
+		t.Fatalf("expected the secrets to be accessed once, got %d calls", calls)
+	}
+}
+
+func TestResolveError(t *testing.T) {
+	var calls int
+	server := newServer(t, &calls)
+
+	os.Clearenv()
+	os.Setenv("APP_DB_PASSWORD", "projects/my-project/secrets/missing")
+
+	var cfg Config
+	source := Resolve(config.EnvSource(), WithEndpoint(server.URL), WithHTTPClient(server.Client()))
+	if err := config.ParseSources("app", &cfg, source); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,147 @@
+// Package storage provides a config.Source backed by a config document stored in Google Cloud Storage.
+// Requests are authenticated with Application Default Credentials.
+//
+//	source := storage.New("app", "gs://my-bucket/app/config.yaml")
+//	err := config.ParseSources("app", &cfg, source, config.EnvSource())
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/josemukorivo/config"
+	"golang.org/x/oauth2/google"
+)
+
+// scope is the OAuth2 scope to read Cloud Storage objects.
+const scope = "https://www.googleapis.com/auth/devstorage.read_only"
+
+// Option configures the Source.
+type Option func(*source)
+
+// WithFormat sets the format of the document, for example config.FormatYAML. By default the format is
+// detected from the extension of the object name.
+func WithFormat(format string) Option {
+	return func(s *source) {
+		s.format = format
+	}
+}
+
+// WithHTTPClient uses the given HTTP client to call the Cloud Storage API instead of a client
+// authenticated with Application Default Credentials.
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *source) {
+		s.client = client
+	}
+}
+
+// WithEndpoint sets the base URL of the Cloud Storage API, it defaults to
+// https://storage.googleapis.com.
+func WithEndpoint(endpoint string) Option {
+	return func(s *source) {
+		s.endpoint = strings.TrimSuffix(endpoint, "/")
+	}
+}
+
+// WithTimeout bounds the time spent downloading the document, it defaults to 10 seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(s *source) {
+		s.timeout = d
+	}
+}
+
+type source struct {
+	prefix   string
+	url      string
+	format   string
+	endpoint string
+	client   *http.Client
+	timeout  time.Duration
+}
+
+// New returns a config.Source that looks up the keys of the config document at the gs://bucket/object
+// URL. Keys are prefixed with prefix the same way config.ReadValues does, so with the prefix "app" the
+// key "host" in the "db" map maps to APP_DB_HOST. The document is downloaded on the first lookup.
+func New(prefix, url string, opts ...Option) config.Source {
+	s := &source{
+		prefix:   prefix,
+		url:      url,
+		endpoint: "https://storage.googleapis.com",
+		timeout:  10 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return config.LoadOnce(s.load)
+}
+
+// load downloads and decodes the document.
+func (s *source) load() (config.MapSource, error) {
+	u, err := url.Parse(s.url)
+	if err != nil || u.Scheme != "gs" || u.Host == "" || strings.Trim(u.Path, "/") == "" {
+		return nil, fmt.Errorf("storage: invalid URL %q, expected gs://bucket/object", s.url)
+	}
+	object := strings.TrimPrefix(u.Path, "/")
+
+	format := s.format
+	if format == "" {
+		var ok bool
+		if format, ok = config.DetectFormat(object); !ok {
+			return nil, fmt.Errorf("storage: unknown format of %s, use WithFormat", s.url)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	client := s.client
+	if client == nil {
+		client, err = google.DefaultClient(ctx, scope)
+		if err != nil {
+			return nil, fmt.Errorf("storage: finding default credentials: %w", err)
+		}
+	}
+
+	body, err := s.download(ctx, client, u.Host, object)
+	if err != nil {
+		return nil, fmt.Errorf("storage: downloading %s: %w", s.url, err)
+	}
+	defer body.Close()
+
+	values, err := config.ReadValues(s.prefix, body, format)
+	if err != nil {
+		return nil, fmt.Errorf("storage: decoding %s: %w", s.url, err)
+	}
+	return values, nil
+}
+
+// download returns the content of the object.
+func (s *source) download(ctx context.Context, client *http.Client, bucket, object string) (io.ReadCloser, error) {
+	u := fmt.Sprintf("%s/storage/v1/b/%s/o/%s?alt=media", s.endpoint, url.PathEscape(bucket), url.PathEscape(object))
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		var e struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.NewDecoder(io.LimitReader(res.Body, 4096)).Decode(&e)
+		return nil, fmt.Errorf("unexpected status %s: %s", res.Status, e.Error.Message)
+	}
+	return res.Body, nil
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/josemukorivo/config"
+)
+
+type Config struct {
+	Host string
+	DB   struct {
+		Port int
+	}
+}
+
+func TestSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/storage/v1/b/my-bucket/o/app%2Fconfig.yaml" || r.URL.Query().Get("alt") != "media" {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": 404, "message": "No such object"}})
+			return
+		}
+		w.Write([]byte("host: gcs.example.com\ndb:\n  port: 5432\n"))
+	}))
+	defer server.Close()
+
+	os.Clearenv()
+	os.Setenv("APP_HOST", "example.com")
+
+	var cfg Config
+	source := New("app", "gs://my-bucket/app/config.yaml", WithEndpoint(server.URL), WithHTTPClient(server.Client()))
+	if err := config.ParseSources("app", &cfg, source, config.EnvSource()); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Host != "example.com" {
+		t.Fatalf("expected host to be example.com, got %s", cfg.Host)
+	}
+	if cfg.DB.Port != 5432 {
+		t.Fatalf("expected db port to be 5432, got %d", cfg.DB.Port)
+	}
+
+	for _, url := range []string{"s3://my-bucket/app/config.yaml", "gs://my-bucket/app/config", "gs://my-bucket/missing.yaml"} {
+		var other Config
+		source := New("app", url, WithEndpoint(server.URL), WithHTTPClient(server.Client()))
+		if err := config.ParseSources("app", &other, source); err == nil {
+			t.Fatalf("expected error for %s, got nil", url)
+		}
+	}
+}
@@ -2,4 +2,9 @@ module github.com/josemukorivo/config
 
 go 1.22
 
-require github.com/joho/godotenv v1.5.1
+require (
+	github.com/BurntSushi/toml v1.4.0
+	github.com/hashicorp/hcl v1.0.0
+	github.com/spf13/pflag v1.0.5
+	gopkg.in/yaml.v3 v3.0.1
+)
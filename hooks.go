@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sync"
+)
+
+// DecodeHook converts the value from to the type to. It returns a nil value, and no error, to leave the
+// conversion to the next hooks and the built-in parsing. The returned value must be assignable or
+// convertible to to.
+type DecodeHook func(from string, to reflect.Type) (any, error)
+
+var (
+	decodeHooksMu sync.RWMutex
+	decodeHooks   []DecodeHook
+)
+
+// AddDecodeHook adds hook to the hooks that run, in the order they were added, before the built-in
+// parsing of every field, item of a slice and key or value of a map. The first hook returning a value
+// sets the field. Hooks let applications add conversions globally, for example "on" and "off" to bool,
+// without implementing Setter on every type:
+//
+//	config.AddDecodeHook(func(from string, to reflect.Type) (any, error) {
+//		if to.Kind() != reflect.Bool {
+//			return nil, nil
+//		}
+//		switch strings.ToLower(from) {
+//		case "on":
+//			return true, nil
+//		case "off":
+//			return false, nil
+//		}
+//		return nil, nil
+//	})
+//
+// AddDecodeHook is usually called from an init function.
+func AddDecodeHook(hook DecodeHook) {
+	decodeHooksMu.Lock()
+	defer decodeHooksMu.Unlock()
+	decodeHooks = append(decodeHooks, hook)
+}
+
+// runDecodeHooks runs the decode hooks on value and sets field to the value returned by the first hook
+// returning one. It reports whether a hook set the field.
+func runDecodeHooks(value string, field reflect.Value) (bool, error) {
+	decodeHooksMu.RLock()
+	hooks := decodeHooks
+	decodeHooksMu.RUnlock()
+
+	t := field.Type()
+	for _, hook := range hooks {
+		v, err := hook(value, t)
+		if err != nil {
+			return false, err
+		}
+		if v == nil {
+			continue
+		}
+		rv := reflect.ValueOf(v)
+		switch {
+		case rv.Type().AssignableTo(t):
+			field.Set(rv)
+		case rv.Type().ConvertibleTo(t):
+			field.Set(rv.Convert(t))
+		default:
+			return false, fmt.Errorf("decode hook returned a %s, not a %s", rv.Type(), t)
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+var (
+	deprecationHandlerMu sync.RWMutex
+	deprecationHandler   func(key, message string)
+)
+
+// SetDeprecationHandler sets the function called with the key and the deprecated tag of every field
+// tagged deprecated whose value is set in a source, the default value does not count. The tag tells
+// what to do instead, so that variables can be renamed gradually:
+//
+//	type Config struct {
+//		Timeout     time.Duration `deprecated:"use APP_HTTP_TIMEOUT"`
+//		HTTPTimeout time.Duration `env:"app_http_timeout"`
+//	}
+//
+// The default handler, restored by passing nil, logs a warning with slog.Default.
+func SetDeprecationHandler(handler func(key, message string)) {
+	deprecationHandlerMu.Lock()
+	defer deprecationHandlerMu.Unlock()
+	deprecationHandler = handler
+}
+
+// warnDeprecated calls the deprecation handler for the key of a deprecated field.
+func warnDeprecated(key, message string) {
+	deprecationHandlerMu.RLock()
+	handler := deprecationHandler
+	deprecationHandlerMu.RUnlock()
+
+	if handler == nil {
+		slog.Warn("config: deprecated key is set", "key", key, "deprecated", message)
+		return
+	}
+	handler(key, message)
+}
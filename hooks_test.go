@@ -0,0 +1,124 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// percent is a custom type without a Setter, set by a decode hook.
+type percent float64
+
+func TestDecodeHook(t *testing.T) {
+	hooks := decodeHooks
+	defer func() { decodeHooks = hooks }()
+
+	AddDecodeHook(func(from string, to reflect.Type) (any, error) {
+		if to.Kind() != reflect.Bool {
+			return nil, nil
+		}
+		switch strings.ToLower(from) {
+		case "on":
+			return true, nil
+		case "off":
+			return false, nil
+		}
+		return nil, nil
+	})
+	AddDecodeHook(func(from string, to reflect.Type) (any, error) {
+		if to != reflect.TypeOf(percent(0)) {
+			return nil, nil
+		}
+		var v float64
+		if _, err := fmt.Sscanf(from, "%g%%", &v); err != nil {
+			return nil, err
+		}
+		return v / 100, nil
+	})
+
+	os.Clearenv()
+	os.Setenv("APP_DEBUG", "on")
+	os.Setenv("APP_CACHE", "true")
+	os.Setenv("APP_FEATURES", "on,off,ON")
+	os.Setenv("APP_RATIO", "25%")
+
+	spec := struct {
+		Debug    bool
+		Cache    bool
+		Features []bool
+		Ratio    percent `env:"app_ratio"`
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if !spec.Debug || !spec.Cache {
+		t.Fatalf("expected debug and cache to be true, got %v and %v", spec.Debug, spec.Cache)
+	}
+	if !reflect.DeepEqual(spec.Features, []bool{true, false, true}) {
+		t.Fatalf("expected features to be on, off and on, got %v", spec.Features)
+	}
+	if spec.Ratio != 0.25 {
+		t.Fatalf("expected ratio to be 0.25, got %v", spec.Ratio)
+	}
+
+	os.Setenv("APP_RATIO", "half")
+	var fieldErr *FieldError
+	if err := Parse("app", &spec); !errors.As(err, &fieldErr) {
+		t.Fatalf("expected a FieldError from the hook, got %v", err)
+	}
+}
+
+func TestDecodeHookWrongType(t *testing.T) {
+	hooks := decodeHooks
+	defer func() { decodeHooks = hooks }()
+
+	AddDecodeHook(func(from string, to reflect.Type) (any, error) {
+		return []string{from}, nil
+	})
+
+	os.Clearenv()
+	os.Setenv("APP_PORT", "8080")
+	spec := struct {
+		Port int
+	}{}
+	if err := Parse("app", &spec); err == nil || !strings.Contains(err.Error(), "decode hook returned a []string, not a int") {
+		t.Fatalf("expected the hook type error, got %v", err)
+	}
+}
+
+func TestDeprecationHandler(t *testing.T) {
+	type deprecation struct{ key, message string }
+	var warnings []deprecation
+	SetDeprecationHandler(func(key, message string) {
+		warnings = append(warnings, deprecation{key, message})
+	})
+	defer SetDeprecationHandler(nil)
+
+	os.Clearenv()
+	os.Setenv("APP_TIMEOUT", "5s")
+	os.Setenv("DB_URL", "postgres://db")
+
+	spec := struct {
+		Timeout     string `deprecated:"use APP_HTTP_TIMEOUT"`
+		HTTPTimeout string `env:"app_http_timeout"`
+		DBURL       string `env:"db_url" deprecated:"use DATABASE_URL"`
+		Retries     int    `deprecated:"retries are automatic" default:"3"`
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Timeout != "5s" {
+		t.Fatalf("expected timeout to be 5s, got %s", spec.Timeout)
+	}
+	expected := []deprecation{{"APP_TIMEOUT", "use APP_HTTP_TIMEOUT"}, {"DB_URL", "use DATABASE_URL"}}
+	if !reflect.DeepEqual(warnings, expected) {
+		t.Fatalf("expected the warnings to be %v, got %v", expected, warnings)
+	}
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"log/slog"
+	"reflect"
+	"sort"
+)
+
+// LogValue returns the fields of cfg, a struct or a pointer to struct, as a slog group so the effective
+// config can be logged in one line. Nested structs and maps of structs are nested groups keyed by the
+// field names and the fields holding secrets, see Field.Secret, are masked with ****.
+//
+//	slog.Info("config loaded", "config", config.LogValue(&cfg))
+func LogValue(cfg any) slog.Value {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return slog.AnyValue(cfg)
+	}
+	return slog.GroupValue(logAttrs(nil, v)...)
+}
+
+// logAttrs appends an attribute for every field of the struct v to attrs. The fields of embedded
+// structs are inlined unless they are tagged with prefix:"true", like for Parse.
+func logAttrs(attrs []slog.Attr, v reflect.Value) []slog.Attr {
+	t := v.Type()
+	for i := range v.NumField() {
+		sf, f, tags := t.Field(i), v.Field(i), fieldTags(t.Field(i))
+		if sf.Anonymous && isStruct(f, tags) && !isTrue(tags.Get("prefix")) {
+			attrs = logAttrs(attrs, f)
+			continue
+		}
+		if !f.CanInterface() || isIgnored(sf) {
+			continue
+		}
+		attrs = append(attrs, slog.Attr{Key: sf.Name, Value: logValue(f, tags)})
+	}
+	return attrs
+}
+
+// logValue returns the value of a field with the struct tags tags.
+func logValue(f reflect.Value, tags reflect.StructTag) slog.Value {
+	if isSecret(f.Type(), tags) {
+		return slog.StringValue(redacted)
+	}
+	if (f.Kind() == reflect.Ptr || f.Kind() == reflect.Interface) && f.IsNil() {
+		return slog.AnyValue(nil)
+	}
+
+	switch {
+	case isStruct(f, tags):
+		return slog.GroupValue(logAttrs(nil, f)...)
+	case f.Kind() == reflect.Ptr && isStruct(f.Elem(), tags):
+		return slog.GroupValue(logAttrs(nil, f.Elem())...)
+	case isStructMap(Field{Field: f, Tags: tags}):
+		keys := make([]string, 0, f.Len())
+		for _, key := range f.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys)
+		attrs := make([]slog.Attr, len(keys))
+		for i, key := range keys {
+			entry := f.MapIndex(reflect.ValueOf(key).Convert(f.Type().Key()))
+			attrs[i] = slog.Attr{Key: key, Value: logValue(entry, "")}
+		}
+		return slog.GroupValue(attrs...)
+	}
+
+	// Format the values that have no readable representation of their own, like url.URL or slices,
+	// the way they are written in the environment.
+	switch f.Kind() {
+	case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map, reflect.Ptr, reflect.Interface:
+		if s, err := formatField(f, tags); err == nil {
+			return slog.StringValue(s)
+		}
+	}
+	return slog.AnyValue(f.Interface())
+}
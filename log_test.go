@@ -0,0 +1,62 @@
+package config
+
+import (
+	"bytes"
+	"log/slog"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogValue(t *testing.T) {
+	type database struct {
+		Host     string
+		Password string `secret:"true"`
+	}
+	cfg := struct {
+		HTTPConfig
+		Token     Secret
+		Timeout   time.Duration
+		API       url.URL
+		Hosts     []string
+		DB        database
+		Replica   *database
+		Cache     *database
+		Endpoints map[string]database
+	}{
+		Token:     Secret{value: []byte("hunter2")},
+		Timeout:   5 * time.Second,
+		API:       url.URL{Scheme: "https", Host: "api.example.com"},
+		Hosts:     []string{"a", "b"},
+		DB:        database{Host: "db.example.com", Password: "hunter2"},
+		Replica:   &database{Host: "replica.example.com", Password: "hunter2"},
+		Endpoints: map[string]database{"users": {Host: "users.example.com"}},
+	}
+	cfg.Addr = ":8080"
+
+	var logs bytes.Buffer
+	slog.New(slog.NewTextHandler(&logs, nil)).Info("config loaded", "config", LogValue(&cfg))
+
+	out := logs.String()
+	if strings.Contains(out, "hunter2") {
+		t.Fatalf("expected the secrets to be masked, got %s", out)
+	}
+	expected := []string{
+		"config.Addr=:8080",
+		"config.Token=****",
+		"config.Timeout=5s",
+		"config.API=https://api.example.com",
+		"config.Hosts=a,b",
+		"config.DB.Host=db.example.com",
+		"config.DB.Password=****",
+		"config.Replica.Host=replica.example.com",
+		"config.Cache=<nil>",
+		"config.Endpoints.users.Host=users.example.com",
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Fatalf("expected the log to contain %s, got %s", e, out)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package config
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Marshal returns the values of the fields of cfg keyed by the environment variables Parse reads them
+// from, so parsing the result gives back cfg, except for secrets, see below. cfg is a struct or a pointer to struct. Fields with an
+// env tag are keyed by the tag, the other fields by their prefixed key, for example APP_DB_HOST, and
+// the fields of the entries of maps of structs by APP_ENDPOINTS_<NAME>_URL keys. Zero values are
+// included, nil pointers to nested structs are left out. Custom types are formatted with their
+// MarshalText, MarshalBinary, in base64, or String method. The values of the fields holding secrets,
+// see Field.Secret, are replaced by ****, so they do not round trip through Marshal and Parse, use
+// MarshalSecrets to get them.
+//
+//	values, err := config.Marshal("app", &cfg)
+//	...
+//	for key, value := range values {
+//		fmt.Printf("%s=%s\n", key, value)
+//	}
+func Marshal(prefix string, cfg any) (map[string]string, error) {
+	return marshal(prefix, cfg, maskSecrets)
+}
+
+// MarshalPublic is like Marshal but leaves out the fields holding secrets, for example to export the
+// effective config as metrics or trace attributes.
+func MarshalPublic(prefix string, cfg any) (map[string]string, error) {
+	return marshal(prefix, cfg, omitSecrets)
+}
+
+// MarshalSecrets is like Marshal but includes the values of the fields holding secrets, so parsing the
+// result gives back cfg, for example to build the environment of another process:
+//
+//	values, err := config.MarshalSecrets("app", &cfg)
+//	...
+//	for key, value := range values {
+//		cmd.Env = append(cmd.Env, key+"="+value)
+//	}
+func MarshalSecrets(prefix string, cfg any) (map[string]string, error) {
+	return marshal(prefix, cfg, revealSecrets)
+}
+
+// The ways marshal handles the values of the secret fields.
+const (
+	maskSecrets   = iota // Replace the values by ****.
+	omitSecrets          // Leave the fields out.
+	revealSecrets        // Keep the values.
+)
+
+// marshal returns the values of the fields of cfg like Marshal, handling the secret fields as secrets
+// says.
+func marshal(prefix string, cfg any, secrets int) (map[string]string, error) {
+	if v := reflect.ValueOf(cfg); v.Kind() == reflect.Struct {
+		// Copy the struct so its fields are addressable.
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		cfg = p.Interface()
+	}
+	fields, err := extractFields(prefix, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(fields))
+	for _, field := range fields {
+		if secrets == omitSecrets && field.Secret {
+			continue
+		}
+		if isStructMap(field) {
+			iter := field.Field.MapRange()
+			for iter.Next() {
+				entry, err := marshal(joinKey(field.Key, strings.ToUpper(iter.Key().String())), iter.Value().Interface(), secrets)
+				if err != nil {
+					return nil, err
+				}
+				for key, value := range entry {
+					values[key] = value
+				}
+			}
+			continue
+		}
+
+		if isStructPtr(field) {
+			if field.Field.IsNil() {
+				continue
+			}
+			nested, err := marshal(field.Key, field.Field.Interface(), secrets)
+			if err != nil {
+				return nil, err
+			}
+			for key, value := range nested {
+				values[key] = value
+			}
+			continue
+		}
+
+		if impl, ok := configurable(field.Field); ok {
+			implValues, err := marshal(field.Key, impl.Interface(), secrets)
+			if err != nil {
+				return nil, err
+			}
+			for key, value := range implValues {
+				values[key] = value
+			}
+		}
+
+		value, err := formatField(field.Field, field.Tags)
+		if err == nil {
+			value, err = encodeValue(value, field.Tags)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("config: marshaling field %s: %w", field.Name, err)
+		}
+		if secrets == maskSecrets && field.Secret && value != "" {
+			value = redacted
+		}
+		key := field.Key
+		if field.EnvKey != "" {
+			key = field.EnvKey
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// formatField formats the value of a field the way parseField parses it, tags are the struct tags of
+// the field.
+func formatField(field reflect.Value, tags reflect.StructTag) (string, error) {
+	if (field.Kind() == reflect.Ptr || field.Kind() == reflect.Interface) && field.IsNil() {
+		return "", nil
+	}
+	if field.Kind() == reflect.Interface {
+		// The field holds an implementation selected by its name, see Register.
+		if name, ok := implementationName(field.Type(), field.Elem()); ok {
+			return name, nil
+		}
+		return "", fmt.Errorf("%s is not a registered implementation of %s", field.Elem().Type(), field.Type())
+	}
+	if layout := tags.Get("layout"); layout != "" {
+		return formatTime(field, layout)
+	}
+	if ft, ok := fieldTypes[field.Type()]; ok {
+		return ft.format(field.Interface()), nil
+	}
+	if field.Kind() == reflect.Ptr {
+		field = field.Elem()
+	}
+	if name, ok := formatEnum(field); ok {
+		return name, nil
+	}
+	if format := tags.Get("format"); format != "" {
+		if format != FormatJSON {
+			return "", fmt.Errorf("unsupported format %q", format)
+		}
+		data, err := json.Marshal(field.Interface())
+		return string(data), err
+	}
+
+	isSetter := extractSetter(field) != nil
+	if ft, ok := fieldTypes[field.Type()]; ok {
+		return ft.format(field.Interface()), nil
+	}
+
+	var (
+		text  string
+		err   error
+		found bool
+	)
+	extractInterface(field, func(v any, ok *bool) {
+		if m, isMarshaler := v.(encoding.TextMarshaler); isMarshaler {
+			var b []byte
+			b, err = m.MarshalText()
+			text, *ok = string(b), true
+		} else if m, isMarshaler := v.(encoding.BinaryMarshaler); isMarshaler && !isSetter {
+			var b []byte
+			b, err = m.MarshalBinary()
+			text, *ok = base64.StdEncoding.EncodeToString(b), true
+		} else if s, isStringer := v.(fmt.Stringer); isStringer && isSetter {
+			text, *ok = s.String(), true
+		}
+		found = *ok
+	})
+	if found {
+		return text, err
+	}
+	if isSetter {
+		return "", fmt.Errorf("type %s implements Setter but not encoding.TextMarshaler or fmt.Stringer", field.Type())
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		return field.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if field.Type() == reflect.TypeOf(time.Duration(0)) {
+			return time.Duration(field.Int()).String(), nil
+		}
+		if tags.Get("unit") == "bytes" && field.Int() >= 0 {
+			return formatByteSize(uint64(field.Int())), nil
+		}
+		if tags.Get("unit") == "si" {
+			if field.Int() < 0 {
+				return "-" + formatSI(uint64(-field.Int())), nil
+			}
+			return formatSI(uint64(field.Int())), nil
+		}
+		return strconv.FormatInt(field.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if tags.Get("unit") == "bytes" {
+			return formatByteSize(field.Uint()), nil
+		}
+		if tags.Get("unit") == "si" {
+			return formatSI(field.Uint()), nil
+		}
+		return strconv.FormatUint(field.Uint(), 10), nil
+	case reflect.Bool:
+		return strconv.FormatBool(field.Bool()), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(field.Float(), 'g', -1, field.Type().Bits()), nil
+	case reflect.Complex64, reflect.Complex128:
+		return strconv.FormatComplex(field.Complex(), 'g', -1, field.Type().Bits()), nil
+	case reflect.Slice, reflect.Array:
+		if field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Uint8 {
+			return string(field.Bytes()), nil
+		}
+		items := make([]string, field.Len())
+		for i := range items {
+			item, err := formatField(field.Index(i), "")
+			if err != nil {
+				return "", err
+			}
+			items[i] = item
+		}
+		return joinList(items, listSeparator(tags)), nil
+	case reflect.Map:
+		pairs := make([]string, 0, field.Len())
+		iter := field.MapRange()
+		for iter.Next() {
+			key, err := formatField(iter.Key(), "")
+			if err != nil {
+				return "", err
+			}
+			value, err := formatField(iter.Value(), "")
+			if err != nil {
+				return "", err
+			}
+			pairs = append(pairs, key+mapSeparator(tags)+value)
+		}
+		sort.Strings(pairs)
+		return joinList(pairs, pairSeparator(tags)), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", field.Type())
+}
+
+// WriteDotEnv writes values to w in the .env format, one NAME=value line per variable sorted by name.
+// Values are double quoted and escaped when needed so that they are read back unchanged by Parse and
+// DotEnvSource, which makes it easy to generate a .env file from a config:
+//
+//	values, err := config.MarshalSecrets("app", &cfg)
+//	...
+//	err = config.WriteDotEnv(f, values)
+func WriteDotEnv(w io.Writer, values map[string]string) error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(quoteEnvValue(values[key]))
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// quoteEnvValue returns value as is if it only contains characters that are safe in an unquoted .env
+// value, otherwise it returns value double quoted with the special characters escaped.
+func quoteEnvValue(value string) string {
+	safe := strings.IndexFunc(value, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_-.,:/@+=%", r))
+	}) < 0
+	if safe {
+		return value
+	}
+
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(value) + `"`
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// level is a custom type implementing Setter and fmt.Stringer.
+type level int
+
+func (l *level) Set(value string) error {
+	switch value {
+	case "debug":
+		*l = 0
+	case "info":
+		*l = 1
+	default:
+		*l = 2
+	}
+	return nil
+}
+
+func (l level) String() string {
+	return [...]string{"debug", "info", "error"}[l]
+}
+
+type marshalConfig struct {
+	Host     string
+	Port     int `env:"port"`
+	Debug    bool
+	Ratio    float64
+	Timeout  time.Duration
+	Level    level
+	Password string
+	Hosts    []string `sep:";"`
+	Labels   map[string]int
+	Params   map[string]string `pairsep:";" kvsep:"="`
+	DB       struct {
+		Host string
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	cfg := marshalConfig{
+		Host:     "example.com",
+		Port:     8080,
+		Debug:    true,
+		Ratio:    0.25,
+		Timeout:  90 * time.Second,
+		Level:    1,
+		Password: "p@ss \"$ecret\"\nline 2",
+		Hosts:    []string{"a.example.com", "b;c"},
+		Labels:   map[string]int{"b": 2, "a": 1},
+		Params:   map[string]string{"a": "1,2", "b": "x;y"},
+	}
+	cfg.DB.Host = "db.example.com"
+
+	values, err := Marshal("app", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"APP_HOST":     "example.com",
+		"PORT":         "8080",
+		"APP_DEBUG":    "true",
+		"APP_RATIO":    "0.25",
+		"APP_TIMEOUT":  "1m30s",
+		"APP_LEVEL":    "info",
+		"APP_PASSWORD": "p@ss \"$ecret\"\nline 2",
+		"APP_HOSTS":    `a.example.com;b\;c`,
+		"APP_LABELS":   "a:1,b:2",
+		"APP_PARAMS":   `a=1,2;b=x\;y`,
+		"APP_DB_HOST":  "db.example.com",
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Fatalf("expected the values to be %v, got %v", expected, values)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteDotEnv(&buf, values); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "APP_DB_HOST=db.example.com\nAPP_DEBUG=true\n") {
+		t.Fatalf("expected the variables to be sorted, got %s", buf.String())
+	}
+
+	// The .env output parses back to the same config.
+	vars, _, err := parseEnv(buf.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out marshalConfig
+	if err := ParseSources("app", &out, MapSource(vars)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, cfg) {
+		t.Fatalf("expected the config to be %+v, got %+v", cfg, out)
+	}
+}
+
+func TestMarshalError(t *testing.T) {
+	if _, err := Marshal("app", "config"); err != ErrInvalidConfig {
+		t.Fatalf("expected error to be ErrInvalidConfig, got %v", err)
+	}
+
+	cfg := struct {
+		Value chan int
+	}{}
+	if _, err := Marshal("app", &cfg); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestMarshalPublic(t *testing.T) {
+	type database struct {
+		Host     string
+		Password string `secret:"true"`
+	}
+	cfg := struct {
+		Token Secret
+		URL   DatabaseURL
+		DB    *database
+		Level string
+	}{
+		DB:    &database{Host: "db.example.com", Password: "hunter2"},
+		Level: "info",
+	}
+
+	values, err := MarshalPublic("app", &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"APP_DB_HOST": "db.example.com", "APP_LEVEL": "info"}
+	if !reflect.DeepEqual(values, expected) {
+		t.Fatalf("expected the values to be %v, got %v", expected, values)
+	}
+}
@@ -0,0 +1,51 @@
+// Package otel exports a config as OpenTelemetry attributes, so that traces and metrics carry the
+// effective configuration of the service. The fields holding secrets are left out.
+//
+//	res, err := otel.Resource("app", &cfg)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	res, err = resource.Merge(resource.Default(), res)
+//	tp := sdktrace.NewTracerProvider(sdktrace.WithResource(res))
+package otel
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/josemukorivo/config"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// Attributes returns a string attribute for every field of cfg but the secrets, see config.Field.Secret,
+// sorted by key. The keys are the keys of config.Marshal lower cased with dots instead of underscores,
+// the field Port of the nested struct DB gives app.db.port with the prefix app.
+func Attributes(prefix string, cfg any) ([]attribute.KeyValue, error) {
+	values, err := config.MarshalPublic(prefix, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]attribute.KeyValue, len(keys))
+	for i, key := range keys {
+		attrs[i] = attribute.String(strings.ToLower(strings.ReplaceAll(key, "_", ".")), values[key])
+	}
+	return attrs, nil
+}
+
+// Resource returns a resource holding the Attributes of cfg, merge it with the resource describing the
+// service.
+func Resource(prefix string, cfg any) (*resource.Resource, error) {
+	attrs, err := Attributes(prefix, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return resource.NewSchemaless(attrs...), nil
+}
@@ -0,0 +1,44 @@
+package otel
+
+import (
+	"testing"
+
+	"github.com/josemukorivo/config"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestAttributes(t *testing.T) {
+	cfg := struct {
+		Port     int
+		Password config.Secret
+		DB       struct {
+			Host  string
+			Token string `secret:"true"`
+		}
+	}{Port: 8080}
+	cfg.DB.Host = "db.example.com"
+
+	attrs, err := Attributes("app", &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []attribute.KeyValue{
+		attribute.String("app.db.host", "db.example.com"),
+		attribute.String("app.port", "8080"),
+	}
+	if len(attrs) != len(expected) || attrs[0] != expected[0] || attrs[1] != expected[1] {
+		t.Fatalf("expected the attributes to be %v, got %v", expected, attrs)
+	}
+
+	res, err := Resource("app", &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := res.Set().Value("app.port"); !ok || v.AsString() != "8080" {
+		t.Fatalf("expected the resource to hold app.port=8080, got %v", res)
+	}
+
+	if _, err := Attributes("app", "config"); err == nil {
+		t.Fatal("expected an error for an invalid config, got nil")
+	}
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ParseProfile is like ParseFile but overlays the profile, for example "dev", "staging" or "prod", on
+// the base config. The values are looked up, from the lowest to the highest precedence, in:
+//
+//   - the default tags, then the default.<profile> tags, for example `default:"info" default.prod:"warn"`
+//   - the config file at path, for example config.yaml
+//   - the config file of the profile next to it, config.<profile>.yaml, if it exists
+//   - the environment, like Parse
+//
+// The path can be empty to only use the profile defaults. An empty profile parses the base config.
+func ParseProfile(prefix string, cfg any, path, profile string, envFiles ...string) error {
+	// Load the .env file if it exists.
+	if err := loadEnvFiles(envFiles...); err != nil {
+		return err
+	}
+	return parse(prefix, cfg, ProfileSource(prefix, cfg, path, profile), EnvSource())
+}
+
+// ProfileSource returns a Source with the values ParseProfile looks up below the environment: the
+// default.<profile> tags of cfg, overlaid by the config file at path, overlaid by the config file of
+// the profile. The files are read on the first lookup.
+func ProfileSource(prefix string, cfg any, path, profile string) Source {
+	sources := []Source{profileDefaults(prefix, cfg, profile)}
+	if path != "" {
+		sources = append(sources, FileSource(prefix, path))
+	}
+	if path != "" && profile != "" {
+		sources = append(sources, optionalFile(prefix, ProfilePath(path, profile)))
+	}
+	return stackSource(sources)
+}
+
+// stackSource looks up the keys in sources, from the last to the first.
+type stackSource []Source
+
+func (s stackSource) Lookup(key string) (string, bool, error) {
+	for i := len(s) - 1; i >= 0; i-- {
+		value, ok, err := s[i].Lookup(key)
+		if ok || err != nil {
+			return value, ok, err
+		}
+	}
+	return "", false, nil
+}
+
+func (s stackSource) lookupList(key string) ([]string, bool, error) {
+	for i := len(s) - 1; i >= 0; i-- {
+		_, ok, err := s[i].Lookup(key)
+		if err != nil {
+			return nil, false, err
+		}
+		if !ok {
+			continue
+		}
+		if ls, isList := s[i].(listSource); isList {
+			return ls.lookupList(key)
+		}
+		return nil, false, nil
+	}
+	return nil, false, nil
+}
+
+// Keys returns the keys of the sources that are KeySources.
+func (s stackSource) Keys() ([]string, error) {
+	var keys []string
+	for _, source := range s {
+		if ks, ok := source.(KeySource); ok {
+			k, err := ks.Keys()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, k...)
+		}
+	}
+	return keys, nil
+}
+
+// ProfilePath returns the path of the config file of the profile, the profile is inserted before the
+// extension of path: config.yaml becomes config.prod.yaml for the profile "prod".
+func ProfilePath(path, profile string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + profile + ext
+}
+
+// profileDefaults returns a Source with the values of the default.<profile> tags of cfg.
+func profileDefaults(prefix string, cfg any, profile string) Source {
+	return LoadOnce(func() (MapSource, error) {
+		values := make(MapSource)
+		if profile == "" {
+			return values, nil
+		}
+		fields, err := extractFields(prefix, cfg)
+		if err != nil {
+			return nil, err
+		}
+		for _, field := range fields {
+			if def, ok := field.Tags.Lookup("default." + profile); ok {
+				values[field.Key] = def
+			}
+		}
+		return values, nil
+	})
+}
+
+// optionalFile is like FileSource but the Source is empty if the file does not exist.
+func optionalFile(prefix, path string) Source {
+	return loadDocument(func() (documentValues, error) {
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			return documentValues{}, nil
+		}
+		return readFile(prefix, path)
+	})
+}
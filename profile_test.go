@@ -0,0 +1,92 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type profileConfig struct {
+	Host     string
+	Port     int    `default:"8080" default.prod:"443"`
+	LogLevel string `default:"debug" default.prod:"warn" default.staging:"info"`
+	DB       struct {
+		Host string `default:"localhost"`
+		Port int
+	}
+}
+
+func TestParseProfile(t *testing.T) {
+	path := writeFile(t, "config.yaml", "host: example.com\ndb:\n  host: db.example.com\n  port: 5432\n")
+	prod := ProfilePath(path, "prod")
+	if prod != filepath.Join(filepath.Dir(path), "config.prod.yaml") {
+		t.Fatalf("expected the prod config file to be config.prod.yaml, got %s", prod)
+	}
+	if err := os.WriteFile(prod, []byte("db:\n  host: prod-db.example.com\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		description string
+		profile     string
+		logLevel    string
+		port        int
+		dbHost      string
+	}{
+		{
+			description: "base",
+			logLevel:    "debug",
+			port:        8080,
+			dbHost:      "db.example.com",
+		},
+		{
+			description: "profile with a config file",
+			profile:     "prod",
+			logLevel:    "warn",
+			port:        443,
+			dbHost:      "prod-db.example.com",
+		},
+		{
+			description: "profile without a config file",
+			profile:     "staging",
+			logLevel:    "info",
+			port:        8080,
+			dbHost:      "db.example.com",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			os.Clearenv()
+			os.Setenv("APP_DB_PORT", "6543")
+
+			var cfg profileConfig
+			if err := ParseProfile("app", &cfg, path, tc.profile); err != nil {
+				t.Fatal(err)
+			}
+
+			if cfg.Host != "example.com" {
+				t.Fatalf("expected host to be example.com, got %s", cfg.Host)
+			}
+			if cfg.LogLevel != tc.logLevel {
+				t.Fatalf("expected log level to be %s, got %s", tc.logLevel, cfg.LogLevel)
+			}
+			if cfg.Port != tc.port {
+				t.Fatalf("expected port to be %d, got %d", tc.port, cfg.Port)
+			}
+			if cfg.DB.Host != tc.dbHost {
+				t.Fatalf("expected db host to be %s, got %s", tc.dbHost, cfg.DB.Host)
+			}
+			if cfg.DB.Port != 6543 {
+				t.Fatalf("expected db port to be 6543, got %d", cfg.DB.Port)
+			}
+		})
+	}
+}
+
+func TestParseProfileErrors(t *testing.T) {
+	var cfg profileConfig
+	if err := ParseProfile("app", &cfg, "missing.yaml", "prod"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,20 @@
+module github.com/josemukorivo/config/redis
+
+go 1.22
+
+require (
+	github.com/alicebob/miniredis/v2 v2.33.0
+	github.com/josemukorivo/config v0.0.0-20261016120655-98cbede698f8
+	github.com/redis/go-redis/v9 v9.7.0
+)
+
+require (
+	github.com/BurntSushi/toml v1.4.0 // indirect
+	github.com/alicebob/gopher-json v0.0.0-20200520072559-a9ecdc9d1d3a // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/hashicorp/hcl v1.0.0 // indirect
+	github.com/spf13/pflag v1.0.5 // indirect
+	github.com/yuin/gopher-lua v1.1.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
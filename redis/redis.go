@@ -0,0 +1,130 @@
+// Package redis provides config.Sources backed by Redis, so settings updated in Redis are picked up
+// the next time the config is parsed, without redeploying environment variables.
+//
+//	client := goredis.NewClient(&goredis.Options{Addr: "localhost:6379"})
+//	err := config.ParseSources("app", &cfg,
+//		config.EnvSource(),
+//		redis.New(client, "config:"),
+//	)
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/josemukorivo/config"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Option configures the Source.
+type Option func(*source)
+
+// WithTimeout bounds the time spent fetching the values, it defaults to 5 seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(s *source) {
+		s.timeout = d
+	}
+}
+
+type source struct {
+	client  goredis.Cmdable
+	timeout time.Duration
+}
+
+// keyReplacer replaces the separators of Redis keys with underscores.
+var keyReplacer = strings.NewReplacer(":", "_")
+
+// globEscaper escapes the special characters of SCAN patterns.
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// New returns a config.Source that looks up the string keys stored under keyPrefix. A key relative to
+// keyPrefix is mapped to the key of a field with config.NormalizeKey, colons being separators too, for
+// example with the key prefix "config:" the key config:app:db:host maps to APP_DB_HOST. Keys of other
+// types are skipped. All the keys under keyPrefix are fetched on the first lookup, use a new Source
+// to pick up updated values.
+func New(client goredis.Cmdable, keyPrefix string, opts ...Option) config.Source {
+	s := newSource(client, opts)
+	return config.LoadOnce(func() (config.MapSource, error) {
+		return s.scan(keyPrefix)
+	})
+}
+
+// NewHash returns a config.Source that looks up the fields of the hash stored at key. The fields are
+// mapped to the keys of the fields with config.NormalizeKey and prefixed with prefix, so with the
+// prefix "app" the field db_host maps to APP_DB_HOST. The hash is fetched on the first lookup, use a
+// new Source to pick up updated values.
+func NewHash(client goredis.Cmdable, prefix, key string, opts ...Option) config.Source {
+	s := newSource(client, opts)
+	return config.LoadOnce(func() (config.MapSource, error) {
+		return s.hash(prefix, key)
+	})
+}
+
+func newSource(client goredis.Cmdable, opts []Option) *source {
+	s := &source{
+		client:  client,
+		timeout: 5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// scan fetches the string keys under keyPrefix.
+func (s *source) scan(keyPrefix string) (config.MapSource, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	values := make(config.MapSource)
+	iter := s.client.Scan(ctx, 0, globEscaper.Replace(keyPrefix)+"*", 100).Iterator()
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("redis: scanning keys under %q: %w", keyPrefix, err)
+	}
+
+	for len(keys) > 0 {
+		batch := keys[:min(len(keys), 100)]
+		keys = keys[len(batch):]
+
+		vals, err := s.client.MGet(ctx, batch...).Result()
+		if err != nil {
+			return nil, fmt.Errorf("redis: fetching keys under %q: %w", keyPrefix, err)
+		}
+		for i, v := range vals {
+			// MGET returns nil for keys that are not strings.
+			value, ok := v.(string)
+			if !ok {
+				continue
+			}
+			name := keyReplacer.Replace(strings.TrimPrefix(batch[i], keyPrefix))
+			values[config.NormalizeKey(name)] = value
+		}
+	}
+	return values, nil
+}
+
+// hash fetches the fields of the hash at key.
+func (s *source) hash(prefix, key string) (config.MapSource, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	fields, err := s.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis: fetching hash %s: %w", key, err)
+	}
+
+	values := make(config.MapSource, len(fields))
+	for field, value := range fields {
+		if prefix != "" {
+			field = prefix + "_" + field
+		}
+		values[config.NormalizeKey(field)] = value
+	}
+	return values, nil
+}
@@ -0,0 +1,90 @@
+package redis
+
+import (
+	"os"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/josemukorivo/config"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+type Config struct {
+	Host string
+	DB   struct {
+		Host string
+		Port int
+	}
+}
+
+func newClient(t *testing.T) (*miniredis.Miniredis, *goredis.Client) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return server, client
+}
+
+func TestSource(t *testing.T) {
+	server, client := newClient(t)
+	server.Set("config:app:host", "redis.example.com")
+	server.Set("config:app:db:host", "db.example.com")
+	server.Set("config:app:db:port", "5432")
+	server.Set("other:app:db:port", "6543")
+	server.HSet("config:app:db", "user", "admin")
+
+	os.Clearenv()
+	os.Setenv("APP_HOST", "example.com")
+
+	var cfg Config
+	if err := config.ParseSources("app", &cfg, New(client, "config:"), config.EnvSource()); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Host != "example.com" {
+		t.Fatalf("expected host to be example.com, got %s", cfg.Host)
+	}
+	if cfg.DB.Host != "db.example.com" {
+		t.Fatalf("expected db host to be db.example.com, got %s", cfg.DB.Host)
+	}
+	if cfg.DB.Port != 5432 {
+		t.Fatalf("expected db port to be 5432, got %d", cfg.DB.Port)
+	}
+}
+
+func TestHashSource(t *testing.T) {
+	server, client := newClient(t)
+	server.HSet("app:config", "host", "example.com", "db_host", "db.example.com", "db.port", "5432")
+
+	os.Clearenv()
+
+	var cfg Config
+	if err := config.ParseSources("app", &cfg, NewHash(client, "app", "app:config")); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Host != "example.com" {
+		t.Fatalf("expected host to be example.com, got %s", cfg.Host)
+	}
+	if cfg.DB.Host != "db.example.com" {
+		t.Fatalf("expected db host to be db.example.com, got %s", cfg.DB.Host)
+	}
+	if cfg.DB.Port != 5432 {
+		t.Fatalf("expected db port to be 5432, got %d", cfg.DB.Port)
+	}
+}
+
+func TestSourceError(t *testing.T) {
+	server, client := newClient(t)
+	server.Set("app:config", "not a hash")
+
+	var cfg Config
+	if err := config.ParseSources("app", &cfg, NewHash(client, "app", "app:config")); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	server.Close()
+	if err := config.ParseSources("app", &cfg, New(client, "config:")); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
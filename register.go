@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	implementationsMu sync.RWMutex
+	// implementations maps interface types to their named implementations, see Register.
+	implementations = make(map[reflect.Type]map[string]func() any)
+)
+
+var (
+	enumsMu sync.RWMutex
+	// enums maps types to the values of their names, see RegisterEnum.
+	enums = make(map[reflect.Type]map[string]any)
+)
+
+// RegisterEnum registers the names of the values of the type T, so that fields of type T are parsed
+// from the names. Names are matched case-insensitively and a value that is not one of the names is
+// reported as a FieldError listing them:
+//
+//	type Mode int
+//
+//	const (
+//		ModeFast Mode = iota
+//		ModeSafe
+//	)
+//
+//	config.RegisterEnum(map[string]Mode{"fast": ModeFast, "safe": ModeSafe})
+//
+// Registering the names of a type again replaces them. RegisterEnum is usually called from an init
+// function.
+func RegisterEnum[T any](names map[string]T) {
+	values := make(map[string]any, len(names))
+	for name, value := range names {
+		values[name] = value
+	}
+
+	enumsMu.Lock()
+	defer enumsMu.Unlock()
+	enums[reflect.TypeOf((*T)(nil)).Elem()] = values
+}
+
+// parseEnum sets field to the value named value if the type of field is registered with RegisterEnum.
+// It reports whether the type is registered.
+func parseEnum(value string, field reflect.Value) (bool, error) {
+	enumsMu.RLock()
+	values, ok := enums[field.Type()]
+	enumsMu.RUnlock()
+	if !ok {
+		return false, nil
+	}
+
+	names := sortedKeys(values)
+	for _, name := range names {
+		if strings.EqualFold(name, value) {
+			field.Set(reflect.ValueOf(values[name]))
+			return true, nil
+		}
+	}
+	return true, fmt.Errorf("unknown value %q, expected one of %s", value, strings.Join(names, ", "))
+}
+
+// formatEnum returns the name of the value of field if its type is registered with RegisterEnum.
+func formatEnum(field reflect.Value) (string, bool) {
+	enumsMu.RLock()
+	values, ok := enums[field.Type()]
+	enumsMu.RUnlock()
+	if !ok {
+		return "", false
+	}
+
+	for _, name := range sortedKeys(values) {
+		if reflect.DeepEqual(values[name], field.Interface()) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Register registers the implementation name of the interface I, newImpl returns a new value of the
+// implementation. The value of a field of type I names the implementation, the field is set to the
+// value returned by newImpl and, if it is a pointer to struct, its fields are parsed prefixed with the
+// key of the field. For example, with:
+//
+//	config.Register[Storage]("s3", func() Storage { return &S3Storage{} })
+//	config.Register[Storage]("local", func() Storage { return &LocalStorage{} })
+//
+// APP_STORAGE=s3 sets a field Storage of type Storage to a *S3Storage whose field Bucket is read from
+// APP_STORAGE_BUCKET. An unknown name is reported as a FieldError listing the registered names. Register
+// panics if I is not an interface type, it is usually called from an init function.
+func Register[I any](name string, newImpl func() I) {
+	t := reflect.TypeOf((*I)(nil)).Elem()
+	if t.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("config: Register called with the non-interface type %s", t))
+	}
+
+	implementationsMu.Lock()
+	defer implementationsMu.Unlock()
+	if implementations[t] == nil {
+		implementations[t] = make(map[string]func() any)
+	}
+	implementations[t][name] = func() any { return newImpl() }
+}
+
+// newImplementation returns a new value of the implementation name of the interface type t.
+func newImplementation(t reflect.Type, name string) (reflect.Value, error) {
+	implementationsMu.RLock()
+	defer implementationsMu.RUnlock()
+
+	impls, ok := implementations[t]
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("unsupported type %s", t)
+	}
+	newImpl, ok := impls[name]
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("unknown implementation %q, expected one of %s", name, strings.Join(sortedKeys(impls), ", "))
+	}
+	impl := reflect.New(t).Elem()
+	if v := newImpl(); v != nil {
+		impl.Set(reflect.ValueOf(v))
+	}
+	return impl, nil
+}
+
+// implementationName returns the name of the registered implementation of the interface type t whose
+// values have the same dynamic type as impl.
+func implementationName(t reflect.Type, impl reflect.Value) (string, bool) {
+	implementationsMu.RLock()
+	defer implementationsMu.RUnlock()
+
+	for _, name := range sortedKeys(implementations[t]) {
+		if reflect.TypeOf(implementations[t][name]()) == impl.Type() {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// sortedKeys returns the sorted keys of m.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// configurable returns the pointer to struct held by the interface field, whose fields are parsed after
+// the implementation is selected, or false if it holds another value.
+func configurable(field reflect.Value) (reflect.Value, bool) {
+	if field.Kind() != reflect.Interface || field.IsNil() {
+		return reflect.Value{}, false
+	}
+	impl := field.Elem()
+	if impl.Kind() != reflect.Ptr || impl.IsNil() || impl.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	return impl, true
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+type storage interface {
+	Name() string
+}
+
+type s3Storage struct {
+	Bucket string `required:"true"`
+	Region string `default:"us-east-1"`
+}
+
+func (s *s3Storage) Name() string { return "s3" }
+
+type localStorage struct {
+	Dir string `default:"/var/lib/app"`
+}
+
+func (s *localStorage) Name() string { return "local" }
+
+type memoryStorage struct{}
+
+func (memoryStorage) Name() string { return "memory" }
+
+func init() {
+	Register[storage]("s3", func() storage { return &s3Storage{} })
+	Register[storage]("local", func() storage { return &localStorage{} })
+	Register[storage]("memory", func() storage { return memoryStorage{} })
+}
+
+func TestRegister(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_STORAGE", "s3")
+	os.Setenv("APP_STORAGE_BUCKET", "uploads")
+	os.Setenv("APP_CACHE", "memory")
+
+	spec := struct {
+		Storage storage
+		Backup  storage `default:"local"`
+		Cache   storage
+		Unset   storage
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	s3, ok := spec.Storage.(*s3Storage)
+	if !ok || s3.Bucket != "uploads" || s3.Region != "us-east-1" {
+		t.Fatalf("expected storage to be s3 with the bucket uploads, got %#v", spec.Storage)
+	}
+	if local, ok := spec.Backup.(*localStorage); !ok || local.Dir != "/var/lib/app" {
+		t.Fatalf("expected backup to be local, got %#v", spec.Backup)
+	}
+	if _, ok := spec.Cache.(memoryStorage); !ok {
+		t.Fatalf("expected cache to be memory, got %#v", spec.Cache)
+	}
+	if spec.Unset != nil {
+		t.Fatalf("expected unset to be nil, got %#v", spec.Unset)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_STORAGE"] != "s3" || values["APP_STORAGE_BUCKET"] != "uploads" || values["APP_CACHE"] != "memory" || values["APP_UNSET"] != "" {
+		t.Fatalf("expected the implementations to be marshaled, got %v", values)
+	}
+
+	// The implementation misses its required bucket.
+	os.Unsetenv("APP_STORAGE_BUCKET")
+	if err := Parse("app", &spec); err == nil || !strings.Contains(err.Error(), "APP_STORAGE_BUCKET") {
+		t.Fatalf("expected the required key APP_STORAGE_BUCKET to be missing, got %v", err)
+	}
+
+	os.Setenv("APP_STORAGE", "gcs")
+	err = Parse("app", &spec)
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || !strings.Contains(err.Error(), `unknown implementation "gcs", expected one of local, memory, s3`) {
+		t.Fatalf("expected a FieldError listing the implementations, got %v", err)
+	}
+}
+
+func TestRegisterNonInterface(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected Register to panic")
+		}
+	}()
+	Register[*s3Storage]("s3", func() *s3Storage { return &s3Storage{} })
+}
+
+type mode int
+
+const (
+	modeFast mode = iota
+	modeSafe
+)
+
+func init() {
+	RegisterEnum(map[string]mode{"fast": modeFast, "safe": modeSafe})
+}
+
+func TestRegisterEnum(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_MODE", "safe")
+	os.Setenv("APP_FALLBACK", "FAST")
+	os.Setenv("APP_MODES", "safe,fast")
+
+	spec := struct {
+		Mode     mode
+		Fallback *mode
+		Modes    []mode
+		Default  mode `default:"safe"`
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Mode != modeSafe || spec.Fallback == nil || *spec.Fallback != modeFast || spec.Default != modeSafe {
+		t.Fatalf("expected the modes to be set from their names, got %+v", spec)
+	}
+	if len(spec.Modes) != 2 || spec.Modes[0] != modeSafe {
+		t.Fatalf("expected modes to be safe and fast, got %v", spec.Modes)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_MODE"] != "safe" || values["APP_FALLBACK"] != "fast" || values["APP_MODES"] != "safe,fast" {
+		t.Fatalf("expected the modes to be marshaled to their names, got %v", values)
+	}
+
+	os.Setenv("APP_MODE", "turbo")
+	err = Parse("app", &spec)
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || !strings.Contains(err.Error(), `unknown value "turbo", expected one of fast, safe`) {
+		t.Fatalf("expected a FieldError listing the names, got %v", err)
+	}
+}
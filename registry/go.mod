@@ -0,0 +1,15 @@
+module github.com/josemukorivo/config/registry
+
+go 1.22
+
+require (
+	github.com/josemukorivo/config v0.0.0-20261016120655-98cbede698f8
+	golang.org/x/sys v0.30.0
+)
+
+require (
+	github.com/BurntSushi/toml v1.4.0 // indirect
+	github.com/hashicorp/hcl v1.0.0 // indirect
+	github.com/spf13/pflag v1.0.5 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,19 @@
+// Package registry provides a config.Source backed by a Windows registry key, so Windows services can
+// be configured from the registry with the same struct used on other platforms. On other platforms
+// the Source is empty.
+//
+//	source := registry.New(registry.LocalMachine, `SOFTWARE\MyApp`, "app")
+//	err := config.ParseSources("app", &cfg, source, config.EnvSource())
+package registry
+
+// Root is a predefined registry key.
+type Root int
+
+// Predefined registry keys.
+const (
+	LocalMachine Root = iota
+	CurrentUser
+	Users
+	ClassesRoot
+	CurrentConfig
+)
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package registry
+
+import "github.com/josemukorivo/config"
+
+// New returns an empty config.Source, the registry only exists on Windows.
+func New(root Root, path, prefix string) config.Source {
+	return config.MapSource{}
+}
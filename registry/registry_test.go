@@ -0,0 +1,49 @@
+//go:build windows
+
+package registry
+
+import (
+	"os"
+	"testing"
+
+	"github.com/josemukorivo/config"
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestSource(t *testing.T) {
+	const path = `Software\josemukorivo-config-test`
+	key, _, err := registry.CreateKey(registry.CURRENT_USER, path+`\DB`, registry.ALL_ACCESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer registry.DeleteKey(registry.CURRENT_USER, path)
+	defer registry.DeleteKey(registry.CURRENT_USER, path+`\DB`)
+	defer key.Close()
+
+	key.SetStringValue("Host", "db.example.com")
+	key.SetDWordValue("Port", 5432)
+	key.SetStringsValue("Replicas", []string{"a", "b"})
+
+	os.Clearenv()
+
+	var cfg struct {
+		DB struct {
+			Host     string
+			Port     int
+			Replicas string
+		}
+	}
+	if err := config.ParseSources("app", &cfg, New(CurrentUser, path, "app")); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.DB.Host != "db.example.com" {
+		t.Fatalf("expected db host to be db.example.com, got %s", cfg.DB.Host)
+	}
+	if cfg.DB.Port != 5432 {
+		t.Fatalf("expected db port to be 5432, got %d", cfg.DB.Port)
+	}
+	if cfg.DB.Replicas != "a,b" {
+		t.Fatalf("expected db replicas to be a,b, got %s", cfg.DB.Replicas)
+	}
+}
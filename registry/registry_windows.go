@@ -0,0 +1,102 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/josemukorivo/config"
+	"golang.org/x/sys/windows/registry"
+)
+
+// roots maps the predefined keys to their handle.
+var roots = map[Root]registry.Key{
+	LocalMachine:  registry.LOCAL_MACHINE,
+	CurrentUser:   registry.CURRENT_USER,
+	Users:         registry.USERS,
+	ClassesRoot:   registry.CLASSES_ROOT,
+	CurrentConfig: registry.CURRENT_CONFIG,
+}
+
+// New returns a config.Source that looks up the values of the registry key at path under root. Value
+// names are prefixed with prefix and subkeys map to nested structs, so with the prefix "app" the value
+// Host of the subkey DB maps to APP_DB_HOST. String values are expanded if they are of type
+// REG_EXPAND_SZ, integer values are formatted in decimal and the strings of REG_MULTI_SZ values are
+// joined with commas. The key is read on the first lookup, a key that does not exist is empty.
+func New(root Root, path, prefix string) config.Source {
+	return config.LoadOnce(func() (config.MapSource, error) {
+		values := make(config.MapSource)
+		if err := readKey(roots[root], path, prefix, values); err != nil {
+			return nil, fmt.Errorf("registry: reading %s: %w", path, err)
+		}
+		return values, nil
+	})
+}
+
+// readKey reads the values of the key at path and its subkeys into values.
+func readKey(root registry.Key, path, prefix string, values config.MapSource) error {
+	key, err := registry.OpenKey(root, path, registry.READ)
+	if errors.Is(err, registry.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer key.Close()
+
+	names, err := key.ReadValueNames(0)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		value, err := readValue(key, name)
+		if err != nil {
+			return fmt.Errorf("value %s: %w", name, err)
+		}
+		values[config.NormalizeKey(joinKey(prefix, name))] = value
+	}
+
+	subkeys, err := key.ReadSubKeyNames(0)
+	if err != nil {
+		return err
+	}
+	for _, subkey := range subkeys {
+		if err := readKey(root, path+`\`+subkey, joinKey(prefix, subkey), values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readValue reads the value name of key as a string.
+func readValue(key registry.Key, name string) (string, error) {
+	_, typ, err := key.GetValue(name, nil)
+	if err != nil {
+		return "", err
+	}
+
+	switch typ {
+	case registry.SZ, registry.EXPAND_SZ:
+		value, _, err := key.GetStringValue(name)
+		if err != nil || typ == registry.SZ {
+			return value, err
+		}
+		return registry.ExpandString(value)
+	case registry.DWORD, registry.QWORD:
+		value, _, err := key.GetIntegerValue(name)
+		return strconv.FormatUint(value, 10), err
+	case registry.MULTI_SZ:
+		value, _, err := key.GetStringsValue(name)
+		return strings.Join(value, ","), err
+	default:
+		return "", fmt.Errorf("unsupported value type %d", typ)
+	}
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "_" + key
+}
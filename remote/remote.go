@@ -0,0 +1,218 @@
+// Package remote provides a config.Source backed by a config document served over HTTP(S), for example
+// by a config service. JSON, YAML and the other formats supported by config.ReadValues can be served.
+//
+//	source := remote.New("https://config.example.com/app.json", "app",
+//		remote.WithHeader("Authorization", "Bearer "+token),
+//		remote.WithRefreshInterval(time.Minute),
+//	)
+//	err := config.ParseSources("app", &cfg, source, config.EnvSource())
+package remote
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/josemukorivo/config"
+)
+
+// Option configures the Source.
+type Option func(*Source)
+
+// WithHeader adds a header to the requests, for example to authenticate.
+func WithHeader(key, value string) Option {
+	return func(s *Source) {
+		s.header.Add(key, value)
+	}
+}
+
+// WithFormat sets the format of the document, for example config.FormatYAML. By default the format is
+// detected from the Content-Type of the response, then from the extension of the URL path.
+func WithFormat(format string) Option {
+	return func(s *Source) {
+		s.format = format
+	}
+}
+
+// WithRefreshInterval fetches the document again on the first parse after d has elapsed since it was
+// last fetched. The document is only downloaded again if its ETag changed. By default the document is
+// fetched once and cached for the lifetime of the Source.
+func WithRefreshInterval(d time.Duration) Option {
+	return func(s *Source) {
+		s.refresh = d
+	}
+}
+
+// WithTimeout bounds the time spent fetching the document, it defaults to 10 seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(s *Source) {
+		s.timeout = d
+	}
+}
+
+// WithHTTPClient uses the given HTTP client to fetch the document, for example to configure TLS.
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *Source) {
+		s.client = client
+	}
+}
+
+// contentTypes maps the media types of config documents to their format.
+var contentTypes = map[string]string{
+	"application/json":   config.FormatJSON,
+	"application/yaml":   config.FormatYAML,
+	"application/x-yaml": config.FormatYAML,
+	"text/yaml":          config.FormatYAML,
+	"text/x-yaml":        config.FormatYAML,
+	"application/toml":   config.FormatTOML,
+}
+
+// Source is a config.Source that looks up the keys of a config document served over HTTP. It is a
+// config.SnapshotSource, a parse looks up all the keys in the same version of the document, and a
+// config.KeySource. It is safe for concurrent use.
+type Source struct {
+	url     string
+	prefix  string
+	header  http.Header
+	format  string
+	client  *http.Client
+	refresh time.Duration
+	timeout time.Duration
+	now     func() time.Time
+
+	mu      sync.Mutex
+	values  config.KeySource
+	etag    string
+	fetched time.Time
+}
+
+// New returns a Source that looks up the keys of the config document at url. Keys are prefixed with
+// prefix the same way config.ReadValues does, so with the prefix "app" the key "host" in the "db" object
+// maps to APP_DB_HOST. The items of lists are kept and joined with the separator of the field they are
+// assigned to. The document is fetched on the first parse and cached.
+func New(url, prefix string, opts ...Option) *Source {
+	s := &Source{
+		url:     url,
+		prefix:  prefix,
+		header:  make(http.Header),
+		client:  &http.Client{},
+		timeout: 10 * time.Second,
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Snapshot returns the values of the document, fetching the document if it is not cached or the
+// refresh interval has elapsed. The parse functions call it once and look up all the keys in its
+// values.
+func (s *Source) Snapshot() (config.Source, error) {
+	return s.snapshot()
+}
+
+// Lookup returns the value of key in the current snapshot of the document.
+func (s *Source) Lookup(key string) (string, bool, error) {
+	values, err := s.snapshot()
+	if err != nil {
+		return "", false, err
+	}
+	return values.Lookup(key)
+}
+
+// Keys returns the keys of the current snapshot of the document.
+func (s *Source) Keys() ([]string, error) {
+	values, err := s.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return values.Keys()
+}
+
+// snapshot returns the cached values of the document, fetching them again when they are missing or
+// the refresh interval has elapsed.
+func (s *Source) snapshot() (config.KeySource, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.values == nil || (s.refresh > 0 && s.now().Sub(s.fetched) >= s.refresh) {
+		if err := s.fetch(); err != nil {
+			return nil, err
+		}
+		s.fetched = s.now()
+	}
+	return s.values, nil
+}
+
+// fetch fetches and decodes the document, unless it has not changed since it was last fetched.
+func (s *Source) fetch() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return fmt.Errorf("remote: fetching %s: %w", s.url, err)
+	}
+	r.Header = s.header.Clone()
+	if s.values != nil && s.etag != "" {
+		r.Header.Set("If-None-Match", s.etag)
+	}
+
+	res, err := s.client.Do(r)
+	if err != nil {
+		return fmt.Errorf("remote: fetching %s: %w", s.url, err)
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		if s.values != nil {
+			return nil
+		}
+		fallthrough
+	default:
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("remote: fetching %s: unexpected status %s: %s", s.url, res.Status, bytes.TrimSpace(body))
+	}
+
+	format, err := s.detectFormat(res)
+	if err != nil {
+		return err
+	}
+	values, err := config.ReadDocument(s.prefix, res.Body, format)
+	if err != nil {
+		return fmt.Errorf("remote: decoding %s: %w", s.url, err)
+	}
+	s.values, s.etag = values, res.Header.Get("ETag")
+	return nil
+}
+
+// detectFormat returns the format of the document in res.
+func (s *Source) detectFormat(res *http.Response) (string, error) {
+	if s.format != "" {
+		return s.format, nil
+	}
+	if mediaType, _, err := mime.ParseMediaType(res.Header.Get("Content-Type")); err == nil {
+		if format, ok := contentTypes[mediaType]; ok {
+			return format, nil
+		}
+		if strings.HasSuffix(mediaType, "+json") {
+			return config.FormatJSON, nil
+		}
+	}
+	if u, err := url.Parse(s.url); err == nil {
+		if format, ok := config.DetectFormat(u.Path); ok {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("remote: unknown format of %s, use WithFormat", s.url)
+}
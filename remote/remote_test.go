@@ -0,0 +1,174 @@
+package remote
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/josemukorivo/config"
+)
+
+type Config struct {
+	Host string
+	DB   struct {
+		Port int
+	}
+}
+
+func TestSource(t *testing.T) {
+	var requests, downloads int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/app":
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			downloads++
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte(`{"host": "example.com", "db": {"port": 5432}}`))
+		case "/app.yaml":
+			w.Write([]byte("host: example.com\ndb:\n  port: 5432\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		description string
+		path        string
+	}{
+		{
+			description: "content type",
+			path:        "/app",
+		},
+		{
+			description: "extension",
+			path:        "/app.yaml",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var cfg Config
+			source := New(server.URL+tc.path, "app", WithHeader("Authorization", "Bearer token"))
+			if err := config.ParseSources("app", &cfg, source); err != nil {
+				t.Fatal(err)
+			}
+
+			if cfg.Host != "example.com" {
+				t.Fatalf("expected host to be example.com, got %s", cfg.Host)
+			}
+			if cfg.DB.Port != 5432 {
+				t.Fatalf("expected db port to be 5432, got %d", cfg.DB.Port)
+			}
+		})
+	}
+
+	t.Run("refresh", func(t *testing.T) {
+		requests, downloads = 0, 0
+		now := time.Now()
+		source := New(server.URL+"/app", "app",
+			WithHeader("Authorization", "Bearer token"),
+			WithRefreshInterval(time.Minute),
+		)
+		source.now = func() time.Time { return now }
+
+		for i := 0; i < 3; i++ {
+			value, ok, err := source.Lookup("APP_HOST")
+			if err != nil || !ok || value != "example.com" {
+				t.Fatalf("expected APP_HOST to be example.com, got %q %v %v", value, ok, err)
+			}
+			now = now.Add(40 * time.Second)
+		}
+
+		if requests != 2 || downloads != 1 {
+			t.Fatalf("expected 2 requests and 1 download, got %d and %d", requests, downloads)
+		}
+	})
+}
+
+func TestSourceSnapshot(t *testing.T) {
+	version := "v1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{
+			"user": %[1]q,
+			"password": %[1]q,
+			"dsns": ["postgres://db1/app?options=a,b", "postgres://db2/app"],
+			"tenants": {"acme": {"token": "t1"}, "globex": {"token": "t2"}}
+		}`, version)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	source := New(server.URL, "app", WithRefreshInterval(time.Minute))
+	source.now = func() time.Time { return now }
+
+	var cfg struct {
+		User     string
+		Password string
+		DSNs     []string `sep:"|"`
+		Tenants  map[string]struct {
+			Token string
+		}
+	}
+	// The document changes while the config is parsed, after the user is looked up.
+	parse := config.SourceFunc(func(key string) (string, bool, error) {
+		if key == "APP_PASSWORD" {
+			version = "v2"
+			now = now.Add(2 * time.Minute)
+		}
+		return "", false, nil
+	})
+	if err := config.ParseSources("app", &cfg, source, parse); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.User != "v1" || cfg.Password != "v1" {
+		t.Fatalf("expected both values from the same version of the document, got %s and %s", cfg.User, cfg.Password)
+	}
+	if !reflect.DeepEqual(cfg.DSNs, []string{"postgres://db1/app?options=a,b", "postgres://db2/app"}) {
+		t.Fatalf("expected the dsns to keep their commas, got %q", cfg.DSNs)
+	}
+	if len(cfg.Tenants) != 2 || cfg.Tenants["acme"].Token != "t1" || cfg.Tenants["globex"].Token != "t2" {
+		t.Fatalf("expected the tenants acme and globex, got %+v", cfg.Tenants)
+	}
+
+	if err := config.ParseSources("app", &cfg, source); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.User != "v2" || cfg.Password != "v2" {
+		t.Fatalf("expected the next parse to see the new version, got %s and %s", cfg.User, cfg.Password)
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("host=example.com"))
+		default:
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	for _, path := range []string{"/app", "/app.json"} {
+		var cfg Config
+		if err := config.ParseSources("app", &cfg, New(server.URL+path, "app")); err == nil {
+			t.Fatalf("expected error for %s, got nil", path)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"log/slog"
+	"reflect"
+)
+
+// redacted replaces the value of a Secret when it is printed, logged or encoded.
+const redacted = "****"
+
+// Secret is a string that is never printed: String, GoString, MarshalJSON and LogValue return ****, so
+// a Secret can be logged with the rest of the config without leaking. Value returns the real value and
+// Close zeroes the memory holding it once it is no longer needed. Marshal writes ****, MarshalSecrets
+// writes the real value.
+//
+//	type Config struct {
+//		DBPassword config.Secret `required:"true"`
+//	}
+//	...
+//	db, err := sql.Open("postgres", dsn(cfg.DBPassword.Value()))
+//	cfg.DBPassword.Close()
+type Secret struct {
+	value []byte
+}
+
+// Value returns the real value of s.
+func (s Secret) Value() string {
+	return string(s.value)
+}
+
+// String returns ****, it implements fmt.Stringer.
+func (s Secret) String() string {
+	return redacted
+}
+
+// GoString returns ****, it is used by the %#v verb.
+func (s Secret) GoString() string {
+	return redacted
+}
+
+// MarshalJSON returns "****", it implements json.Marshaler.
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + redacted + `"`), nil
+}
+
+// LogValue returns ****, it implements slog.LogValuer.
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(redacted)
+}
+
+// Close zeroes the memory holding the value of s and empties s. Copies of s made before Close share
+// the zeroed memory. Close always returns nil.
+func (s *Secret) Close() error {
+	clear(s.value)
+	s.value = nil
+	return nil
+}
+
+func parseSecret(value string, _ reflect.StructTag) (any, error) {
+	return Secret{value: []byte(value)}, nil
+}
+
+func formatSecret(v any) string {
+	return v.(Secret).Value()
+}
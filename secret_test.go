@@ -0,0 +1,119 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSecret(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_PASSWORD", "hunter2")
+
+	spec := struct {
+		Password Secret
+		Token    *Secret
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Password.Value() != "hunter2" {
+		t.Fatalf("expected password to be hunter2, got %s", spec.Password.Value())
+	}
+	if spec.Token != nil {
+		t.Fatalf("expected token to be nil, got %v", spec.Token)
+	}
+
+	var logs bytes.Buffer
+	slog.New(slog.NewTextHandler(&logs, nil)).Info("config", "password", spec.Password)
+	data, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	printed := []string{fmt.Sprint(spec.Password), fmt.Sprintf("%+v", spec), fmt.Sprintf("%#v", spec), string(data), logs.String()}
+	for _, p := range printed {
+		if strings.Contains(p, "hunter2") || !strings.Contains(p, "****") {
+			t.Fatalf("expected the password to be redacted, got %s", p)
+		}
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_PASSWORD"] != "****" {
+		t.Fatalf("expected the password to be redacted, got %s", values["APP_PASSWORD"])
+	}
+	values, err = MarshalSecrets("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_PASSWORD"] != "hunter2" {
+		t.Fatalf("expected the password to be marshaled, got %s", values["APP_PASSWORD"])
+	}
+
+	value := spec.Password.value
+	if err := spec.Password.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if spec.Password.Value() != "" || !bytes.Equal(value, make([]byte, len("hunter2"))) {
+		t.Fatalf("expected the password to be zeroed, got %q", value)
+	}
+}
+
+func TestSecretTag(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_PIN", "12a4")
+
+	spec := struct {
+		PIN    int    `secret:"true"`
+		APIKey string `secret:"true" default:"dev-key"`
+	}{}
+
+	err := Parse("app", &spec)
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) {
+		t.Fatalf("expected a FieldError, got %v", err)
+	}
+	if strings.Contains(err.Error(), "12a4") || !strings.Contains(err.Error(), "****") {
+		t.Fatalf("expected the value to be redacted, got %s", err)
+	}
+
+	// The validators quote the value, escaping it.
+	for _, tag := range []string{`pattern:"^[a-z]+$"`, `oneof:"debug info"`} {
+		os.Clearenv()
+		os.Setenv("APP_TOKEN", "zq\"x")
+		spec := reflect.New(reflect.StructOf([]reflect.StructField{{
+			Name: "Token",
+			Type: reflect.TypeOf(""),
+			Tag:  reflect.StructTag(`secret:"true" ` + tag),
+		}}))
+		err := Parse("app", spec.Interface())
+		if !errors.As(err, &fieldErr) {
+			t.Fatalf("expected a FieldError, got %v", err)
+		}
+		if strings.Contains(err.Error(), "zq") {
+			t.Fatalf("expected the value to be left out, got %s", err)
+		}
+	}
+
+	var out bytes.Buffer
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	fs.SetOutput(&out)
+	if err := BindFlags(fs, &spec); err != nil {
+		t.Fatal(err)
+	}
+	fs.PrintDefaults()
+	if strings.Contains(out.String(), "dev-key") || !strings.Contains(out.String(), "(default ****)") {
+		t.Fatalf("expected the default of the api key to be redacted, got %s", out.String())
+	}
+}
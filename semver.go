@@ -0,0 +1,162 @@
+package config
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Semver is a semantic version, see https://semver.org, like 1.4.2, 2.0.0-rc.1 or v1.2.3+build.5. The
+// leading v is optional.
+//
+//	type Config struct {
+//		MinPeerVersion config.Semver `default:"1.4.0"`
+//	}
+//	...
+//	if peer.Less(cfg.MinPeerVersion) {
+//		return fmt.Errorf("peer version %s is older than %s", peer, cfg.MinPeerVersion)
+//	}
+type Semver struct {
+	Major, Minor, Patch uint64
+	Prerelease          string // The dot separated identifiers after the -, like rc.1.
+	Build               string // The dot separated identifiers after the +, ignored by Compare.
+}
+
+// ParseSemver parses a semantic version, for example to compare a version received at runtime with a
+// Semver field.
+func ParseSemver(value string) (Semver, error) {
+	var (
+		v                       Semver
+		hasBuild, hasPrerelease bool
+	)
+	s := strings.TrimPrefix(value, "v")
+	s, v.Build, hasBuild = strings.Cut(s, "+")
+	s, v.Prerelease, hasPrerelease = strings.Cut(s, "-")
+
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return Semver{}, fmt.Errorf("invalid version %q, expected major.minor.patch", value)
+	}
+	for i, n := range []*uint64{&v.Major, &v.Minor, &v.Patch} {
+		if !isNumericIdentifier(parts[i]) {
+			return Semver{}, fmt.Errorf("invalid version %q, %q is not a number", value, parts[i])
+		}
+		var err error
+		if *n, err = strconv.ParseUint(parts[i], 10, 64); err != nil {
+			return Semver{}, fmt.Errorf("invalid version %q: %w", value, err)
+		}
+	}
+
+	if hasPrerelease && !validIdentifiers(v.Prerelease, true) {
+		return Semver{}, fmt.Errorf("invalid pre-release %q in version %q", v.Prerelease, value)
+	}
+	if hasBuild && !validIdentifiers(v.Build, false) {
+		return Semver{}, fmt.Errorf("invalid build metadata %q in version %q", v.Build, value)
+	}
+	return v, nil
+}
+
+// Set parses value into v, it implements Setter.
+func (v *Semver) Set(value string) error {
+	parsed, err := ParseSemver(value)
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+// String formats v without the leading v, for example 2.0.0-rc.1.
+func (v Semver) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Compare returns -1 if v is lower than w, 0 if they have the same precedence and +1 if v is greater
+// than w. A pre-release is lower than its release and the build metadata is ignored.
+func (v Semver) Compare(w Semver) int {
+	if c := cmp.Compare(v.Major, w.Major); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Minor, w.Minor); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Patch, w.Patch); c != 0 {
+		return c
+	}
+
+	switch {
+	case v.Prerelease == w.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case w.Prerelease == "":
+		return -1
+	}
+	a, b := strings.Split(v.Prerelease, "."), strings.Split(w.Prerelease, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := compareIdentifiers(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+// Less reports whether v is lower than w, see Compare.
+func (v Semver) Less(w Semver) bool {
+	return v.Compare(w) < 0
+}
+
+// AtLeast reports whether v is greater than or equal to w, see Compare.
+func (v Semver) AtLeast(w Semver) bool {
+	return v.Compare(w) >= 0
+}
+
+// compareIdentifiers compares two pre-release identifiers, numeric identifiers are compared as numbers
+// and are lower than alphanumeric identifiers.
+func compareIdentifiers(a, b string) int {
+	an, bn := isNumericIdentifier(a), isNumericIdentifier(b)
+	switch {
+	case an && bn:
+		if c := cmp.Compare(len(a), len(b)); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	case an:
+		return -1
+	case bn:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// validIdentifiers reports whether s is a dot separated list of identifiers made of ASCII letters,
+// digits and hyphens. The numeric identifiers of a pre-release must not have leading zeros.
+func validIdentifiers(s string, prerelease bool) bool {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" || strings.IndexFunc(id, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-')
+		}) >= 0 {
+			return false
+		}
+		if prerelease && strings.Trim(id, "0123456789") == "" && !isNumericIdentifier(id) {
+			return false
+		}
+	}
+	return true
+}
+
+// isNumericIdentifier reports whether s is a number without leading zeros.
+func isNumericIdentifier(s string) bool {
+	if s == "" || len(s) > 1 && s[0] == '0' {
+		return false
+	}
+	return strings.Trim(s, "0123456789") == ""
+}
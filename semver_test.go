@@ -0,0 +1,98 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestParseSemver(t *testing.T) {
+	tests := []struct {
+		description string
+		value       string
+		expected    Semver
+		err         bool
+	}{
+		{description: "release", value: "1.4.2", expected: Semver{Major: 1, Minor: 4, Patch: 2}},
+		{description: "leading v", value: "v2.0.0", expected: Semver{Major: 2}},
+		{description: "pre-release and build", value: "2.0.0-rc.1+build-5", expected: Semver{Major: 2, Prerelease: "rc.1", Build: "build-5"}},
+		{description: "build with hyphen", value: "1.0.0+exp.sha-5114f85", expected: Semver{Major: 1, Build: "exp.sha-5114f85"}},
+		{description: "missing patch", value: "1.4", err: true},
+		{description: "leading zero", value: "01.4.2", err: true},
+		{description: "numeric pre-release with leading zero", value: "1.4.2-01", err: true},
+		{description: "empty pre-release", value: "1.4.2-", err: true},
+		{description: "invalid build", value: "1.4.2+build_5", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			v, err := ParseSemver(tt.value)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected an error for %s, got %v", tt.value, v)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v != tt.expected {
+				t.Fatalf("expected version to be %+v, got %+v", tt.expected, v)
+			}
+		})
+	}
+}
+
+func TestSemverCompare(t *testing.T) {
+	// Ordered as in the example of the specification.
+	versions := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta", "1.0.0-beta.2", "1.0.0-beta.11",
+		"1.0.0-rc.1", "1.0.0", "1.0.1", "1.1.0", "2.0.0",
+	}
+	for i := 1; i < len(versions); i++ {
+		lower, _ := ParseSemver(versions[i-1])
+		higher, _ := ParseSemver(versions[i])
+		if !lower.Less(higher) || higher.Less(lower) || !higher.AtLeast(lower) {
+			t.Fatalf("expected %s to be lower than %s", lower, higher)
+		}
+	}
+
+	a, _ := ParseSemver("1.0.0+build.1")
+	b, _ := ParseSemver("1.0.0+build.2")
+	if a.Compare(b) != 0 {
+		t.Fatalf("expected the build metadata to be ignored, got %d", a.Compare(b))
+	}
+}
+
+func TestParseSemverField(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_MIN_PEER_VERSION", "v1.4.0-rc.2")
+
+	spec := struct {
+		MinPeerVersion Semver `env:"app_min_peer_version"`
+		Default        Semver `default:"1.0.0"`
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.MinPeerVersion.String() != "1.4.0-rc.2" || spec.Default.Major != 1 {
+		t.Fatalf("expected the versions to be 1.4.0-rc.2 and 1.0.0, got %s and %s", spec.MinPeerVersion, spec.Default)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_MIN_PEER_VERSION"] != "1.4.0-rc.2" {
+		t.Fatalf("expected the version to be marshaled to 1.4.0-rc.2, got %s", values["APP_MIN_PEER_VERSION"])
+	}
+
+	os.Setenv("APP_MIN_PEER_VERSION", "latest")
+	err = Parse("app", &spec)
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) {
+		t.Fatalf("expected a FieldError for the invalid version, got %v", err)
+	}
+}
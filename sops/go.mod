@@ -0,0 +1,17 @@
+module github.com/josemukorivo/config/sops
+
+go 1.22
+
+require (
+	filippo.io/age v1.2.1
+	github.com/josemukorivo/config v0.0.0-20261016120655-98cbede698f8
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/BurntSushi/toml v1.4.0 // indirect
+	github.com/hashicorp/hcl v1.0.0 // indirect
+	github.com/spf13/pflag v1.0.5 // indirect
+	golang.org/x/crypto v0.24.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+)
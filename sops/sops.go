@@ -0,0 +1,415 @@
+// Package sops provides a config.Source backed by a YAML or JSON config file encrypted with SOPS. The
+// file is decrypted in memory, the plaintext never touches the disk.
+//
+//	source := sops.New("app", "secrets.enc.yaml")
+//	err := config.ParseSources("app", &cfg, config.FileSource("app", "config.yaml"), source, config.EnvSource())
+//
+// The data key of the file is decrypted with age or PGP out of the box. age identities are read from
+// SOPS_AGE_KEY, SOPS_AGE_KEY_FILE or the sops/age/keys.txt file of the user config directory, like the
+// sops command does, and PGP keys with the gpg command. Other backends, like AWS KMS, are plugged in
+// with WithKeyDecrypter.
+//
+// The package implements the part of SOPS needed to read config files and does not depend on the
+// sops module and the SDKs of its backends. Files it cannot decrypt are rejected with an error:
+//
+//   - files encrypted with key groups, the data key is split with Shamir's secret sharing,
+//   - files whose data key is only encrypted with kms, gcp_kms, azure_kv or hc_vault and no
+//     WithKeyDecrypter is given for them,
+//   - dotenv, INI and binary files, only YAML and JSON documents are read.
+//
+// Decrypt those files with the sops command or github.com/getsops/sops/v3/decrypt instead.
+package sops
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/josemukorivo/config"
+	"gopkg.in/yaml.v3"
+)
+
+// KeyDecrypter decrypts the data key of a file with one of its key entries, for example an entry of
+// the kms list of the sops metadata, with the fields arn, enc and context.
+type KeyDecrypter func(key map[string]any) ([]byte, error)
+
+// Option configures the decryption.
+type Option func(*decrypter)
+
+// WithAgeIdentities decrypts the data key with the given age identities instead of the identities
+// found in the environment.
+func WithAgeIdentities(identities ...age.Identity) Option {
+	return func(d *decrypter) {
+		d.identities = identities
+	}
+}
+
+// WithKeyDecrypter decrypts the data key with fn for the key entries of the given backend, the name of
+// its list in the sops metadata, for example "kms", "gcp_kms", "azure_kv" or "hc_vault". It replaces
+// the built-in decrypter of "age" and "pgp".
+func WithKeyDecrypter(backend string, fn KeyDecrypter) Option {
+	return func(d *decrypter) {
+		d.backends[backend] = fn
+	}
+}
+
+// New returns a config.Source that looks up the keys of the SOPS encrypted YAML or JSON config file at
+// path. Keys are prefixed with prefix the same way config.FileSource does. The file is read and
+// decrypted on the first lookup.
+func New(prefix, path string, opts ...Option) config.Source {
+	return config.LoadOnce(func() (config.MapSource, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("sops: reading %s: %w", path, err)
+		}
+		plain, err := Decrypt(data, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("sops: decrypting %s: %w", path, err)
+		}
+		return config.ReadValues(prefix, bytes.NewReader(plain), config.FormatYAML)
+	})
+}
+
+// Decrypt decrypts the SOPS encrypted YAML or JSON document data and returns the plaintext document as
+// YAML, without the sops metadata. The message authentication code of the document is verified, the
+// comments taken into account are the full-line comments above keys and list items.
+func Decrypt(data []byte, opts ...Option) ([]byte, error) {
+	d := &decrypter{backends: make(map[string]KeyDecrypter)}
+	d.backends["age"] = d.decryptAge
+	d.backends["pgp"] = decryptPGP
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("not a SOPS document")
+	}
+	root := doc.Content[0]
+
+	var meta *yaml.Node
+	for i := 0; i < len(root.Content); i += 2 {
+		if root.Content[i].Value == "sops" {
+			meta = root.Content[i+1]
+			root.Content = append(root.Content[:i], root.Content[i+2:]...)
+			break
+		}
+	}
+	if meta == nil {
+		return nil, errors.New("not a SOPS document, the sops metadata is missing")
+	}
+	var m metadata
+	if err := meta.Decode(&m); err != nil {
+		return nil, fmt.Errorf("decoding the sops metadata: %w", err)
+	}
+	var keys map[string]any
+	if err := meta.Decode(&keys); err != nil {
+		return nil, fmt.Errorf("decoding the sops metadata: %w", err)
+	}
+
+	key, err := d.dataKey(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &walker{key: key, hash: sha512.New(), macOnlyEncrypted: m.MACOnlyEncrypted}
+	if err := w.walk(root, nil); err != nil {
+		return nil, err
+	}
+	if err := w.verify(m); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(root)
+}
+
+// metadata is the part of the sops metadata needed to verify a document.
+type metadata struct {
+	LastModified     string `yaml:"lastmodified"`
+	MAC              string `yaml:"mac"`
+	MACOnlyEncrypted bool   `yaml:"mac_only_encrypted"`
+}
+
+type decrypter struct {
+	identities []age.Identity
+	backends   map[string]KeyDecrypter
+}
+
+// backends are the names of the lists of key entries in the sops metadata.
+var backends = []string{"age", "pgp", "kms", "gcp_kms", "azure_kv", "hc_vault"}
+
+// dataKey decrypts the data key with the first key entry that can decrypt it.
+func (d *decrypter) dataKey(meta map[string]any) ([]byte, error) {
+	if _, ok := meta["key_groups"]; ok {
+		return nil, errors.New("key groups are not supported, decrypt the file with the sops command")
+	}
+
+	names := slices.Clone(backends)
+	for backend := range d.backends {
+		if !slices.Contains(names, backend) {
+			names = append(names, backend)
+		}
+	}
+	var (
+		errs        []error
+		unsupported []string
+	)
+	for _, backend := range names {
+		entries, _ := meta[backend].([]any)
+		if len(entries) == 0 {
+			continue
+		}
+		fn, ok := d.backends[backend]
+		if !ok {
+			unsupported = append(unsupported, backend)
+			continue
+		}
+		for _, entry := range entries {
+			key, ok := entry.(map[string]any)
+			if !ok {
+				continue
+			}
+			dataKey, err := fn(key)
+			if err == nil {
+				return dataKey, nil
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", backend, err))
+		}
+	}
+	if len(errs) == 0 && len(unsupported) > 0 {
+		return nil, fmt.Errorf("the data key is only encrypted with %s, use WithKeyDecrypter to decrypt it", strings.Join(unsupported, ", "))
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("the sops metadata has no key entry")
+	}
+	return nil, fmt.Errorf("no key could decrypt the data key: %w", errors.Join(errs...))
+}
+
+// decryptAge decrypts the data key with the age identities.
+func (d *decrypter) decryptAge(key map[string]any) ([]byte, error) {
+	identities := d.identities
+	if identities == nil {
+		var err error
+		if identities, err = ageIdentities(); err != nil {
+			return nil, err
+		}
+	}
+
+	enc, _ := key["enc"].(string)
+	r, err := age.Decrypt(armor.NewReader(strings.NewReader(enc)), identities...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// ageIdentities reads the age identities from the environment the way the sops command does.
+func ageIdentities() ([]age.Identity, error) {
+	if key := os.Getenv("SOPS_AGE_KEY"); key != "" {
+		return age.ParseIdentities(strings.NewReader(key))
+	}
+	path := os.Getenv("SOPS_AGE_KEY_FILE")
+	if path == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dir, "sops", "age", "keys.txt")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return age.ParseIdentities(f)
+}
+
+// decryptPGP decrypts the data key with the gpg command.
+func decryptPGP(key map[string]any) ([]byte, error) {
+	enc, _ := key["enc"].(string)
+	cmd := exec.Command("gpg", "--batch", "--quiet", "--decrypt")
+	cmd.Stdin = strings.NewReader(enc)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// encrypted matches the values encrypted by SOPS.
+var encrypted = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.+),tag:(.+),type:(.+)\]$`)
+
+// walker decrypts the values of a document in place and hashes them to verify the document.
+type walker struct {
+	key              []byte
+	hash             hash.Hash
+	macOnlyEncrypted bool
+}
+
+// walk decrypts the node at path, the keys of the maps leading to it.
+func (w *walker) walk(node *yaml.Node, path []string) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content); i += 2 {
+			if err := w.comments(node.Content[i].HeadComment, path); err != nil {
+				return err
+			}
+			p := append(path[:len(path):len(path)], node.Content[i].Value)
+			if err := w.walk(node.Content[i+1], p); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if err := w.comments(item.HeadComment, path); err != nil {
+				return err
+			}
+			if err := w.walk(item, path); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		return w.scalar(node, path)
+	}
+	return nil
+}
+
+// comments decrypts and hashes the comment lines above a key or an item, SOPS encrypts them too.
+func (w *walker) comments(comment string, path []string) error {
+	if comment == "" {
+		return nil
+	}
+	for _, line := range strings.Split(comment, "\n") {
+		if line == "" {
+			continue
+		}
+		value := strings.TrimPrefix(line, "#")
+		isEncrypted := encrypted.MatchString(value)
+		if isEncrypted {
+			var err error
+			if value, _, err = w.decrypt(value, strings.Join(path, ":")+":"); err != nil {
+				return err
+			}
+		}
+		if isEncrypted || !w.macOnlyEncrypted {
+			w.hash.Write([]byte(value))
+		}
+	}
+	return nil
+}
+
+// scalar decrypts and hashes a value.
+func (w *walker) scalar(node *yaml.Node, path []string) error {
+	if node.Tag == "!!null" {
+		return nil
+	}
+
+	isEncrypted := node.Tag == "!!str" && encrypted.MatchString(node.Value)
+	typ := strings.TrimPrefix(node.Tag, "!!")
+	value := node.Value
+	if isEncrypted {
+		var err error
+		if value, typ, err = w.decrypt(node.Value, strings.Join(path, ":")+":"); err != nil {
+			return fmt.Errorf("decrypting %s: %w", strings.Join(path, "."), err)
+		}
+		node.Value, node.Tag, node.Style = value, "!!"+typ, 0
+		if typ == "bytes" {
+			node.Tag = "!!str"
+		}
+	}
+
+	if isEncrypted || !w.macOnlyEncrypted {
+		w.hash.Write(macBytes(value, typ))
+	}
+	return nil
+}
+
+// macBytes returns the bytes of a value SOPS hashes, the value as formatted by Go.
+func macBytes(value, typ string) []byte {
+	switch typ {
+	case "int":
+		if i, err := strconv.ParseInt(value, 0, 64); err == nil {
+			return []byte(strconv.FormatInt(i, 10))
+		}
+	case "float":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return []byte(strconv.FormatFloat(f, 'f', -1, 64))
+		}
+	case "bool":
+		var b bool
+		if err := yaml.Unmarshal([]byte(value), &b); err == nil {
+			if b {
+				return []byte("True")
+			}
+			return []byte("False")
+		}
+	}
+	return []byte(value)
+}
+
+// decrypt decrypts a value encrypted with AES-GCM and returns the plaintext and its type.
+func (w *walker) decrypt(value, additionalData string) (string, string, error) {
+	m := encrypted.FindStringSubmatch(value)
+	var parts [3][]byte
+	for i := range parts {
+		b, err := base64.StdEncoding.DecodeString(m[i+1])
+		if err != nil {
+			return "", "", err
+		}
+		parts[i] = b
+	}
+	data, iv, tag := parts[0], parts[1], parts[2]
+
+	block, err := aes.NewCipher(w.key)
+	if err != nil {
+		return "", "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return "", "", err
+	}
+	plain, err := gcm.Open(nil, iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return "", "", err
+	}
+	return string(plain), m[4], nil
+}
+
+// verify checks the message authentication code of the document.
+func (w *walker) verify(m metadata) error {
+	if !encrypted.MatchString(m.MAC) {
+		return errors.New("the message authentication code is missing")
+	}
+	lastModified, err := time.Parse(time.RFC3339, m.LastModified)
+	if err != nil {
+		return fmt.Errorf("invalid lastmodified: %w", err)
+	}
+	mac, _, err := w.decrypt(m.MAC, lastModified.Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("decrypting the message authentication code: %w", err)
+	}
+	if mac != fmt.Sprintf("%X", w.hash.Sum(nil)) {
+		return errors.New("the message authentication code does not match, the document was tampered with")
+	}
+	return nil
+}
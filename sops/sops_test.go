@@ -0,0 +1,314 @@
+package sops
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/josemukorivo/config"
+)
+
+// encrypt encrypts a value the way SOPS does.
+func encrypt(t *testing.T, key []byte, value, typ, additionalData string) string {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iv := make([]byte, 32)
+	rand.Read(iv)
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := gcm.Seal(nil, iv, []byte(value), []byte(additionalData))
+	data, tag := out[:len(out)-gcm.Overhead()], out[len(out)-gcm.Overhead():]
+	b64 := base64.StdEncoding.EncodeToString
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:%s]", b64(data), b64(iv), b64(tag), typ)
+}
+
+// encryptFile writes a document encrypted for the age identity and returns its path.
+func encryptFile(t *testing.T, identity *age.X25519Identity, tamper bool) string {
+	t.Helper()
+	key := make([]byte, 32)
+	rand.Read(key)
+
+	var enc bytes.Buffer
+	aw := armor.NewWriter(&enc)
+	w, err := age.Encrypt(aw, identity.Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(key)
+	w.Close()
+	aw.Close()
+
+	keys := fmt.Sprintf(`    age:
+        - recipient: %s
+          enc: |
+%s
+`, identity.Recipient(), indent(enc.String(), "            "))
+	return writeDocument(t, key, keys, tamper)
+}
+
+// writeDocument writes a document encrypted with the data key, whose sops metadata lists keys, and
+// returns its path.
+func writeDocument(t *testing.T, key []byte, keys string, tamper bool) string {
+	t.Helper()
+	hash := sha512.New()
+	for _, v := range []string{"example.com", "database password", "s3cr3t", "5432", "True", "a", "b"} {
+		hash.Write([]byte(v))
+	}
+	lastModified := time.Now().UTC().Format(time.RFC3339)
+	mac := encrypt(t, key, fmt.Sprintf("%X", hash.Sum(nil)), "str", lastModified)
+
+	password := "s3cr3t"
+	if tamper {
+		password = "tampered"
+	}
+	doc := fmt.Sprintf(`host: example.com
+db:
+    #%s
+    password: %s
+    port: %s
+    tls: %s
+tags:
+    - %s
+    - %s
+sops:
+%s    lastmodified: "%s"
+    mac: %s
+    version: 3.8.1
+`,
+		encrypt(t, key, "database password", "comment", "db:"),
+		encrypt(t, key, password, "str", "db:password:"),
+		encrypt(t, key, "5432", "int", "db:port:"),
+		encrypt(t, key, "True", "bool", "db:tls:"),
+		encrypt(t, key, "a", "str", "tags:"),
+		encrypt(t, key, "b", "str", "tags:"),
+		keys,
+		lastModified,
+		mac,
+	)
+
+	path := filepath.Join(t.TempDir(), "secrets.enc.yaml")
+	if err := os.WriteFile(path, []byte(doc), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// encryptPGPFile writes a document whose data key is encrypted with the gpg command for a key generated
+// in a temporary GNUPGHOME, which it sets, and returns its path.
+func encryptPGPFile(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg is not installed")
+	}
+	home := t.TempDir()
+	t.Setenv("GNUPGHOME", home)
+	t.Cleanup(func() {
+		exec.Command("gpgconf", "--kill", "gpg-agent").Run()
+	})
+
+	gpg := func(stdin []byte, args ...string) []byte {
+		cmd := exec.Command("gpg", append([]string{"--batch", "--quiet"}, args...)...)
+		cmd.Stdin = bytes.NewReader(stdin)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("gpg %s: %v", strings.Join(args, " "), err)
+		}
+		return out
+	}
+	gpg(nil, "--passphrase", "", "--quick-gen-key", "sops test <sops@example.com>", "default", "default", "never")
+	var fingerprint string
+	for _, line := range strings.Split(string(gpg(nil, "--with-colons", "--list-keys")), "\n") {
+		if fields := strings.Split(line, ":"); fields[0] == "fpr" {
+			fingerprint = fields[9]
+			break
+		}
+	}
+
+	key := make([]byte, 32)
+	rand.Read(key)
+	enc := gpg(key, "--armor", "--encrypt", "--trust-model", "always", "--recipient", fingerprint)
+
+	keys := fmt.Sprintf(`    pgp:
+        - fp: %s
+          created_at: "%s"
+          enc: |
+%s
+`, fingerprint, time.Now().UTC().Format(time.RFC3339), indent(string(enc), "            "))
+	return writeDocument(t, key, keys, false)
+}
+
+func indent(s, prefix string) string {
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	return prefix + strings.Join(lines, "\n"+prefix)
+}
+
+type Config struct {
+	Host string
+	DB   struct {
+		Password string
+		Port     int
+		TLS      bool
+	}
+	Tags string
+}
+
+func TestSource(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := encryptFile(t, identity, false)
+
+	os.Clearenv()
+	os.Setenv("SOPS_AGE_KEY", identity.String())
+
+	var cfg Config
+	if err := config.ParseSources("app", &cfg, New("app", path)); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Host != "example.com" {
+		t.Fatalf("expected host to be example.com, got %s", cfg.Host)
+	}
+	if cfg.DB.Password != "s3cr3t" {
+		t.Fatalf("expected db password to be s3cr3t, got %s", cfg.DB.Password)
+	}
+	if cfg.DB.Port != 5432 {
+		t.Fatalf("expected db port to be 5432, got %d", cfg.DB.Port)
+	}
+	if !cfg.DB.TLS {
+		t.Fatal("expected db tls to be true, got false")
+	}
+	if cfg.Tags != "a,b" {
+		t.Fatalf("expected tags to be a,b, got %s", cfg.Tags)
+	}
+}
+
+// path is the PATH of the tests, read before they clear the environment, to find the gpg command.
+var path = os.Getenv("PATH")
+
+func TestSourcePGP(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("PATH", path)
+	file := encryptPGPFile(t)
+
+	var cfg Config
+	if err := config.ParseSources("app", &cfg, New("app", file)); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.DB.Password != "s3cr3t" {
+		t.Fatalf("expected db password to be s3cr3t, got %s", cfg.DB.Password)
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Clearenv()
+
+	failing := func(key map[string]any) ([]byte, error) {
+		return nil, errors.New("access denied")
+	}
+
+	tests := []struct {
+		description string
+		path        string
+		opts        []Option
+	}{
+		{
+			description: "tampered document",
+			path:        encryptFile(t, identity, true),
+			opts:        []Option{WithAgeIdentities(identity)},
+		},
+		{
+			description: "wrong identity",
+			path:        encryptFile(t, identity, false),
+			opts:        []Option{WithAgeIdentities(other)},
+		},
+		{
+			description: "failing decrypter",
+			path:        encryptFile(t, identity, false),
+			opts:        []Option{WithKeyDecrypter("age", failing)},
+		},
+		{
+			description: "missing file",
+			path:        "missing.yaml",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var cfg Config
+			if err := config.ParseSources("app", &cfg, New("app", tc.path, tc.opts...)); err == nil {
+				t.Fatal("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestDecryptUnsupported(t *testing.T) {
+	tests := []struct {
+		description string
+		metadata    string
+		expected    string
+	}{
+		{
+			description: "key groups",
+			metadata: `    key_groups:
+        - age:
+            - recipient: age1example
+              enc: data
+    shamir_threshold: 1
+`,
+			expected: "key groups are not supported",
+		},
+		{
+			description: "kms",
+			metadata: `    kms:
+        - arn: arn:aws:kms:us-east-1:123456789012:key/example
+          enc: data
+`,
+			expected: "only encrypted with kms, use WithKeyDecrypter",
+		},
+		{
+			description: "no key",
+			metadata:    "    version: 3.9.0\n",
+			expected:    "no key entry",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			doc := "password: ENC[AES256_GCM,data:AA==,iv:AA==,tag:AA==,type:str]\nsops:\n" + tc.metadata
+			_, err := Decrypt([]byte(doc))
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Fatalf("expected error containing %q, got %v", tc.expected, err)
+			}
+		})
+	}
+}
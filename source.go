@@ -0,0 +1,514 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Source is the interface that wraps the Lookup method. A Source provides the values of the fields.
+// Lookup returns the value of key and whether it was found. Keys are the keys of the fields, the
+// environment variable names, for example APP_DB_HOST. A non-nil error aborts parsing.
+type Source interface {
+	Lookup(key string) (string, bool, error)
+}
+
+// KeySource is a Source that can list its keys. Fields of type map[string]Struct are filled from the
+// keys listed by the sources implementing it, see Parse. MapSource, EnvSource, LoadOnce and the sources
+// built on them implement KeySource.
+type KeySource interface {
+	Source
+	Keys() ([]string, error)
+}
+
+// SnapshotSource is a Source whose values can change between parses, for example a remote document
+// that is refreshed periodically. The parse functions call Snapshot once and look up all the keys in
+// the Source it returns, so the values of a parse never mix two versions of the document.
+type SnapshotSource interface {
+	Source
+	Snapshot() (Source, error)
+}
+
+// FieldSource is a Source whose values depend on the field they are looked up for, for example to
+// resolve references to secrets only in the values of secret fields. The parse functions call
+// LookupField rather than Lookup to look up the keys of a field, at every level of nesting. The keys
+// looked up for other reasons, like the references of the expand tag, go through Lookup.
+type FieldSource interface {
+	Source
+	LookupField(field Field, key string) (string, bool, error)
+}
+
+// listSource is implemented by the sources of config documents, like FileSource, that keep the lists
+// of the document as lists. The items of a list are joined with the separator of the field they are
+// assigned to, see lookupField.
+type listSource interface {
+	// lookupList returns the items of the value of key and whether the value is a list.
+	lookupList(key string) ([]string, bool, error)
+}
+
+// Origins of values that do not come from a Layer.
+const (
+	OriginDefault = "default"
+	OriginFlags   = "flags"
+)
+
+// Layer is a named Source, see ParseLayers.
+type Layer struct {
+	Name   string
+	Source Source
+}
+
+// Origins maps the keys of the fields to the name of the layer their value came from. Fields that were
+// not assigned a value are not included.
+type Origins map[string]string
+
+// SourceFunc is an adapter to allow the use of ordinary functions as Sources.
+type SourceFunc func(key string) (string, bool, error)
+
+// Lookup calls f(key).
+func (f SourceFunc) Lookup(key string) (string, bool, error) {
+	return f(key)
+}
+
+// MapSource is a Source backed by a map of keys to values.
+type MapSource map[string]string
+
+// Lookup returns the value of key in the map.
+func (m MapSource) Lookup(key string) (string, bool, error) {
+	value, ok := m[key]
+	return value, ok, nil
+}
+
+// Keys returns the keys of the map.
+func (m MapSource) Keys() ([]string, error) {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// EnvSource returns a Source that looks up environment variables.
+func EnvSource() Source {
+	return envSource{}
+}
+
+type envSource struct{}
+
+func (envSource) Lookup(key string) (string, bool, error) {
+	value, ok := os.LookupEnv(key)
+	return value, ok, nil
+}
+
+// Keys returns the names of the environment variables.
+func (envSource) Keys() ([]string, error) {
+	env := os.Environ()
+	keys := make([]string, 0, len(env))
+	for _, kv := range env {
+		if key, _, ok := strings.Cut(kv, "="); ok && key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// DotEnvSource returns a Source that looks up the variables defined in the .env files, it defaults to
+// the .env file in the working directory. Unlike Parse, the files are not loaded into the environment.
+// Files that do not exist are skipped, when a variable is defined in more than one file the first one
+// wins. The files are read on the first lookup.
+//
+// Each line of a file defines a variable, NAME=value, optionally preceded by export, and lines starting
+// with # are comments. Single quoted values are taken literally, double quoted values interpret the
+// escape sequences \n, \r, \t, \", \\ and \$ and both can span several lines. Unquoted and double quoted
+// values expand $NAME, ${NAME} and ${NAME:-default} from the environment or the variables defined
+// before in the file. A malformed file is reported with its name and line.
+func DotEnvSource(files ...string) Source {
+	if len(files) == 0 {
+		files = []string{".env"}
+	}
+	return LoadOnce(func() (MapSource, error) {
+		values := make(MapSource)
+		for _, file := range files {
+			vars, _, err := readEnvFile(file)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			for k, v := range vars {
+				if _, ok := values[k]; !ok {
+					values[k] = v
+				}
+			}
+		}
+		return values, nil
+	})
+}
+
+// EnvFiles returns the .env files of the environment that exist in the working directory, from the
+// highest to the lowest precedence, following the common convention:
+//
+//	.env.<environment>.local
+//	.env.local
+//	.env.<environment>
+//	.env
+//
+// The .local files hold machine-specific overrides and are not committed, .env.local is skipped in the
+// "test" environment so tests run the same everywhere. The files can be passed to Parse or
+// DotEnvSource, both give precedence to the first file defining a variable:
+//
+//	err := config.Parse("app", &cfg, config.EnvFiles(os.Getenv("APP_ENV"))...)
+func EnvFiles(environment string) []string {
+	var candidates []string
+	if environment != "" {
+		candidates = append(candidates, ".env."+environment+".local")
+	}
+	if environment != "test" {
+		candidates = append(candidates, ".env.local")
+	}
+	if environment != "" {
+		candidates = append(candidates, ".env."+environment)
+	}
+	candidates = append(candidates, ".env")
+
+	var files []string
+	for _, file := range candidates {
+		if _, err := os.Stat(file); err == nil {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// FindEnvFiles returns the .env files found in the working directory and its parents, up to the root
+// of the repository, the first directory containing .git, or the filesystem root if there is none. The
+// files are listed from the closest to the farthest, so when they are passed to Parse or DotEnvSource
+// the variables of closer files override the ones of farther files, like direnv does. This lets the
+// services of a monorepo refine a .env file shared at its root. The names are the files looked up in
+// each directory from the highest to the lowest precedence, they default to .env:
+//
+//	err := config.Parse("app", &cfg, config.FindEnvFiles(".env.local", ".env")...)
+func FindEnvFiles(names ...string) []string {
+	if len(names) == 0 {
+		names = []string{".env"}
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+
+	var files []string
+	for {
+		for _, name := range names {
+			file := filepath.Join(dir, name)
+			if info, err := os.Stat(file); err == nil && !info.IsDir() {
+				files = append(files, file)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return files
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return files
+		}
+		dir = parent
+	}
+}
+
+// FileSource returns a Source that looks up the values of the config file at path. Keys in the file are
+// prefixed with prefix, see ParseFile for the supported formats. The file is read on the first lookup.
+func FileSource(prefix, path string) Source {
+	return loadDocument(func() (documentValues, error) {
+		return readFile(prefix, path)
+	})
+}
+
+// FSSource is like FileSource but reads the config file at path from fsys, for example an embed.FS.
+// Listed first, it provides compiled-in defaults.
+func FSSource(prefix string, fsys fs.FS, path string) Source {
+	return loadDocument(func() (documentValues, error) {
+		return readFSFile(prefix, fsys, path)
+	})
+}
+
+// DirSource returns a Source that looks up the files of the directory dir, the layout of Kubernetes
+// ConfigMap and Secret volumes: each file name is a key, prefixed with prefix and normalized with
+// NormalizeKey, and the content of the file is its value, without trailing newlines. With the prefix
+// "app", the file DB_PORT or db.port is looked up as APP_DB_PORT. Hidden files and subdirectories,
+// like the ..data directory Kubernetes uses to swap the files atomically, are skipped. The directory
+// is read on the first lookup.
+func DirSource(prefix, dir string) Source {
+	return LoadOnce(func() (MapSource, error) {
+		return readConfigDir(prefix, dir)
+	})
+}
+
+// readConfigDir reads the files of dir as DirSource does. The files that cannot be read are skipped,
+// as other applications may share the directory like /run/secrets, unless the prefix is empty or their
+// name starts with it.
+func readConfigDir(prefix, dir string) (MapSource, error) {
+	values := make(MapSource)
+	required := func(name string) bool {
+		return prefix == "" || strings.HasPrefix(NormalizeKey(name)+"_", NormalizeKey(prefix)+"_")
+	}
+	err := readDir(dir, required, func(name string, data []byte) {
+		values[NormalizeKey(joinKey(prefix, name))] = strings.TrimRight(string(data), "\r\n")
+	})
+	return values, err
+}
+
+// EnvDirSource returns a Source that looks up the variables of the envdir dir, as used by daemontools
+// and runit: each file name is the name of a variable, for example APP_DB_HOST, and the first line of
+// the file is its value. Trailing spaces and tabs are removed and NUL characters are replaced by
+// newlines, an empty file leaves the variable unset. Since file names are variable names, they include
+// the prefix. The directory is read on the first lookup.
+func EnvDirSource(dir string) Source {
+	return LoadOnce(func() (MapSource, error) {
+		values := make(MapSource)
+		err := readDir(dir, nil, func(name string, data []byte) {
+			if len(data) == 0 || strings.Contains(name, "=") {
+				return
+			}
+			value, _, _ := strings.Cut(string(data), "\n")
+			value = strings.TrimRight(value, " \t")
+			values[name] = strings.ReplaceAll(value, "\x00", "\n")
+		})
+		return values, err
+	})
+}
+
+// readDir calls fn with the name and content of each file of dir, following symlinks. Hidden files
+// and subdirectories are skipped. A file that cannot be read is an error if required, when not nil,
+// reports true for its name, and is skipped otherwise.
+func readDir(dir string, required func(name string) bool, fn func(name string, data []byte)) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("config: reading config directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		info, err := os.Stat(path)
+		var data []byte
+		if err == nil && !info.IsDir() {
+			data, err = os.ReadFile(path)
+		}
+		if err != nil {
+			if required == nil || required(entry.Name()) {
+				return fmt.Errorf("config: reading config directory: %w", err)
+			}
+			continue
+		}
+		if info.IsDir() {
+			continue
+		}
+		fn(entry.Name(), data)
+	}
+	return nil
+}
+
+// dockerSecretsDir is where Docker and Compose mount secrets.
+var dockerSecretsDir = "/run/secrets"
+
+// DockerSecretsSource returns a Source that looks up the Docker secrets mounted in /run/secrets, like
+// DirSource does, so with the prefix "app" the secret db_password is looked up as APP_DB_PASSWORD. The
+// Source is empty when the directory does not exist.
+func DockerSecretsSource(prefix string) Source {
+	return LoadOnce(func() (MapSource, error) {
+		if _, err := os.Stat(dockerSecretsDir); errors.Is(err, fs.ErrNotExist) {
+			return MapSource{}, nil
+		}
+		return readConfigDir(prefix, dockerSecretsDir)
+	})
+}
+
+// CredentialsSource returns a Source that looks up the systemd credentials passed to the unit with
+// LoadCredential= or SetCredential=, the files of the directory named by $CREDENTIALS_DIRECTORY, like
+// DirSource does. With the prefix "app" the credential db_password is looked up as APP_DB_PASSWORD. The
+// Source is empty when the process has no credentials. Credentials are meant to be preferred over the
+// environment for secrets, list the Source after EnvSource for them to take precedence:
+//
+//	err := config.ParseSources("app", &cfg, config.EnvSource(), config.CredentialsSource("app"))
+func CredentialsSource(prefix string) Source {
+	return LoadOnce(func() (MapSource, error) {
+		dir := os.Getenv("CREDENTIALS_DIRECTORY")
+		if dir == "" {
+			return MapSource{}, nil
+		}
+		return readConfigDir(prefix, dir)
+	})
+}
+
+// FileRefSource returns a Source that looks up keys in source and, when a key is not found, reads the
+// value from the file named by the key suffixed with _FILE, the convention of Docker images. For
+// example, APP_DB_PASSWORD_FILE=/run/secrets/db_password sets APP_DB_PASSWORD to the content of the
+// file, without trailing newlines.
+func FileRefSource(source Source) Source {
+	return fileRefSource{source}
+}
+
+type fileRefSource struct {
+	source Source
+}
+
+func (s fileRefSource) Lookup(key string) (string, bool, error) {
+	value, ok, err := s.source.Lookup(key)
+	if ok || err != nil {
+		return value, ok, err
+	}
+	path, ok, err := s.source.Lookup(key + "_FILE")
+	if !ok || err != nil {
+		return "", false, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("config: reading %s_FILE: %w", key, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), true, nil
+}
+
+// fromFile reports whether the value of key is read from the file named by key suffixed with _FILE.
+func (s fileRefSource) fromFile(key string) bool {
+	if _, ok, err := s.source.Lookup(key); ok || err != nil {
+		return false
+	}
+	_, ok, err := s.source.Lookup(key + "_FILE")
+	return ok && err == nil
+}
+
+// Keys returns the keys of the wrapped source, with the keys suffixed with _FILE also listed without
+// the suffix. It returns no keys if the wrapped source is not a KeySource.
+func (s fileRefSource) Keys() ([]string, error) {
+	ks, ok := s.source.(KeySource)
+	if !ok {
+		return nil, nil
+	}
+	keys, err := ks.Keys()
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if key, ok := strings.CutSuffix(key, "_FILE"); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// NormalizeKey converts a hierarchical key, like "app/db/host" or "db.host", into the form of the keys
+// of the fields, "APP_DB_HOST" or "DB_HOST". Slashes, dots and dashes are replaced by underscores and
+// the key is upper cased.
+func NormalizeKey(key string) string {
+	key = strings.Trim(keyReplacer.Replace(key), "_")
+	return strings.ToUpper(key)
+}
+
+var keyReplacer = strings.NewReplacer("/", "_", ".", "_", "-", "_")
+
+// ReaderSource is like FileSource but reads the config document in the given format from r.
+func ReaderSource(prefix string, r io.Reader, format string) Source {
+	return loadDocument(func() (documentValues, error) {
+		return readDocument(prefix, r, format)
+	})
+}
+
+// stdin is the reader StdinSource reads the document from.
+var stdin io.Reader = os.Stdin
+
+// StdinSource returns a Source that looks up the values of the JSON or YAML config document piped on
+// the standard input, keyed like ReaderSource does. The Source is empty when the standard input is a
+// terminal or empty. The document is read on the first lookup.
+func StdinSource(prefix string) Source {
+	return loadDocument(func() (documentValues, error) {
+		if f, ok := stdin.(*os.File); ok {
+			info, err := f.Stat()
+			if err != nil || info.Mode()&os.ModeCharDevice != 0 {
+				return documentValues{}, nil
+			}
+		}
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return documentValues{}, fmt.Errorf("config: reading standard input: %w", err)
+		}
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 {
+			return documentValues{}, nil
+		}
+
+		format := FormatYAML
+		if data[0] == '{' {
+			format = FormatJSON
+		}
+		values, err := decodeValues(prefix, format, data)
+		if err != nil {
+			return documentValues{}, fmt.Errorf("config: decoding standard input: %w", err)
+		}
+		return values, nil
+	})
+}
+
+// LoadOnce returns a Source that calls load on the first lookup and looks up the keys in the values it
+// returns. The error returned by load is returned by every lookup. LoadOnce is useful to implement
+// sources that fetch all their values at once, for example from a remote store. The Source is a
+// KeySource listing the keys of the values.
+func LoadOnce(load func() (MapSource, error)) Source {
+	return loadDocument(func() (documentValues, error) {
+		values, err := load()
+		return documentValues{MapSource: values}, err
+	})
+}
+
+// loadDocument is like LoadOnce for the values of a config document, keeping its lists.
+func loadDocument(load func() (documentValues, error)) Source {
+	return &loadOnceSource{load: load}
+}
+
+type loadOnceSource struct {
+	load   func() (documentValues, error)
+	once   sync.Once
+	values documentValues
+	err    error
+}
+
+// get calls load once and returns its result.
+func (s *loadOnceSource) get() (documentValues, error) {
+	s.once.Do(func() {
+		s.values, s.err = s.load()
+	})
+	return s.values, s.err
+}
+
+func (s *loadOnceSource) lookupList(key string) ([]string, bool, error) {
+	values, err := s.get()
+	if err != nil {
+		return nil, false, err
+	}
+	return values.lookupList(key)
+}
+
+func (s *loadOnceSource) Lookup(key string) (string, bool, error) {
+	values, err := s.get()
+	if err != nil {
+		return "", false, err
+	}
+	return values.Lookup(key)
+}
+
+func (s *loadOnceSource) Keys() ([]string, error) {
+	values, err := s.get()
+	if err != nil {
+		return nil, err
+	}
+	return values.Keys()
+}
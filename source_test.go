@@ -0,0 +1,541 @@
+package config
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseSources(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_DB_PORT", "6543")
+	path := writeFile(t, "config.yaml", `
+host: example.com
+port: 8080
+db:
+  host: db.example.com
+  port: 5432
+`)
+
+	var cfg fileConfig
+	err := ParseSources("app", &cfg,
+		FileSource("app", path),
+		EnvSource(),
+		MapSource{"APP_HOST": "override.example.com"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Host != "override.example.com" {
+		t.Fatalf("expected host to be override.example.com, got %s", cfg.Host)
+	}
+	if cfg.Port != 8080 {
+		t.Fatalf("expected port to be 8080, got %d", cfg.Port)
+	}
+	if cfg.DB.Port != 6543 {
+		t.Fatalf("expected db port to be 6543, got %d", cfg.DB.Port)
+	}
+}
+
+func TestParseSourcesError(t *testing.T) {
+	errUnavailable := errors.New("unavailable")
+	source := SourceFunc(func(key string) (string, bool, error) {
+		return "", false, errUnavailable
+	})
+
+	var cfg fileConfig
+	if err := ParseSources("app", &cfg, source); !errors.Is(err, errUnavailable) {
+		t.Fatalf("expected error to wrap errUnavailable, got %v", err)
+	}
+
+	if err := ParseSources("app", &cfg, FileSource("app", "missing.yaml")); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestDotEnvSource(t *testing.T) {
+	os.Clearenv()
+	first := writeFile(t, ".env", "APP_HOST=example.com\nAPP_PORT=8080\n")
+	second := writeFile(t, ".env.local", "APP_HOST=local.example.com\nAPP_DEBUG=true\n")
+
+	var cfg fileConfig
+	err := ParseSources("app", &cfg, DotEnvSource(first, "missing.env", second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Host != "example.com" {
+		t.Fatalf("expected host to be example.com, got %s", cfg.Host)
+	}
+	if !cfg.Debug {
+		t.Fatal("expected debug to be true, got false")
+	}
+	if _, ok := os.LookupEnv("APP_HOST"); ok {
+		t.Fatal("expected APP_HOST not to be loaded into the environment")
+	}
+}
+
+func TestEnvFiles(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range []string{".env", ".env.local", ".env.production", ".env.test"} {
+		if err := os.WriteFile(file, []byte("APP_HOST="+file+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		environment string
+		files       []string
+	}{
+		{"", []string{".env.local", ".env"}},
+		{"production", []string{".env.local", ".env.production", ".env"}},
+		{"test", []string{".env.test", ".env"}},
+		{"staging", []string{".env.local", ".env"}},
+	}
+
+	for _, tc := range tests {
+		files := EnvFiles(tc.environment)
+		if strings.Join(files, ",") != strings.Join(tc.files, ",") {
+			t.Fatalf("expected the files of %q to be %v, got %v", tc.environment, tc.files, files)
+		}
+	}
+
+	os.Clearenv()
+	var cfg fileConfig
+	if err := Parse("app", &cfg, EnvFiles("production")...); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != ".env.local" {
+		t.Fatalf("expected host to be .env.local, got %s", cfg.Host)
+	}
+}
+
+func TestFindEnvFiles(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	// outside/repo/.git marks the root, outside/.env must not be found.
+	outside := t.TempDir()
+	repo := filepath.Join(outside, "repo")
+	service := filepath.Join(repo, "services", "api")
+	for _, dir := range []string{filepath.Join(repo, ".git"), service} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		filepath.Join(outside, ".env"):       "APP_HOST=outside.example.com\nAPP_PORT=1\n",
+		filepath.Join(repo, ".env"):          "APP_HOST=repo.example.com\nAPP_PORT=8080\nAPP_DEBUG=true\n",
+		filepath.Join(service, ".env"):       "APP_HOST=api.example.com\n",
+		filepath.Join(service, ".env.local"): "APP_DB_HOST=localhost\n",
+	}
+	for file, content := range files {
+		if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chdir(service); err != nil {
+		t.Fatal(err)
+	}
+
+	// The temporary directory can be behind a symlink, compare the base names relative to the repo.
+	found := FindEnvFiles(".env.local", ".env")
+	var names []string
+	for _, file := range found {
+		names = append(names, filepath.Base(filepath.Dir(file))+"/"+filepath.Base(file))
+	}
+	expected := []string{"api/.env.local", "api/.env", "repo/.env"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected the files to be %v, got %v", expected, names)
+	}
+
+	os.Clearenv()
+	var cfg fileConfig
+	if err := Parse("app", &cfg, FindEnvFiles()...); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != "api.example.com" {
+		t.Fatalf("expected host to be api.example.com, got %s", cfg.Host)
+	}
+	if cfg.Port != 8080 {
+		t.Fatalf("expected port to be 8080, got %d", cfg.Port)
+	}
+	if !cfg.Debug {
+		t.Fatal("expected debug to be true")
+	}
+	if cfg.DB.Host != "" {
+		t.Fatalf("expected db host to be empty, got %s", cfg.DB.Host)
+	}
+}
+
+func TestDirSource(t *testing.T) {
+	os.Clearenv()
+	dir := t.TempDir()
+	data := filepath.Join(dir, "..data")
+	if err := os.Mkdir(data, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range map[string]string{"HOST": "example.com\n", "db.port": "5432", ".hidden": "secret"} {
+		if err := os.WriteFile(filepath.Join(data, name), []byte(value), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(filepath.Join("..data", name), filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var cfg fileConfig
+	if err := ParseSources("app", &cfg, DirSource("app", dir)); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Host != "example.com" {
+		t.Fatalf("expected host to be example.com, got %q", cfg.Host)
+	}
+	if cfg.DB.Port != 5432 {
+		t.Fatalf("expected db port to be 5432, got %d", cfg.DB.Port)
+	}
+
+	if err := ParseSources("app", &cfg, DirSource("app", filepath.Join(dir, "missing"))); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestEnvDirSource(t *testing.T) {
+	os.Clearenv()
+	dir := t.TempDir()
+	files := map[string]string{
+		"APP_HOST":    "example.com  \nignored\n",
+		"APP_PORT":    "",
+		"APP_DB_HOST": "db\x00example.com\t",
+	}
+	for name, value := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var cfg fileConfig
+	if err := ParseSources("app", &cfg, EnvDirSource(dir)); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Host != "example.com" {
+		t.Fatalf("expected host to be example.com, got %q", cfg.Host)
+	}
+	if cfg.Port != 80 {
+		t.Fatalf("expected port to be 80, got %d", cfg.Port)
+	}
+	if cfg.DB.Host != "db\nexample.com" {
+		t.Fatalf("expected db host to be db\\nexample.com, got %q", cfg.DB.Host)
+	}
+}
+
+func TestDockerSecrets(t *testing.T) {
+	dir := t.TempDir()
+	defer func(dir string) { dockerSecretsDir = dir }(dockerSecretsDir)
+	dockerSecretsDir = dir
+
+	os.Clearenv()
+	os.Setenv("APP_PORT", "8080")
+	os.Setenv("APP_HOST_FILE", writeFile(t, "host", "example.com\n"))
+	for name, value := range map[string]string{"db_host": "db.example.com\n", "port": "9000"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0o444); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A secret of another application that cannot be read is skipped.
+	if err := os.Symlink(filepath.Join(dir, "missing"), filepath.Join(dir, "other_token")); err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg fileConfig
+	if err := ParseSources("app", &cfg, DockerSecretsSource("app"), FileRefSource(EnvSource())); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Host != "example.com" {
+		t.Fatalf("expected host to be example.com, got %q", cfg.Host)
+	}
+	if cfg.Port != 8080 {
+		t.Fatalf("expected port to be 8080, got %d", cfg.Port)
+	}
+	if cfg.DB.Host != "db.example.com" {
+		t.Fatalf("expected db host to be db.example.com, got %q", cfg.DB.Host)
+	}
+
+	// Parse does not resolve Docker secrets and *_FILE variables.
+	var env fileConfig
+	if err := Parse("app", &env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Host != "" || env.DB.Host != "" {
+		t.Fatalf("expected Parse to ignore Docker secrets and *_FILE variables, got %+v", env)
+	}
+
+	os.Setenv("APP_HOST_FILE", filepath.Join(dir, "missing"))
+	if err := ParseSources("app", &cfg, DockerSecretsSource("app"), FileRefSource(EnvSource())); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	// An unreadable secret whose name starts with the prefix is an error.
+	os.Unsetenv("APP_HOST_FILE")
+	if err := os.Symlink(filepath.Join(dir, "missing"), filepath.Join(dir, "app_token")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ParseSources("app", &cfg, DockerSecretsSource("app")); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestCredentialsSource(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "db_host"), []byte("db.example.com\n"), 0o400); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Clearenv()
+	os.Setenv("APP_HOST", "example.com")
+	os.Setenv("APP_DB_HOST", "env.example.com")
+	os.Setenv("CREDENTIALS_DIRECTORY", dir)
+
+	var cfg fileConfig
+	if err := ParseSources("app", &cfg, EnvSource(), CredentialsSource("app")); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Host != "example.com" {
+		t.Fatalf("expected host to be example.com, got %q", cfg.Host)
+	}
+	if cfg.DB.Host != "db.example.com" {
+		t.Fatalf("expected db host to be db.example.com, got %q", cfg.DB.Host)
+	}
+
+	// Parse does not read the credentials.
+	var env fileConfig
+	if err := Parse("app", &env); err != nil {
+		t.Fatal(err)
+	}
+	if env.DB.Host != "env.example.com" {
+		t.Fatalf("expected Parse to ignore the credentials, got %q", env.DB.Host)
+	}
+}
+
+func TestStdinSource(t *testing.T) {
+	defer func(r io.Reader) { stdin = r }(stdin)
+
+	tests := []struct {
+		description string
+		input       string
+		host        string
+	}{
+		{"json", `{"host": "example.com", "db": {"port": 5432}}`, "example.com"},
+		{"yaml", "host: example.com\ndb:\n  port: 5432\n", "example.com"},
+		{"empty", "", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			os.Clearenv()
+			os.Setenv("APP_DB_PORT", "6543")
+			stdin = strings.NewReader(tc.input)
+
+			var cfg fileConfig
+			if err := ParseStdin("app", &cfg); err != nil {
+				t.Fatal(err)
+			}
+
+			if cfg.Host != tc.host {
+				t.Fatalf("expected host to be %q, got %q", tc.host, cfg.Host)
+			}
+			if cfg.DB.Port != 6543 {
+				t.Fatalf("expected db port to be 6543, got %d", cfg.DB.Port)
+			}
+		})
+	}
+
+	stdin = strings.NewReader("{invalid")
+	if err := ParseSources("app", &fileConfig{}, StdinSource("app")); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestParseLayers(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_DB_PORT", "6543")
+	os.Setenv("APP_HOST", "env.example.com")
+	path := writeFile(t, "config.yaml", `
+host: example.com
+db:
+  host: db.example.com
+  port: 5432
+`)
+
+	var cfg fileConfig
+	origins, err := ParseLayers("app", &cfg,
+		Layer{Name: "file", Source: FileSource("app", path)},
+		Layer{Name: "env", Source: EnvSource()},
+		Layer{Name: "overrides", Source: MapSource{"APP_HOST": "override.example.com"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Origins{
+		"APP_HOST":    "overrides",
+		"APP_PORT":    OriginDefault,
+		"APP_DB_HOST": "file",
+		"APP_DB_PORT": "env",
+	}
+	if len(origins) != len(expected) {
+		t.Fatalf("expected %d origins, got %v", len(expected), origins)
+	}
+	for key, origin := range expected {
+		if origins[key] != origin {
+			t.Fatalf("expected origin of %s to be %s, got %s", key, origin, origins[key])
+		}
+	}
+	if cfg.Host != "override.example.com" {
+		t.Fatalf("expected host to be override.example.com, got %s", cfg.Host)
+	}
+}
+
+func TestNormalizeKey(t *testing.T) {
+	tests := map[string]string{
+		"app/db/host":   "APP_DB_HOST",
+		"/app/db/host/": "APP_DB_HOST",
+		"db.max-conns":  "DB_MAX_CONNS",
+		"HOST":          "HOST",
+	}
+	for key, expected := range tests {
+		if got := NormalizeKey(key); got != expected {
+			t.Fatalf("expected %s to normalize to %s, got %s", key, expected, got)
+		}
+	}
+}
+
+func TestLoadOnce(t *testing.T) {
+	calls := 0
+	source := LoadOnce(func() (MapSource, error) {
+		calls++
+		return MapSource{"APP_HOST": "example.com"}, nil
+	})
+
+	for range 2 {
+		value, ok, err := source.Lookup("APP_HOST")
+		if err != nil || !ok || value != "example.com" {
+			t.Fatalf("expected example.com, got %q %v %v", value, ok, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected load to be called once, got %d", calls)
+	}
+}
+
+// versionSource is a SnapshotSource whose version changes every time a snapshot is taken.
+type versionSource struct {
+	version int
+}
+
+func (s *versionSource) Lookup(key string) (string, bool, error) {
+	return "", false, errors.New("expected the lookups to go to the snapshot")
+}
+
+func (s *versionSource) Snapshot() (Source, error) {
+	s.version++
+	v := strconv.Itoa(s.version)
+	return MapSource{"APP_HOST": "host" + v, "APP_PORT": v}, nil
+}
+
+func TestSnapshotSource(t *testing.T) {
+	source := &versionSource{}
+	var cfg struct {
+		Host string
+		Port int
+	}
+	if err := ParseSources("app", &cfg, source); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != "host1" || cfg.Port != 1 {
+		t.Fatalf("expected the values of the first snapshot, got %+v", cfg)
+	}
+
+	origins, err := ParseLayers("app", &cfg, Layer{Name: "remote", Source: source})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != "host2" || cfg.Port != 2 || origins["APP_HOST"] != "remote" {
+		t.Fatalf("expected the values of the second snapshot from the remote layer, got %+v and %v", cfg, origins)
+	}
+}
+
+// secretSource is a FieldSource that returns the values of secret fields reversed.
+type secretSource struct {
+	MapSource
+}
+
+func (s secretSource) LookupField(field Field, key string) (string, bool, error) {
+	value, ok, err := s.Lookup(key)
+	if !field.Secret {
+		return value, ok, err
+	}
+	runes := []rune(value)
+	slices.Reverse(runes)
+	return string(runes), ok, err
+}
+
+func TestFieldSource(t *testing.T) {
+	source := secretSource{MapSource{
+		"APP_HOST":               "tsoh",
+		"APP_PASSWORD":           "terces",
+		"APP_REPLICA_PASSWORD":   "acilper",
+		"APP_TENANTS_ACME_TOKEN": "emca",
+		"APP_STORAGE":            "s3",
+		"APP_STORAGE_BUCKET":     "tekcub",
+	}}
+
+	var cfg struct {
+		Host     string
+		Password string `secret:"true"`
+		Replica  *struct {
+			Password Secret
+		}
+		Tenants map[string]struct {
+			Token string `secret:"true"`
+		}
+		Storage storage
+	}
+	if err := ParseSources("app", &cfg, source); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Host != "tsoh" || cfg.Password != "secret" {
+		t.Fatalf("expected only the password to go through LookupField, got %s and %s", cfg.Host, cfg.Password)
+	}
+	if cfg.Replica == nil || cfg.Replica.Password.Value() != "replica" {
+		t.Fatalf("expected the replica password to go through LookupField, got %+v", cfg.Replica)
+	}
+	if cfg.Tenants["acme"].Token != "acme" {
+		t.Fatalf("expected the tenant token to go through LookupField, got %+v", cfg.Tenants)
+	}
+	if s3, ok := cfg.Storage.(*s3Storage); !ok || s3.Bucket != "tekcub" {
+		t.Fatalf("expected the storage to be s3 with the bucket tekcub, got %#v", cfg.Storage)
+	}
+}
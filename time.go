@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeLayouts are the named layouts of the layout tag, any other value of the tag is a layout of the
+// time package, like 02/01/2006.
+var timeLayouts = map[string]string{
+	"rfc3339":     time.RFC3339,
+	"rfc3339nano": time.RFC3339Nano,
+	"rfc1123":     time.RFC1123,
+	"rfc1123z":    time.RFC1123Z,
+	"rfc822":      time.RFC822,
+	"rfc822z":     time.RFC822Z,
+	"date":        time.DateOnly,
+	"datetime":    time.DateTime,
+	"kitchen":     time.Kitchen,
+}
+
+// unixLayouts are the layouts of the layout tag parsing a Unix timestamp, mapped to the functions
+// converting a time from and to the timestamp.
+var unixLayouts = map[string]struct {
+	time func(int64) time.Time
+	unix func(time.Time) int64
+}{
+	"unix":      {func(n int64) time.Time { return time.Unix(n, 0) }, time.Time.Unix},
+	"unixmilli": {time.UnixMilli, time.Time.UnixMilli},
+	"unixmicro": {time.UnixMicro, time.Time.UnixMicro},
+	"unixnano":  {func(n int64) time.Time { return time.Unix(0, n) }, time.Time.UnixNano},
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// parseTime parses value with the layout of the layout tag into field, a time.Time, a type defined as
+// time.Time or a pointer to one of them.
+func parseTime(value string, field reflect.Value, layout string) error {
+	if field.Kind() == reflect.Ptr {
+		// Parse into a new value so that the pointer is only set when the value is valid.
+		ptr := reflect.New(field.Type().Elem())
+		if err := parseTime(value, ptr.Elem(), layout); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+	if !field.Type().ConvertibleTo(timeType) {
+		return fmt.Errorf("the layout tag requires a time.Time field, got %s", field.Type())
+	}
+
+	var (
+		t   time.Time
+		err error
+	)
+	if unix, ok := unixLayouts[strings.ToLower(layout)]; ok {
+		var n int64
+		if n, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("invalid Unix timestamp %q", value)
+		}
+		t = unix.time(n).UTC()
+	} else {
+		if named, ok := timeLayouts[strings.ToLower(layout)]; ok {
+			layout = named
+		}
+		if t, err = time.Parse(layout, value); err != nil {
+			return err
+		}
+	}
+	field.Set(reflect.ValueOf(t).Convert(field.Type()))
+	return nil
+}
+
+// formatTime formats field, a time.Time or a type defined as time.Time, the way parseTime parses it
+// with layout.
+func formatTime(field reflect.Value, layout string) (string, error) {
+	if field.Kind() == reflect.Ptr {
+		field = field.Elem()
+	}
+	if !field.Type().ConvertibleTo(timeType) {
+		return "", fmt.Errorf("the layout tag requires a time.Time field, got %s", field.Type())
+	}
+
+	t := field.Convert(timeType).Interface().(time.Time)
+	if unix, ok := unixLayouts[strings.ToLower(layout)]; ok {
+		return strconv.FormatInt(unix.unix(t), 10), nil
+	}
+	if named, ok := timeLayouts[strings.ToLower(layout)]; ok {
+		layout = named
+	}
+	return t.Format(layout), nil
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type timestamp time.Time
+
+func TestLayoutTag(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_STARTED", "2024-03-01T12:30:00+02:00")
+	os.Setenv("APP_RELEASE", "2024-03-01")
+	os.Setenv("APP_EXPIRES", "1709296200")
+	os.Setenv("APP_UPDATED", "1709296200123")
+	os.Setenv("APP_BIRTHDAY", "01/03/2024")
+	os.Setenv("APP_DEADLINE", "2024-03-01 12:30:00")
+
+	spec := struct {
+		Started  time.Time  `layout:"rfc3339"`
+		Release  time.Time  `layout:"date"`
+		Expires  time.Time  `layout:"unix"`
+		Updated  *time.Time `layout:"UnixMilli"`
+		Birthday time.Time  `layout:"02/01/2006"`
+		Deadline timestamp  `layout:"datetime"`
+		Reset    *time.Time `layout:"unix"`
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	instant := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	if !spec.Started.Equal(instant.Add(-2 * time.Hour)) {
+		t.Fatalf("expected started to be 2024-03-01T10:30:00Z, got %s", spec.Started)
+	}
+	if !spec.Release.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected release to be 2024-03-01, got %s", spec.Release)
+	}
+	if !spec.Expires.Equal(instant) || spec.Expires.Location() != time.UTC {
+		t.Fatalf("expected expires to be %s, got %s", instant, spec.Expires)
+	}
+	if spec.Updated == nil || !spec.Updated.Equal(instant.Add(123*time.Millisecond)) {
+		t.Fatalf("expected updated to be %s, got %v", instant.Add(123*time.Millisecond), spec.Updated)
+	}
+	if !spec.Birthday.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected birthday to be 2024-03-01, got %s", spec.Birthday)
+	}
+	if !time.Time(spec.Deadline).Equal(instant) {
+		t.Fatalf("expected deadline to be %s, got %s", instant, time.Time(spec.Deadline))
+	}
+	if spec.Reset != nil {
+		t.Fatalf("expected reset to be nil, got %s", spec.Reset)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"APP_STARTED":  "2024-03-01T12:30:00+02:00",
+		"APP_RELEASE":  "2024-03-01",
+		"APP_EXPIRES":  "1709296200",
+		"APP_UPDATED":  "1709296200123",
+		"APP_BIRTHDAY": "01/03/2024",
+		"APP_DEADLINE": "2024-03-01 12:30:00",
+		"APP_RESET":    "",
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Fatalf("expected the values to be %v, got %v", expected, values)
+	}
+
+	tests := []struct {
+		description string
+		value       string
+		layout      string
+	}{
+		{description: "value not matching the layout", value: "2024-03-01", layout: "rfc3339"},
+		{description: "invalid unix timestamp", value: "1.5", layout: "unix"},
+		{description: "value not matching a custom layout", value: "2024-03-01", layout: "02/01/2006"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			os.Clearenv()
+			os.Setenv("APP_AT", tt.value)
+
+			spec := reflect.New(reflect.StructOf([]reflect.StructField{{
+				Name: "At",
+				Type: reflect.TypeOf(time.Time{}),
+				Tag:  reflect.StructTag(`layout:"` + tt.layout + `"`),
+			}}))
+			err := Parse("app", spec.Interface())
+			var fieldErr *FieldError
+			if !errors.As(err, &fieldErr) {
+				t.Fatalf("expected a FieldError, got %v", err)
+			}
+		})
+	}
+
+	os.Clearenv()
+	os.Setenv("APP_UPDATED", "soon")
+	spec.Updated = nil
+	if err := Parse("app", &spec); err == nil {
+		t.Fatal("expected an error for an invalid timestamp, got nil")
+	}
+	if spec.Updated != nil {
+		t.Fatalf("expected updated to stay nil, got %s", spec.Updated)
+	}
+
+	os.Clearenv()
+	os.Setenv("APP_COUNT", "5")
+	invalid := struct {
+		Count int `layout:"unix"`
+	}{}
+	if err := Parse("app", &invalid); err == nil {
+		t.Fatal("expected an error for a layout tag on an int field, got nil")
+	}
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// TLSVersion is a TLS protocol version parsed from 1.0, 1.1, 1.2 or 1.3.
+type TLSVersion uint16
+
+func init() {
+	RegisterEnum(map[string]TLSVersion{
+		"1.0": tls.VersionTLS10, "1.1": tls.VersionTLS11, "1.2": tls.VersionTLS12, "1.3": tls.VersionTLS13,
+	})
+	RegisterEnum(map[string]tls.ClientAuthType{
+		"none":               tls.NoClientCert,
+		"request":            tls.RequestClientCert,
+		"require":            tls.RequireAnyClientCert,
+		"verify-if-given":    tls.VerifyClientCertIfGiven,
+		"require-and-verify": tls.RequireAndVerifyClientCert,
+	})
+}
+
+// TLS holds the settings of a TLS server or client, nest it in a config and call Build to get a
+// tls.Config. With the prefix APP and a field named TLS, the settings are read from APP_TLS_CERT,
+// APP_TLS_KEY, APP_TLS_CA, APP_TLS_MINVERSION and APP_TLS_CLIENTAUTH.
+//
+//	type Config struct {
+//		TLS config.TLS
+//	}
+//	...
+//	tlsConfig, err := cfg.TLS.Build()
+//	srv := &http.Server{Addr: ":8443", TLSConfig: tlsConfig}
+type TLS struct {
+	Cert Path `exists:"file"` // The PEM encoded certificate chain.
+	Key  Path `exists:"file"` // The PEM encoded private key of the certificate.
+	CA   Path `exists:"file"` // The PEM encoded certificates of the CAs verifying the peers.
+	// MinVersion is the minimum TLS version, 1.0, 1.1, 1.2 or 1.3.
+	MinVersion TLSVersion `default:"1.2"`
+	// ClientAuth is the verification of the client certificates by a server, none, request, require,
+	// verify-if-given or require-and-verify.
+	ClientAuth tls.ClientAuthType `default:"none"`
+}
+
+// Build returns a tls.Config with the certificate and key loaded if they are set. The certificates of
+// the CA file, if set, verify the client certificates for a server and the server certificates for a
+// client.
+func (t TLS) Build() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: uint16(t.MinVersion),
+		ClientAuth: t.ClientAuth,
+	}
+
+	if t.Cert != "" || t.Key != "" {
+		if t.Cert == "" || t.Key == "" {
+			return nil, errors.New("config: TLS certificate and key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(string(t.Cert), string(t.Key))
+		if err != nil {
+			return nil, fmt.Errorf("config: loading TLS certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if t.CA != "" {
+		data, err := os.ReadFile(string(t.CA))
+		if err != nil {
+			return nil, fmt.Errorf("config: reading TLS CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("config: no PEM certificate found in TLS CA %s", t.CA)
+		}
+		cfg.ClientCAs, cfg.RootCAs = pool, pool
+	}
+	return cfg, nil
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeCert writes a self-signed certificate and its key to dir and returns their paths.
+func writeCert(t *testing.T, dir string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestTLS(t *testing.T) {
+	certFile, keyFile := writeCert(t, t.TempDir())
+
+	os.Clearenv()
+	os.Setenv("APP_TLS_CERT", certFile)
+	os.Setenv("APP_TLS_KEY", keyFile)
+	os.Setenv("APP_TLS_CA", certFile)
+	os.Setenv("APP_TLS_CLIENTAUTH", "require-and-verify")
+
+	spec := struct {
+		TLS TLS
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := spec.TLS.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MinVersion != tls.VersionTLS12 || cfg.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Fatalf("expected TLS 1.2 and require-and-verify, got %x and %s", cfg.MinVersion, cfg.ClientAuth)
+	}
+	if len(cfg.Certificates) != 1 || cfg.ClientCAs == nil || cfg.RootCAs == nil {
+		t.Fatalf("expected the certificate and the CA to be loaded, got %+v", cfg)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_TLS_MINVERSION"] != "1.2" || values["APP_TLS_CLIENTAUTH"] != "require-and-verify" {
+		t.Fatalf("expected the TLS settings to be marshaled, got %v", values)
+	}
+
+	if _, err := (TLS{Cert: Path(certFile)}).Build(); err == nil || !strings.Contains(err.Error(), "must be set together") {
+		t.Fatalf("expected an error for the missing key, got %v", err)
+	}
+	if _, err := (TLS{CA: Path(keyFile)}).Build(); err == nil || !strings.Contains(err.Error(), "no PEM certificate") {
+		t.Fatalf("expected an error for the invalid CA, got %v", err)
+	}
+
+	os.Setenv("APP_TLS_MINVERSION", "1.4")
+	err = Parse("app", &spec)
+	if err == nil || !strings.Contains(err.Error(), `unknown value "1.4", expected one of 1.0, 1.1, 1.2, 1.3`) {
+		t.Fatalf("expected an error for the unknown version, got %v", err)
+	}
+}
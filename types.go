@@ -0,0 +1,531 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"math"
+	"math/big"
+	"net"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/josemukorivo/config/types"
+)
+
+// fieldType parses and formats the values of a type that is not handled by its kind, usually a type of
+// the standard library.
+type fieldType struct {
+	// parse parses value into a value of the type, tags are the struct tags of the field.
+	parse func(value string, tags reflect.StructTag) (any, error)
+	// format formats a value of the type the way parse parses it.
+	format func(v any) string
+}
+
+// fieldTypes maps the types that are not handled by their kind to their fieldType.
+var fieldTypes = map[reflect.Type]fieldType{
+	reflect.TypeOf(url.URL{}):        {parse: parseURL, format: formatURL},
+	reflect.TypeOf(net.IP{}):         {parse: parseIP, format: formatIP},
+	reflect.TypeOf(net.IPNet{}):      {parse: parseIPNet, format: formatIPNet},
+	reflect.TypeOf(netip.Addr{}):     {parse: parseAddr, format: formatAddr},
+	reflect.TypeOf(netip.AddrPort{}): {parse: parseAddrPort, format: formatAddrPort},
+	reflect.TypeOf(netip.Prefix{}):   {parse: parsePrefix, format: formatPrefix},
+	reflect.TypeOf(regexp.Regexp{}):  {parse: parseRegexp, format: formatRegexp},
+	reflect.TypeOf(mail.Address{}):   {parse: parseMailAddress, format: formatMailAddress},
+
+	reflect.TypeOf(big.Float{}):           {parse: parseBigFloat, format: formatBigFloat},
+	reflect.TypeOf((*time.Location)(nil)): {parse: parseLocation, format: formatLocation},
+	reflect.TypeOf(slog.Level(0)):         {parse: parseLevel, format: formatLevel},
+	reflect.TypeOf(fs.FileMode(0)):        {parse: parseFileMode, format: formatFileMode},
+	reflect.TypeOf(Path("")):              {parse: parsePath, format: formatPath},
+	reflect.TypeOf(Secret{}):              {parse: parseSecret, format: formatSecret},
+	reflect.TypeOf(DatabaseURL{}):         {parse: parseDatabaseURL, format: formatDatabaseURL},
+	reflect.TypeOf(RedisURL{}):            {parse: parseRedisURL, format: formatRedisURL},
+}
+
+// parseURL parses a url.URL. The scheme tag restricts the schemes of the URL to a comma separated
+// list, for example scheme:"http,https", the URL must then be absolute.
+func parseURL(value string, tags reflect.StructTag) (any, error) {
+	u, err := url.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	if schemes := tags.Get("scheme"); schemes != "" {
+		allowed := strings.Split(schemes, ",")
+		if !slices.Contains(allowed, strings.ToLower(u.Scheme)) {
+			return nil, fmt.Errorf("the scheme of %q is not one of %s", value, schemes)
+		}
+	}
+	return *u, nil
+}
+
+func formatURL(v any) string {
+	u := v.(url.URL)
+	return u.String()
+}
+
+// parseIP parses an IPv4 or IPv6 address into a net.IP.
+func parseIP(value string, _ reflect.StructTag) (any, error) {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address %q", value)
+	}
+	return ip, nil
+}
+
+func formatIP(v any) string {
+	if ip := v.(net.IP); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
+
+// parseIPNet parses a CIDR block, 10.0.0.0/8 or 2001:db8::/32, into a net.IPNet.
+func parseIPNet(value string, _ reflect.StructTag) (any, error) {
+	_, ipNet, err := net.ParseCIDR(value)
+	if err != nil {
+		return nil, err
+	}
+	return *ipNet, nil
+}
+
+func formatIPNet(v any) string {
+	if ipNet := v.(net.IPNet); ipNet.IP != nil {
+		return ipNet.String()
+	}
+	return ""
+}
+
+func parseAddr(value string, _ reflect.StructTag) (any, error) {
+	return netip.ParseAddr(value)
+}
+
+func formatAddr(v any) string {
+	if addr := v.(netip.Addr); addr.IsValid() {
+		return addr.String()
+	}
+	return ""
+}
+
+func parseAddrPort(value string, _ reflect.StructTag) (any, error) {
+	return netip.ParseAddrPort(value)
+}
+
+func formatAddrPort(v any) string {
+	if addrPort := v.(netip.AddrPort); addrPort.IsValid() {
+		return addrPort.String()
+	}
+	return ""
+}
+
+func parsePrefix(value string, _ reflect.StructTag) (any, error) {
+	return netip.ParsePrefix(value)
+}
+
+func formatPrefix(v any) string {
+	if prefix := v.(netip.Prefix); prefix.IsValid() {
+		return prefix.String()
+	}
+	return ""
+}
+
+// parseRegexp compiles a regular expression, usually into a *regexp.Regexp field.
+func parseRegexp(value string, _ reflect.StructTag) (any, error) {
+	re, err := regexp.Compile(value)
+	if err != nil {
+		return nil, err
+	}
+	return *re, nil
+}
+
+func formatRegexp(v any) string {
+	re := v.(regexp.Regexp)
+	return re.String()
+}
+
+// parseMailAddress parses an email address, with an optional display name like
+// "Alerts <alerts@example.com>".
+func parseMailAddress(value string, _ reflect.StructTag) (any, error) {
+	addr, err := mail.ParseAddress(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email address %q: %w", value, err)
+	}
+	return *addr, nil
+}
+
+func formatMailAddress(v any) string {
+	addr := v.(mail.Address)
+	if addr.Name == "" {
+		return addr.Address
+	}
+	return addr.String()
+}
+
+// parseLocation loads a *time.Location from its IANA name, like Europe/Paris, UTC or Local. Systems
+// without a time zone database need to import time/tzdata.
+func parseLocation(value string, _ reflect.StructTag) (any, error) {
+	if value == "" {
+		return nil, errors.New("empty time zone name")
+	}
+	loc, err := time.LoadLocation(value)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", value)
+	}
+	return loc, nil
+}
+
+func formatLocation(v any) string {
+	return v.(*time.Location).String()
+}
+
+// parseLevel parses a slog.Level from its name, debug, info, warn or error in any case with an optional
+// offset like info+2, from the legacy name warning, or from its numeric value like -4.
+func parseLevel(value string, _ reflect.StructTag) (any, error) {
+	if n, err := strconv.Atoi(value); err == nil {
+		return slog.Level(n), nil
+	}
+	text := value
+	if name, offset, ok := strings.Cut(value, "+"); strings.EqualFold(name, "warning") {
+		text = "warn"
+		if ok {
+			text += "+" + offset
+		}
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(text)); err != nil {
+		return nil, fmt.Errorf("unknown log level %q, expected debug, info, warn, error or a number", value)
+	}
+	return level, nil
+}
+
+func formatLevel(v any) string {
+	return v.(slog.Level).String()
+}
+
+// fileModeBits maps the setuid, setgid and sticky bits of octal permissions to their fs.FileMode bits.
+var fileModeBits = []struct {
+	octal uint64
+	mode  fs.FileMode
+}{
+	{0o4000, fs.ModeSetuid}, {0o2000, fs.ModeSetgid}, {0o1000, fs.ModeSticky},
+}
+
+// parseFileMode parses a fs.FileMode, also known as os.FileMode, from octal permissions like 0640, 640
+// or 0o640, up to 07777 with the setuid, setgid and sticky bits.
+func parseFileMode(value string, _ reflect.StructTag) (any, error) {
+	octal, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimPrefix(value, "0o"), "0O"), 8, 32)
+	if err != nil || octal > 0o7777 {
+		return nil, fmt.Errorf("invalid file mode %q, expected octal permissions like 0640", value)
+	}
+	mode := fs.FileMode(octal) & fs.ModePerm
+	for _, b := range fileModeBits {
+		if octal&b.octal != 0 {
+			mode |= b.mode
+		}
+	}
+	return mode, nil
+}
+
+func formatFileMode(v any) string {
+	mode := v.(fs.FileMode)
+	octal := uint64(mode.Perm())
+	for _, b := range fileModeBits {
+		if mode&b.mode != 0 {
+			octal |= b.octal
+		}
+	}
+	return "0" + strconv.FormatUint(octal, 8)
+}
+
+// parseBigFloat parses a big.Float with the precision in bits of the prec tag, 64 by default like
+// big.Float.UnmarshalText. big.Int and big.Rat fields are parsed with their UnmarshalText method.
+func parseBigFloat(value string, tags reflect.StructTag) (any, error) {
+	prec := uint64(64)
+	if p := tags.Get("prec"); p != "" {
+		var err error
+		if prec, err = strconv.ParseUint(p, 10, 32); err != nil {
+			return nil, fmt.Errorf("invalid prec tag %q", p)
+		}
+	}
+	f, _, err := big.ParseFloat(value, 0, uint(prec), big.ToNearestEven)
+	if err != nil {
+		return nil, err
+	}
+	return *f, nil
+}
+
+func formatBigFloat(v any) string {
+	f := v.(big.Float)
+	return f.Text('g', -1)
+}
+
+// ByteSize is a size in bytes parsed from a human-readable value, see ParseByteSize. Int and uint fields
+// tagged with unit:"bytes" accept the same values.
+//
+//	type Config struct {
+//		MaxBody config.ByteSize `default:"10MiB"`
+//		Cache   int64           `unit:"bytes" default:"512MB"`
+//	}
+type ByteSize uint64
+
+// Set parses value into s, it implements Setter.
+func (s *ByteSize) Set(value string) error {
+	size, err := ParseByteSize(value)
+	if err != nil {
+		return err
+	}
+	*s = ByteSize(size)
+	return nil
+}
+
+// String formats s with the unit giving the smallest whole number, for example 2GiB or 512MB.
+func (s ByteSize) String() string {
+	return formatByteSize(uint64(s))
+}
+
+// byteUnits are the units of ParseByteSize, longest first so GiB is matched before B.
+var byteUnits = []struct {
+	name string
+	size uint64
+}{
+	{"PiB", 1 << 50}, {"TiB", 1 << 40}, {"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10},
+	{"PB", 1e15}, {"TB", 1e12}, {"GB", 1e9}, {"MB", 1e6}, {"KB", 1e3},
+	{"Pi", 1 << 50}, {"Ti", 1 << 40}, {"Gi", 1 << 30}, {"Mi", 1 << 20}, {"Ki", 1 << 10},
+	{"P", 1e15}, {"T", 1e12}, {"G", 1e9}, {"M", 1e6}, {"K", 1e3},
+	{"B", 1},
+}
+
+// ParseByteSize parses a human-readable size in bytes, a number followed by an optional unit, for
+// example 512MB, 2GiB or 1.5 GB. Decimal units, KB, MB, GB, TB and PB, are powers of 1000 and binary
+// units, KiB, MiB, GiB, TiB and PiB, powers of 1024. Units are case-insensitive, the trailing B can be
+// omitted and a value without a unit is in bytes.
+func ParseByteSize(value string) (uint64, error) {
+	s := strings.TrimSpace(value)
+	unit := uint64(1)
+	for _, u := range byteUnits {
+		if len(s) >= len(u.name) && strings.EqualFold(s[len(s)-len(u.name):], u.name) {
+			s, unit = strings.TrimSpace(s[:len(s)-len(u.name)]), u.size
+			break
+		}
+	}
+
+	if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+		if n > math.MaxUint64/unit {
+			return 0, fmt.Errorf("byte size %q overflows uint64", value)
+		}
+		return n * unit, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid byte size %q", value)
+	}
+	size := f * float64(unit)
+	if size >= math.MaxUint64 {
+		return 0, fmt.Errorf("byte size %q overflows uint64", value)
+	}
+	return uint64(size), nil
+}
+
+// formatByteSize formats size with the unit giving the smallest whole number.
+func formatByteSize(size uint64) string {
+	best, name := size, "B"
+	for _, u := range byteUnits[:10] {
+		if size%u.size == 0 && size/u.size < best {
+			best, name = size/u.size, u.name
+		}
+	}
+	return strconv.FormatUint(best, 10) + name
+}
+
+// siUnits are the suffixes of integer fields tagged with unit:"si", longest first so Ki is matched
+// before K. The first units are used to format the numbers.
+var siUnits = []struct {
+	name string
+	size uint64
+}{
+	{"Ei", 1 << 60}, {"Pi", 1 << 50}, {"Ti", 1 << 40}, {"Gi", 1 << 30}, {"Mi", 1 << 20}, {"Ki", 1 << 10},
+	{"E", 1e18}, {"P", 1e15}, {"T", 1e12}, {"G", 1e9}, {"M", 1e6}, {"k", 1e3},
+	{"K", 1e3},
+}
+
+// parseSI parses a count with an optional SI suffix, k, M, G, T, P or E for powers of 1000, or IEC
+// suffix, Ki, Mi, Gi, Ti, Pi or Ei for powers of 1024, for example 1k, 2M or 4Ki. Unlike byte sizes
+// the suffixes are case-sensitive, except k that can be written K. It returns the absolute value of the
+// number and whether it is negative.
+func parseSI(value string) (uint64, bool, error) {
+	s := strings.TrimSpace(value)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	unit := uint64(1)
+	for _, u := range siUnits {
+		if n, ok := strings.CutSuffix(s, u.name); ok {
+			s, unit = strings.TrimSpace(n), u.size
+			break
+		}
+	}
+
+	if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+		if n > math.MaxUint64/unit {
+			return 0, false, fmt.Errorf("number %q overflows uint64", value)
+		}
+		return n * unit, negative, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	n := math.Round(f * float64(unit))
+	if err != nil || f < 0 || math.Abs(f*float64(unit)-n) > 1e-6 {
+		return 0, false, fmt.Errorf("invalid number %q, expected a whole number with an optional suffix like 2k or 4Ki", value)
+	}
+	if n >= math.MaxUint64 {
+		return 0, false, fmt.Errorf("number %q overflows uint64", value)
+	}
+	return uint64(n), negative, nil
+}
+
+// formatSI formats n with the suffix giving the smallest whole number.
+func formatSI(n uint64) string {
+	best, name := n, ""
+	for _, u := range siUnits[:12] {
+		if n%u.size == 0 && n/u.size < best {
+			best, name = n/u.size, u.name
+		}
+	}
+	return strconv.FormatUint(best, 10) + name
+}
+
+// Percent is a fraction parsed from a percentage like 15% or from the fraction itself like 0.15, see
+// types.Percent.
+type Percent = types.Percent
+
+// Ratio is a fraction between 0 and 1 parsed from a decimal, a percentage or a quotient like 1/100, see
+// types.Ratio.
+type Ratio = types.Ratio
+
+// HostPort is a network address with a port, like db.example.com:5432, [::1]:8080 or :8080 to listen
+// on all interfaces. A missing or invalid port is an error.
+//
+//	type Config struct {
+//		Listen config.HostPort `default:":8080"`
+//	}
+type HostPort struct {
+	Host string
+	Port int
+}
+
+// Set parses value into a, it implements Setter.
+func (a *HostPort) Set(value string) error {
+	host, port, err := net.SplitHostPort(value)
+	if err != nil {
+		return err
+	}
+	n, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid port %q in address %q", port, value)
+	}
+	*a = HostPort{Host: host, Port: int(n)}
+	return nil
+}
+
+// String formats a as host:port, the host is bracketed if it is an IPv6 address.
+func (a HostPort) String() string {
+	return net.JoinHostPort(a.Host, strconv.Itoa(a.Port))
+}
+
+// Port is a TCP or UDP port number between 1 and 65535.
+type Port uint16
+
+// Set parses value into p, it implements Setter.
+func (p *Port) Set(value string) error {
+	n, err := strconv.ParseUint(strings.TrimSpace(value), 10, 16)
+	if err != nil || n == 0 {
+		return fmt.Errorf("invalid port %q, expected a number between 1 and 65535", value)
+	}
+	*p = Port(n)
+	return nil
+}
+
+// String formats p as a number.
+func (p Port) String() string {
+	return strconv.Itoa(int(p))
+}
+
+// Listen is the address a service listens on. The host and the port are read from the keys of the
+// field, like APP_LISTEN_HOST and APP_LISTEN_PORT, so a config can hold several addresses. The port
+// defaults to 8080 and the host is empty by default to listen on all interfaces. To follow the
+// twelve-factor convention of platforms like Heroku or Cloud Run that set the PORT variable, read it
+// into a Port field with an env tag instead.
+//
+//	type Config struct {
+//		Listen config.Listen
+//		Admin  config.Listen
+//	}
+//	...
+//	http.ListenAndServe(cfg.Listen.Addr(), handler)
+type Listen struct {
+	Host string
+	Port Port `default:"8080"`
+}
+
+// Addr returns the address to listen on, like :8080 or 127.0.0.1:8080.
+func (l Listen) Addr() string {
+	return net.JoinHostPort(l.Host, l.Port.String())
+}
+
+// Path is a file system path expanded when it is parsed: a leading ~ is replaced by the home directory,
+// $VAR and ${VAR} by the environment variables and a relative path is made absolute against the
+// directory of the base tag, itself expanded, or the working directory. The path is cleaned.
+//
+//	type Config struct {
+//		DataDir config.Path `default:"~/.local/share/app"`
+//		Plugins config.Path `base:"/opt/app" default:"plugins"`
+//	}
+type Path string
+
+func parsePath(value string, tags reflect.StructTag) (any, error) {
+	if value == "" {
+		return Path(""), nil
+	}
+	path, err := expandPath(value)
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(path) {
+		base := tags.Get("base")
+		if base != "" {
+			if base, err = expandPath(base); err != nil {
+				return nil, err
+			}
+		}
+		// filepath.Abs joins a relative base with the working directory.
+		if path, err = filepath.Abs(filepath.Join(base, path)); err != nil {
+			return nil, err
+		}
+	}
+	return Path(filepath.Clean(path)), nil
+}
+
+func formatPath(v any) string {
+	return string(v.(Path))
+}
+
+// expandPath replaces a leading ~ in path with the home directory and the $VAR and ${VAR} environment
+// variables with their values.
+func expandPath(path string) (string, error) {
+	if rest, ok := strings.CutPrefix(path, "~"); ok && (rest == "" || os.IsPathSeparator(rest[0])) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("expanding ~: %w", err)
+		}
+		path = home + rest
+	}
+	return os.ExpandEnv(path), nil
+}
@@ -0,0 +1,94 @@
+// Package types holds small value types for config fields, like percentages and sampling rates, so
+// they are parsed the same way in every service. The config package aliases them, config.Percent is
+// types.Percent.
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Percent is a fraction parsed from a percentage like 15% or from the fraction itself like 0.15, both
+// give 0.15. Values over 100% are allowed, for example for quotas.
+//
+//	type Config struct {
+//		QuotaWarning types.Percent `default:"80%"`
+//	}
+type Percent float64
+
+// Set parses value into p, it implements config.Setter.
+func (p *Percent) Set(value string) error {
+	f, err := parsePercent(value)
+	if err != nil {
+		return fmt.Errorf("invalid percentage %q", value)
+	}
+	*p = Percent(f)
+	return nil
+}
+
+// String formats p as a percentage, for example 15%.
+func (p Percent) String() string {
+	return strconv.FormatFloat(float64(p)*100, 'g', 15, 64) + "%"
+}
+
+// Ratio is a fraction between 0 and 1 parsed from a decimal like 0.25, a percentage like 25% or a
+// quotient like 1/4, for example a sampling rate.
+//
+//	type Config struct {
+//		TraceSampling types.Ratio `default:"1/100"`
+//	}
+type Ratio float64
+
+// Set parses value into r, it implements config.Setter.
+func (r *Ratio) Set(value string) error {
+	var (
+		f   float64
+		err error
+	)
+	if num, den, ok := strings.Cut(value, "/"); ok {
+		var n, d float64
+		n, err = strconv.ParseFloat(strings.TrimSpace(num), 64)
+		if err == nil {
+			d, err = strconv.ParseFloat(strings.TrimSpace(den), 64)
+		}
+		if err == nil && d == 0 {
+			err = errors.New("division by zero")
+		}
+		f = n / d
+		if err == nil && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			err = errors.New("not a finite number")
+		}
+	} else {
+		f, err = parsePercent(value)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid ratio %q", value)
+	}
+	if f < 0 || f > 1 {
+		return fmt.Errorf("ratio %q is not between 0 and 1", value)
+	}
+	*r = Ratio(f)
+	return nil
+}
+
+// String formats r as a decimal, for example 0.25.
+func (r Ratio) String() string {
+	return strconv.FormatFloat(float64(r), 'g', -1, 64)
+}
+
+// parsePercent parses a percentage like 15% or a fraction like 0.15 into a fraction.
+func parsePercent(value string) (float64, error) {
+	s := strings.TrimSpace(value)
+	scale := 1.0
+	if p, ok := strings.CutSuffix(s, "%"); ok {
+		s, scale = strings.TrimSpace(p), 100
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return f / scale, nil
+}
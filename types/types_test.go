@@ -0,0 +1,49 @@
+package types
+
+import "testing"
+
+func TestPercentAndRatio(t *testing.T) {
+	tests := []struct {
+		description string
+		value       string
+		percent     float64
+		percentErr  bool
+		ratio       float64
+		ratioErr    bool
+	}{
+		{description: "percentage", value: "15%", percent: 0.15, ratio: 0.15},
+		{description: "fraction", value: "0.15", percent: 0.15, ratio: 0.15},
+		{description: "quotient", value: "1/4", percentErr: true, ratio: 0.25},
+		{description: "over 100 percent", value: "150 %", percent: 1.5, ratioErr: true},
+		{description: "negative", value: "-0.5", percent: -0.5, ratioErr: true},
+		{description: "division by zero", value: "1/0", percentErr: true, ratioErr: true},
+		{description: "invalid", value: "most", percentErr: true, ratioErr: true},
+		{description: "not a number", value: "inf/inf", percentErr: true, ratioErr: true},
+		{description: "infinite", value: "1/1e-320", percentErr: true, ratioErr: true},
+		{description: "negative infinity", value: "-inf/1", percentErr: true, ratioErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			var p Percent
+			err := p.Set(tt.value)
+			if tt.percentErr {
+				if err == nil {
+					t.Fatalf("expected an error for %s, got %v", tt.value, p)
+				}
+			} else if err != nil || float64(p) != tt.percent {
+				t.Fatalf("expected percent to be %v, got %v (%v)", tt.percent, float64(p), err)
+			}
+
+			var r Ratio
+			err = r.Set(tt.value)
+			if tt.ratioErr {
+				if err == nil {
+					t.Fatalf("expected an error for %s, got %v", tt.value, r)
+				}
+			} else if err != nil || float64(r) != tt.ratio {
+				t.Fatalf("expected ratio to be %v, got %v (%v)", tt.ratio, float64(r), err)
+			}
+		})
+	}
+}
@@ -0,0 +1,816 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseURL(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_API", "https://api.example.com/v1?debug=true")
+	os.Setenv("APP_PROXY", "http://proxy.example.com:3128")
+
+	spec := struct {
+		API      url.URL  `scheme:"http,https"`
+		Proxy    *url.URL `scheme:"http"`
+		Callback *url.URL
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.API.Host != "api.example.com" || spec.API.Path != "/v1" || spec.API.Query().Get("debug") != "true" {
+		t.Fatalf("expected api to be https://api.example.com/v1?debug=true, got %s", spec.API.String())
+	}
+	if spec.Proxy == nil || spec.Proxy.Port() != "3128" {
+		t.Fatalf("expected proxy to be http://proxy.example.com:3128, got %v", spec.Proxy)
+	}
+	if spec.Callback != nil {
+		t.Fatalf("expected callback to be nil, got %s", spec.Callback)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_API"] != "https://api.example.com/v1?debug=true" || values["APP_CALLBACK"] != "" {
+		t.Fatalf("expected the urls to be marshaled, got %v", values)
+	}
+
+	tests := []struct {
+		description string
+		key         string
+		value       string
+	}{
+		{description: "invalid url", key: "APP_CALLBACK", value: "http://[::1"},
+		{description: "scheme not allowed", key: "APP_PROXY", value: "https://proxy.example.com"},
+		{description: "relative url", key: "APP_API", value: "api.example.com"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			os.Clearenv()
+			os.Setenv(tc.key, tc.value)
+			var fieldErr *FieldError
+			if err := Parse("app", &spec); !errors.As(err, &fieldErr) {
+				t.Fatalf("expected a FieldError, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseIP(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_BIND", "0.0.0.0")
+	os.Setenv("APP_DNS", "2001:4860:4860::8888")
+	os.Setenv("APP_ALLOW", "10.0.0.0/8,192.168.1.0/24")
+	os.Setenv("APP_SUBNET", "172.16.0.0/12")
+	os.Setenv("APP_ADDR", "127.0.0.1")
+	os.Setenv("APP_LISTEN", "[::1]:8080")
+	os.Setenv("APP_TRUSTED", "fd00::/8, 10.1.0.0/16")
+
+	spec := struct {
+		Bind    net.IP
+		DNS     *net.IP
+		Allow   []net.IPNet
+		Subnet  net.IPNet
+		Addr    netip.Addr
+		Listen  netip.AddrPort
+		Trusted []netip.Prefix
+		Unset   netip.Prefix
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if !spec.Bind.Equal(net.IPv4zero) {
+		t.Fatalf("expected bind to be 0.0.0.0, got %s", spec.Bind)
+	}
+	if spec.DNS == nil || spec.DNS.String() != "2001:4860:4860::8888" {
+		t.Fatalf("expected dns to be 2001:4860:4860::8888, got %v", spec.DNS)
+	}
+	if len(spec.Allow) != 2 || !spec.Allow[0].Contains(net.ParseIP("10.1.2.3")) || spec.Allow[1].String() != "192.168.1.0/24" {
+		t.Fatalf("expected allow to be 10.0.0.0/8 and 192.168.1.0/24, got %v", spec.Allow)
+	}
+	if spec.Subnet.String() != "172.16.0.0/12" {
+		t.Fatalf("expected subnet to be 172.16.0.0/12, got %s", spec.Subnet.String())
+	}
+	if spec.Addr != netip.MustParseAddr("127.0.0.1") {
+		t.Fatalf("expected addr to be 127.0.0.1, got %s", spec.Addr)
+	}
+	if spec.Listen.Port() != 8080 || !spec.Listen.Addr().IsLoopback() {
+		t.Fatalf("expected listen to be [::1]:8080, got %s", spec.Listen)
+	}
+	if len(spec.Trusted) != 2 || !spec.Trusted[1].Contains(netip.MustParseAddr("10.1.2.3")) {
+		t.Fatalf("expected trusted to be fd00::/8 and 10.1.0.0/16, got %v", spec.Trusted)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_ALLOW"] != "10.0.0.0/8,192.168.1.0/24" || values["APP_SUBNET"] != "172.16.0.0/12" || values["APP_UNSET"] != "" {
+		t.Fatalf("expected the networks to be marshaled, got %v", values)
+	}
+
+	tests := []struct {
+		description string
+		key         string
+		value       string
+	}{
+		{description: "invalid ip", key: "APP_BIND", value: "localhost"},
+		{description: "invalid cidr", key: "APP_SUBNET", value: "172.16.0.0"},
+		{description: "invalid cidr in list", key: "APP_ALLOW", value: "10.0.0.0/8,10.0.0.0/33"},
+		{description: "invalid addr", key: "APP_ADDR", value: "127.0.0.256"},
+		{description: "missing port", key: "APP_LISTEN", value: "127.0.0.1"},
+		{description: "invalid prefix", key: "APP_TRUSTED", value: "fd00::"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			os.Clearenv()
+			os.Setenv(tc.key, tc.value)
+			var fieldErr *FieldError
+			if err := Parse("app", &spec); !errors.As(err, &fieldErr) {
+				t.Fatalf("expected a FieldError, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseRegexp(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_ORIGINS", `^https://([a-z]+\.)?example\.com$`)
+
+	spec := struct {
+		Origins *regexp.Regexp
+		Exclude *regexp.Regexp
+		Paths   []*regexp.Regexp `default:"^/api/,^/static/"`
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Origins == nil || !spec.Origins.MatchString("https://api.example.com") || spec.Origins.MatchString("https://example.org") {
+		t.Fatalf("expected origins to match the example.com origins, got %v", spec.Origins)
+	}
+	if spec.Exclude != nil {
+		t.Fatalf("expected exclude to be nil, got %s", spec.Exclude)
+	}
+	if len(spec.Paths) != 2 || !spec.Paths[1].MatchString("/static/app.js") {
+		t.Fatalf("expected paths to be ^/api/ and ^/static/, got %v", spec.Paths)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_ORIGINS"] != `^https://([a-z]+\.)?example\.com$` || values["APP_PATHS"] != "^/api/,^/static/" {
+		t.Fatalf("expected the regular expressions to be marshaled, got %v", values)
+	}
+
+	os.Setenv("APP_ORIGINS", "^(example")
+	err = Parse("app", &spec)
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || !strings.Contains(err.Error(), "missing closing )") {
+		t.Fatalf("expected a FieldError with the compile error, got %v", err)
+	}
+}
+
+// version is a struct implementing encoding.TextUnmarshaler, it must not be flattened.
+type version struct {
+	Major, Minor int
+}
+
+func (v *version) UnmarshalText(text []byte) error {
+	_, err := fmt.Sscanf(string(text), "v%d.%d", &v.Major, &v.Minor)
+	return err
+}
+
+func (v version) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("v%d.%d", v.Major, v.Minor)), nil
+}
+
+func TestParseTextUnmarshaler(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_LOG_LEVEL", "warn")
+	os.Setenv("APP_VERSION", "v1.2")
+	os.Setenv("APP_LEVELS", "debug,error")
+
+	spec := struct {
+		LogLevel slog.Level `env:"app_log_level"`
+		Version  version
+		Levels   []slog.Level
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.LogLevel != slog.LevelWarn {
+		t.Fatalf("expected log level to be WARN, got %s", spec.LogLevel)
+	}
+	if spec.Version != (version{1, 2}) {
+		t.Fatalf("expected version to be v1.2, got %+v", spec.Version)
+	}
+	if len(spec.Levels) != 2 || spec.Levels[1] != slog.LevelError {
+		t.Fatalf("expected levels to be DEBUG and ERROR, got %v", spec.Levels)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_LOG_LEVEL"] != "WARN" || values["APP_VERSION"] != "v1.2" {
+		t.Fatalf("expected the values to be marshaled with MarshalText, got %v", values)
+	}
+
+	os.Setenv("APP_VERSION", "1.2")
+	var fieldErr *FieldError
+	if err := Parse("app", &spec); !errors.As(err, &fieldErr) {
+		t.Fatalf("expected a FieldError, got %v", err)
+	}
+}
+
+// blob implements encoding.BinaryUnmarshaler, it is set from a base64 value.
+type blob struct {
+	data []byte
+}
+
+func (b *blob) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return errors.New("empty blob")
+	}
+	b.data = data
+	return nil
+}
+
+func (b blob) MarshalBinary() ([]byte, error) {
+	return b.data, nil
+}
+
+func TestParseBinaryUnmarshaler(t *testing.T) {
+	tests := []struct {
+		description string
+		value       string
+	}{
+		{description: "standard encoding", value: "+/8AAQ=="},
+		{description: "standard encoding without padding", value: "+/8AAQ"},
+		{description: "url encoding", value: "-_8AAQ=="},
+		{description: "url encoding without padding", value: "-_8AAQ"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			os.Clearenv()
+			os.Setenv("APP_KEY", tc.value)
+
+			spec := struct {
+				Key blob
+			}{}
+			if err := Parse("app", &spec); err != nil {
+				t.Fatal(err)
+			}
+			if string(spec.Key.data) != "\xfb\xff\x00\x01" {
+				t.Fatalf("expected key to be fbff0001, got %x", spec.Key.data)
+			}
+
+			values, err := Marshal("app", &spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if values["APP_KEY"] != "+/8AAQ==" {
+				t.Fatalf("expected key to be marshaled to +/8AAQ==, got %s", values["APP_KEY"])
+			}
+		})
+	}
+
+	spec := struct {
+		Key blob
+	}{}
+	for _, value := range []string{"not base64!", ""} {
+		os.Setenv("APP_KEY", value)
+		var fieldErr *FieldError
+		if err := Parse("app", &spec); !errors.As(err, &fieldErr) {
+			t.Fatalf("expected a FieldError for %q, got %v", value, err)
+		}
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		value string
+		size  uint64
+	}{
+		{"1024", 1024},
+		{"0", 0},
+		{"512B", 512},
+		{"512MB", 512e6},
+		{"512mb", 512e6},
+		{"2GiB", 2 << 30},
+		{"2Gi", 2 << 30},
+		{"1.5 GB", 1.5e9},
+		{"10K", 10e3},
+		{"1PiB", 1 << 50},
+	}
+	for _, tc := range tests {
+		size, err := ParseByteSize(tc.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size != tc.size {
+			t.Fatalf("expected %s to be %d bytes, got %d", tc.value, tc.size, size)
+		}
+	}
+
+	for _, value := range []string{"", "MB", "-1MB", "1XB", "16385PiB"} {
+		if _, err := ParseByteSize(value); err == nil {
+			t.Fatalf("expected error for %q, got nil", value)
+		}
+	}
+}
+
+func TestParseByteSizeFields(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_MAX_BODY", "10MiB")
+	os.Setenv("APP_CACHE", "512MB")
+	os.Setenv("APP_DISK", "2TB")
+
+	spec := struct {
+		MaxBody ByteSize `env:"app_max_body"`
+		Cache   int64    `unit:"bytes"`
+		Disk    uint64   `unit:"bytes"`
+		Buffer  ByteSize `default:"4KiB"`
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.MaxBody != 10<<20 || spec.Cache != 512e6 || spec.Disk != 2e12 || spec.Buffer != 4096 {
+		t.Fatalf("expected the sizes to be parsed, got %+v", spec)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_MAX_BODY"] != "10MiB" || values["APP_CACHE"] != "512MB" || values["APP_DISK"] != "2TB" || values["APP_BUFFER"] != "4KiB" {
+		t.Fatalf("expected the sizes to be marshaled with units, got %v", values)
+	}
+
+	small := struct {
+		Size int16 `unit:"bytes"`
+	}{}
+	os.Setenv("APP_SIZE", "1MB")
+	var fieldErr *FieldError
+	if err := Parse("app", &small); !errors.As(err, &fieldErr) {
+		t.Fatalf("expected a FieldError for the overflow, got %v", err)
+	}
+}
+
+func TestParseSIFields(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_QUEUE", "10k")
+	os.Setenv("APP_ENTRIES", "4Ki")
+	os.Setenv("APP_OFFSET", "-2.5M")
+	os.Setenv("APP_PLAIN", "1200")
+
+	spec := struct {
+		Queue   int    `unit:"si"`
+		Entries uint32 `unit:"si"`
+		Offset  int64  `unit:"si"`
+		Plain   int    `unit:"si"`
+		Default uint   `unit:"si" default:"2.3k"`
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Queue != 10000 || spec.Entries != 4096 || spec.Offset != -2500000 || spec.Plain != 1200 || spec.Default != 2300 {
+		t.Fatalf("expected the numbers to be parsed with their suffixes, got %+v", spec)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_QUEUE"] != "10k" || values["APP_ENTRIES"] != "4Ki" || values["APP_OFFSET"] != "-2500k" || values["APP_PLAIN"] != "1200" {
+		t.Fatalf("expected the numbers to be marshaled with suffixes, got %v", values)
+	}
+
+	tests := []struct {
+		description string
+		value       string
+	}{
+		{description: "fraction", value: "1.5"},
+		{description: "byte unit", value: "2MB"},
+		{description: "overflow", value: "5Gi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			os.Setenv("APP_ENTRIES", tt.value)
+			err := Parse("app", &spec)
+			var fieldErr *FieldError
+			if !errors.As(err, &fieldErr) {
+				t.Fatalf("expected a FieldError for %s, got %v", tt.value, err)
+			}
+		})
+	}
+}
+
+func TestParseMailAddress(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_SENDER", "Alerts <alerts@example.com>")
+	os.Setenv("APP_RECIPIENTS", "oncall@example.com,Ops Team <ops@example.com>")
+
+	spec := struct {
+		Sender     mail.Address
+		Recipients []mail.Address
+		ReplyTo    *mail.Address
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Sender.Name != "Alerts" || spec.Sender.Address != "alerts@example.com" {
+		t.Fatalf("expected sender to be Alerts <alerts@example.com>, got %+v", spec.Sender)
+	}
+	if len(spec.Recipients) != 2 || spec.Recipients[1].Address != "ops@example.com" {
+		t.Fatalf("expected recipients to be oncall and ops, got %v", spec.Recipients)
+	}
+	if spec.ReplyTo != nil {
+		t.Fatalf("expected reply to to be nil, got %v", spec.ReplyTo)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_SENDER"] != `"Alerts" <alerts@example.com>` || values["APP_RECIPIENTS"] != `oncall@example.com,"Ops Team" <ops@example.com>` {
+		t.Fatalf("expected the addresses to be marshaled, got %v", values)
+	}
+
+	os.Setenv("APP_SENDER", "alerts.example.com")
+	err = Parse("app", &spec)
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || !strings.Contains(err.Error(), `invalid email address "alerts.example.com"`) {
+		t.Fatalf("expected a FieldError for the invalid address, got %v", err)
+	}
+}
+
+func TestParseLocation(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_REPORT_TZ", "UTC")
+	os.Setenv("APP_ZONES", "UTC,Local")
+
+	spec := struct {
+		ReportTZ *time.Location `env:"app_report_tz"`
+		Zones    []*time.Location
+		Default  *time.Location
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.ReportTZ != time.UTC {
+		t.Fatalf("expected report tz to be UTC, got %v", spec.ReportTZ)
+	}
+	if len(spec.Zones) != 2 || spec.Zones[1] != time.Local {
+		t.Fatalf("expected zones to be UTC and Local, got %v", spec.Zones)
+	}
+	if spec.Default != nil {
+		t.Fatalf("expected default to be nil, got %v", spec.Default)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_REPORT_TZ"] != "UTC" || values["APP_ZONES"] != "UTC,Local" || values["APP_DEFAULT"] != "" {
+		t.Fatalf("expected the locations to be marshaled, got %v", values)
+	}
+
+	os.Setenv("APP_REPORT_TZ", "Mars/Olympus_Mons")
+	err = Parse("app", &spec)
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || !strings.Contains(err.Error(), `unknown time zone "Mars/Olympus_Mons"`) {
+		t.Fatalf("expected a FieldError for the unknown time zone, got %v", err)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_LEVEL", "Warning")
+	os.Setenv("APP_VERBOSE", "debug-2")
+	os.Setenv("APP_NUMERIC", "-8")
+
+	spec := struct {
+		Level   slog.Level
+		Verbose slog.Level
+		Numeric slog.Level
+		Default slog.Level `default:"error"`
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Level != slog.LevelWarn {
+		t.Fatalf("expected level to be WARN, got %s", spec.Level)
+	}
+	if spec.Verbose != slog.LevelDebug-2 || spec.Numeric != slog.LevelDebug-4 {
+		t.Fatalf("expected verbose to be DEBUG-2 and numeric DEBUG-4, got %s and %s", spec.Verbose, spec.Numeric)
+	}
+	if spec.Default != slog.LevelError {
+		t.Fatalf("expected default to be ERROR, got %s", spec.Default)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_NUMERIC"] != "DEBUG-4" {
+		t.Fatalf("expected numeric to be marshaled to DEBUG-4, got %s", values["APP_NUMERIC"])
+	}
+
+	os.Setenv("APP_LEVEL", "loud")
+	err = Parse("app", &spec)
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || !strings.Contains(err.Error(), `unknown log level "loud"`) {
+		t.Fatalf("expected a FieldError for the unknown level, got %v", err)
+	}
+}
+
+func TestParseFileMode(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_FILE_MODE", "0640")
+	os.Setenv("APP_DIR_MODE", "750")
+
+	spec := struct {
+		FileMode os.FileMode `env:"app_file_mode"`
+		DirMode  fs.FileMode `env:"app_dir_mode"`
+		Default  os.FileMode `default:"0o600"`
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.FileMode != 0o640 || spec.DirMode != 0o750 || spec.Default != 0o600 {
+		t.Fatalf("expected the modes to be 0640, 0750 and 0600, got %o, %o and %o", spec.FileMode, spec.DirMode, spec.Default)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_FILE_MODE"] != "0640" {
+		t.Fatalf("expected file mode to be marshaled to 0640, got %s", values["APP_FILE_MODE"])
+	}
+
+	os.Setenv("APP_FILE_MODE", "4755")
+	os.Setenv("APP_DIR_MODE", "1777")
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+	if spec.FileMode != fs.ModeSetuid|0o755 || spec.DirMode != fs.ModeSticky|0o777 {
+		t.Fatalf("expected the modes to be setuid 0755 and sticky 0777, got %s and %s", spec.FileMode, spec.DirMode)
+	}
+	values, err = Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_FILE_MODE"] != "04755" || values["APP_DIR_MODE"] != "01777" {
+		t.Fatalf("expected the modes to be marshaled to 04755 and 01777, got %v", values)
+	}
+
+	for _, value := range []string{"0689", "10000", "0o77777"} {
+		os.Setenv("APP_FILE_MODE", value)
+		err = Parse("app", &spec)
+		var fieldErr *FieldError
+		if !errors.As(err, &fieldErr) || !strings.Contains(err.Error(), `invalid file mode "`+value+`"`) {
+			t.Fatalf("expected a FieldError for the invalid file mode %s, got %v", value, err)
+		}
+	}
+}
+
+func TestParseBig(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_WEI", "1000000000000000000000000")
+	os.Setenv("APP_MASK", "0xffffffffffffffffffffffff")
+	os.Setenv("APP_RATIO", "0.1000000000000000000001")
+	os.Setenv("APP_SHARE", "1/3")
+
+	spec := struct {
+		Wei   *big.Int
+		Mask  big.Int
+		Ratio *big.Float `prec:"128"`
+		Share *big.Rat
+		Unset *big.Int
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	wei, _ := new(big.Int).SetString("1000000000000000000000000", 10)
+	if spec.Wei == nil || spec.Wei.Cmp(wei) != 0 {
+		t.Fatalf("expected wei to be 10^24, got %v", spec.Wei)
+	}
+	if spec.Mask.BitLen() != 96 {
+		t.Fatalf("expected mask to be 96 bits long, got %d", spec.Mask.BitLen())
+	}
+	if spec.Ratio == nil || spec.Ratio.Prec() != 128 || spec.Ratio.Text('g', 22) != "0.1000000000000000000001" {
+		t.Fatalf("expected ratio to be 0.1000000000000000000001 with 128 bits of precision, got %v", spec.Ratio)
+	}
+	if spec.Share == nil || spec.Share.Cmp(big.NewRat(1, 3)) != 0 {
+		t.Fatalf("expected share to be 1/3, got %v", spec.Share)
+	}
+	if spec.Unset != nil {
+		t.Fatalf("expected unset to be nil, got %v", spec.Unset)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_WEI"] != "1000000000000000000000000" || values["APP_SHARE"] != "1/3" {
+		t.Fatalf("expected the big numbers to be marshaled, got %v", values)
+	}
+
+	for key, value := range map[string]string{"APP_WEI": "1e24", "APP_RATIO": "ten"} {
+		os.Clearenv()
+		os.Setenv(key, value)
+		var fieldErr *FieldError
+		if err := Parse("app", &spec); !errors.As(err, &fieldErr) {
+			t.Fatalf("expected a FieldError for %s, got %v", key, err)
+		}
+	}
+}
+
+func TestParsePercentAndRatio(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_SAMPLING", "1/100")
+
+	spec := struct {
+		Quota    Percent `default:"80%"`
+		Sampling Ratio
+	}{}
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+	if spec.Quota != 0.8 || spec.Sampling != 0.01 {
+		t.Fatalf("expected quota to be 0.8 and sampling 0.01, got %+v", spec)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_QUOTA"] != "80%" || values["APP_SAMPLING"] != "0.01" {
+		t.Fatalf("expected quota to be marshaled to 80%% and sampling to 0.01, got %v", values)
+	}
+}
+
+func TestParseHostPort(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_DB", "db.example.com:5432")
+	os.Setenv("APP_PEERS", "[::1]:7000,10.0.0.2:7000")
+
+	spec := struct {
+		DB     HostPort
+		Peers  []HostPort
+		Listen HostPort `default:":8080"`
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.DB.Host != "db.example.com" || spec.DB.Port != 5432 {
+		t.Fatalf("expected db to be db.example.com and 5432, got %+v", spec.DB)
+	}
+	if len(spec.Peers) != 2 || spec.Peers[0].Host != "::1" || spec.Peers[1].Port != 7000 {
+		t.Fatalf("expected peers to be [::1]:7000 and 10.0.0.2:7000, got %v", spec.Peers)
+	}
+	if spec.Listen.Host != "" || spec.Listen.Port != 8080 {
+		t.Fatalf("expected listen to be :8080, got %+v", spec.Listen)
+	}
+
+	values, err := Marshal("app", &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["APP_PEERS"] != "[::1]:7000,10.0.0.2:7000" || values["APP_LISTEN"] != ":8080" {
+		t.Fatalf("expected the addresses to be marshaled, got %v", values)
+	}
+
+	for _, value := range []string{"db.example.com", "db.example.com:postgres", "db.example.com:70000"} {
+		os.Setenv("APP_DB", value)
+		err := Parse("app", &spec)
+		var fieldErr *FieldError
+		if !errors.As(err, &fieldErr) {
+			t.Fatalf("expected a FieldError for %s, got %v", value, err)
+		}
+	}
+}
+
+func TestListen(t *testing.T) {
+	os.Clearenv()
+
+	spec := struct {
+		Listen Listen
+		Admin  Listen
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+	if addr := spec.Listen.Addr(); addr != ":8080" {
+		t.Fatalf("expected the default address to be :8080, got %s", addr)
+	}
+
+	os.Setenv("APP_LISTEN_PORT", "3000")
+	os.Setenv("APP_LISTEN_HOST", "127.0.0.1")
+	os.Setenv("APP_ADMIN_PORT", "9090")
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+	if addr := spec.Listen.Addr(); addr != "127.0.0.1:3000" {
+		t.Fatalf("expected the address to be 127.0.0.1:3000, got %s", addr)
+	}
+	if addr := spec.Admin.Addr(); addr != ":9090" {
+		t.Fatalf("expected the admin address to be :9090, got %s", addr)
+	}
+
+	for _, value := range []string{"0", "65536", "http"} {
+		os.Setenv("APP_LISTEN_PORT", value)
+		err := Parse("app", &spec)
+		var fieldErr *FieldError
+		if !errors.As(err, &fieldErr) || !strings.Contains(err.Error(), "expected a number between 1 and 65535") {
+			t.Fatalf("expected a FieldError for port %s, got %v", value, err)
+		}
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Clearenv()
+	os.Setenv("HOME", "/home/gopher")
+	os.Setenv("DATA_ROOT", "/srv/data")
+	os.Setenv("APP_CACHE", "~/cache/../.cache")
+	os.Setenv("APP_DATA", "${DATA_ROOT}/app/")
+	os.Setenv("APP_LOGS", "logs")
+	os.Setenv("APP_PLUGINS", "plugins")
+
+	spec := struct {
+		Cache   Path
+		Data    Path
+		Logs    Path
+		Plugins Path `base:"$HOME/app"`
+		Empty   Path
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		name string
+		got  Path
+		want string
+	}{
+		{"cache", spec.Cache, "/home/gopher/.cache"},
+		{"data", spec.Data, "/srv/data/app"},
+		{"logs", spec.Logs, filepath.Join(wd, "logs")},
+		{"plugins", spec.Plugins, "/home/gopher/app/plugins"},
+		{"empty", spec.Empty, ""},
+	}
+	for _, e := range expected {
+		if string(e.got) != e.want {
+			t.Fatalf("expected %s to be %s, got %s", e.name, e.want, e.got)
+		}
+	}
+}
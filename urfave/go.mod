@@ -0,0 +1,18 @@
+module github.com/josemukorivo/config/urfave
+
+go 1.22
+
+require (
+	github.com/josemukorivo/config v0.0.0-20261016120655-98cbede698f8
+	github.com/urfave/cli/v2 v2.27.5
+)
+
+require (
+	github.com/BurntSushi/toml v1.4.0 // indirect
+	github.com/cpuguy83/go-md2man/v2 v2.0.5 // indirect
+	github.com/hashicorp/hcl v1.0.0 // indirect
+	github.com/russross/blackfriday/v2 v2.1.0 // indirect
+	github.com/spf13/pflag v1.0.5 // indirect
+	github.com/xrash/smetrics v0.0.0-20240521201337-686a1a2994c1 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,118 @@
+// Package urfave integrates config with github.com/urfave/cli. It generates the flags of a command from
+// a config struct and populates the struct in the Before hook of the command.
+//
+//	var cfg Config
+//	flags, err := urfave.Flags("app", &cfg)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	app := &cli.App{
+//		Flags:  flags,
+//		Before: urfave.Before("app", &cfg),
+//	}
+package urfave
+
+import (
+	"flag"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/josemukorivo/config"
+	"github.com/urfave/cli/v2"
+)
+
+// Flags returns a flag for every field of cfg that config.BindFlags registers a flag for, cfg must be a
+// pointer to struct. The flags are named like the flags registered by config.BindFlags, show the
+// environment variable of the field in their help text and use the default and usage tags as their
+// default value and help text. The flags do not read the environment themselves, config.Parse does, so
+// that only the values set on the command line take precedence over the environment.
+func Flags(prefix string, cfg any) ([]cli.Flag, error) {
+	fields, err := config.Fields(prefix, cfg)
+	if err != nil {
+		return nil, err
+	}
+	names, err := flagNames(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// Bind a zero config of the same type to find the fields config.BindFlags skips, like optional
+	// sections and maps of structs, without binding cfg.
+	bound := flag.NewFlagSet("", flag.ContinueOnError)
+	if err := config.BindFlags(bound, reflect.New(reflect.TypeOf(cfg).Elem()).Interface()); err != nil {
+		return nil, err
+	}
+
+	flags := make([]cli.Flag, 0, len(fields))
+	for i, field := range fields {
+		f := bound.Lookup(names[i])
+		if f == nil {
+			continue
+		}
+		envKey := field.Key
+		if field.EnvKey != "" {
+			envKey = field.EnvKey
+		}
+		usage := strings.TrimSpace(field.Tags.Get("usage") + " [$" + envKey + "]")
+
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			flags = append(flags, &cli.BoolFlag{
+				Name:  names[i],
+				Usage: usage,
+				Value: field.Default == "true",
+			})
+			continue
+		}
+		flags = append(flags, &cli.StringFlag{
+			Name:  names[i],
+			Usage: usage,
+			Value: field.Default,
+		})
+	}
+	return flags, nil
+}
+
+// Before returns a cli.BeforeFunc that parses cfg with config.Parse. Values of the flags returned by
+// Flags set on the command line take precedence over environment variables and defaults. The optional envFiles are passed on
+// to config.Parse.
+func Before(prefix string, cfg any, envFiles ...string) cli.BeforeFunc {
+	return func(c *cli.Context) error {
+		// Replay the flags set on the command onto a flag set bound to cfg, config.Parse resolves
+		// bound flags before environment variables.
+		fs := flag.NewFlagSet(c.App.Name, flag.ContinueOnError)
+		if err := config.BindFlags(fs, cfg); err != nil {
+			return err
+		}
+		names, err := flagNames(cfg)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			if !c.IsSet(name) {
+				continue
+			}
+			value := c.String(name)
+			if b, ok := c.Value(name).(bool); ok {
+				value = strconv.FormatBool(b)
+			}
+			if err := fs.Set(name, value); err != nil {
+				return err
+			}
+		}
+		return config.Parse(prefix, cfg, envFiles...)
+	}
+}
+
+// flagNames returns the flag names of the fields of cfg in the order returned by config.Fields.
+func flagNames(cfg any) ([]string, error) {
+	fields, err := config.Fields("", cfg)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = strings.ToLower(strings.ReplaceAll(field.Key, "_", "-"))
+	}
+	return names, nil
+}
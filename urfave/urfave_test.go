@@ -0,0 +1,99 @@
+package urfave
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/josemukorivo/config"
+	"github.com/urfave/cli/v2"
+)
+
+type Config struct {
+	Host  string `default:"localhost" usage:"address to listen on"`
+	Port  int    `default:"8080"`
+	Debug bool
+	DB    struct {
+		Port int `required:"true"`
+	}
+	Verbose  *bool
+	Cache    *struct{ TTL int }
+	Replicas map[string]struct{ Host string }
+}
+
+func TestFlags(t *testing.T) {
+	var cfg Config
+	flags, err := Flags("app", &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, len(flags))
+	for i, f := range flags {
+		names[i] = f.Names()[0]
+	}
+	expected := []string{"host", "port", "debug", "db-port", "verbose"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected the flags to be %v, got %v", expected, names)
+	}
+	host, ok := flags[0].(*cli.StringFlag)
+	if !ok {
+		t.Fatalf("expected host to be a string flag, got %T", flags[0])
+	}
+	if host.Name != "host" || host.Value != "localhost" || host.Usage != "address to listen on [$APP_HOST]" || host.EnvVars != nil {
+		t.Fatalf("unexpected host flag %+v", host)
+	}
+	if _, ok := flags[2].(*cli.BoolFlag); !ok {
+		t.Fatalf("expected debug to be a bool flag, got %T", flags[2])
+	}
+	if _, ok := flags[4].(*cli.BoolFlag); !ok {
+		t.Fatalf("expected verbose to be a bool flag, got %T", flags[4])
+	}
+}
+
+func TestBefore(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_PORT", "9000")
+	os.Setenv("APP_DB_PORT", "5432")
+
+	var cfg Config
+	flags, err := Flags("app", &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &cli.App{
+		Name:   "app",
+		Flags:  flags,
+		Before: Before("app", &cfg),
+		Action: func(*cli.Context) error { return nil },
+	}
+
+	if err := app.Run([]string{"app", "--db-port", "6543", "--debug", "--verbose"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Host != "localhost" {
+		t.Fatalf("expected host to be localhost, got %s", cfg.Host)
+	}
+	if cfg.Port != 9000 {
+		t.Fatalf("expected port to be 9000, got %d", cfg.Port)
+	}
+	if !cfg.Debug {
+		t.Fatal("expected debug to be true, got false")
+	}
+	if cfg.DB.Port != 6543 {
+		t.Fatalf("expected db port to be 6543, got %d", cfg.DB.Port)
+	}
+	if cfg.Verbose == nil || !*cfg.Verbose {
+		t.Fatalf("expected verbose to be true, got %v", cfg.Verbose)
+	}
+
+	// Only the values set on the command line come from the flags.
+	origins, err := config.ParseLayers("app", &cfg, config.Layer{Name: "env", Source: config.EnvSource()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if origins["APP_PORT"] != "env" || origins["APP_DB_PORT"] != config.OriginFlags {
+		t.Fatalf("expected port to come from env and db port from flags, got %v", origins)
+	}
+}
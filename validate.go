@@ -0,0 +1,173 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// validateField checks the value of a field once it is parsed against the validation tags of the field,
+// tags. value is the raw value the field was parsed from.
+func validateField(value string, field reflect.Value, tags reflect.StructTag) error {
+	if err := checkOneOf(value, field, tags); err != nil {
+		return err
+	}
+	if err := checkLength(field, tags); err != nil {
+		return err
+	}
+	return checkExists(field, tags)
+}
+
+// patterns caches the regular expressions of the pattern tags, so they are compiled once.
+var patterns sync.Map // map[string]*regexp.Regexp
+
+// checkPattern checks that the raw value of a field, before it is parsed, matches the regular
+// expression of the pattern tag, for example pattern:"^[a-z0-9-]+$".
+func checkPattern(value string, tags reflect.StructTag) error {
+	pattern, ok := tags.Lookup("pattern")
+	if !ok {
+		return nil
+	}
+
+	re, ok := patterns.Load(pattern)
+	if !ok {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern tag %q: %w", pattern, err)
+		}
+		re, _ = patterns.LoadOrStore(pattern, compiled)
+	}
+	if !re.(*regexp.Regexp).MatchString(value) {
+		return fmt.Errorf("value %q does not match the pattern %s", value, pattern)
+	}
+	return nil
+}
+
+// checkOneOf checks that value is one of the space separated values of the oneof tag, for example
+// oneof:"debug info warn error". Every item of the value of a slice or an array is checked.
+func checkOneOf(value string, field reflect.Value, tags reflect.StructTag) error {
+	allowed := strings.Fields(tags.Get("oneof"))
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	items := []string{value}
+	if t := field.Type(); t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		items = nil
+		if value != "" {
+			items = splitList(value, listSeparator(tags))
+		}
+	}
+	for _, item := range items {
+		if item = strings.TrimSpace(item); !slices.Contains(allowed, item) {
+			return fmt.Errorf("value %q is not one of %s", item, strings.Join(allowed, ", "))
+		}
+	}
+	return nil
+}
+
+// checkLength checks the length of a string, in characters, or of a slice, an array or a map against
+// the len, minlen and maxlen tags.
+func checkLength(field reflect.Value, tags reflect.StructTag) error {
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return nil
+		}
+		field = field.Elem()
+	}
+
+	var n int
+	switch field.Kind() {
+	case reflect.String:
+		n = utf8.RuneCountInString(field.String())
+	case reflect.Slice, reflect.Array, reflect.Map:
+		n = field.Len()
+	default:
+		for _, name := range []string{"len", "minlen", "maxlen"} {
+			if _, ok := tags.Lookup(name); ok {
+				return fmt.Errorf("%s tag on %s, expected a string, a slice or a map", name, field.Type())
+			}
+		}
+		return nil
+	}
+
+	for _, check := range []struct {
+		name string
+		ok   func(limit int) bool
+		msg  string
+	}{
+		{"len", func(limit int) bool { return n == limit }, "length %d is not %d"},
+		{"minlen", func(limit int) bool { return n >= limit }, "length %d is less than the minimum %d"},
+		{"maxlen", func(limit int) bool { return n <= limit }, "length %d is greater than the maximum %d"},
+	} {
+		tag, ok := tags.Lookup(check.name)
+		if !ok {
+			continue
+		}
+		limit, err := strconv.Atoi(tag)
+		if err != nil || limit < 0 {
+			return fmt.Errorf("invalid %s tag %q", check.name, tag)
+		}
+		if !check.ok(limit) {
+			return fmt.Errorf(check.msg, n, limit)
+		}
+	}
+	return nil
+}
+
+// checkExists checks that the paths held by field exist and are of the kind of the exists tag, "file"
+// or "dir", if any. The field is a string, like a Path, a pointer to a string or a slice of strings.
+func checkExists(field reflect.Value, tags reflect.StructTag) error {
+	kind := tags.Get("exists")
+	if kind == "" {
+		return nil
+	}
+	if kind != "file" && kind != "dir" {
+		return fmt.Errorf("invalid exists tag %q, expected file or dir", kind)
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		return checkPath(field.String(), kind == "dir")
+	case reflect.Ptr:
+		if field.IsNil() {
+			return nil
+		}
+		return checkExists(field.Elem(), tags)
+	case reflect.Slice, reflect.Array:
+		for i := range field.Len() {
+			if err := checkExists(field.Index(i), tags); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("exists tag on %s, expected a string or a Path", field.Type())
+}
+
+// checkPath checks that path exists and is a directory if dir is true, or not a directory otherwise.
+func checkPath(path string, dir bool) error {
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		if dir {
+			return fmt.Errorf("directory %q does not exist", path)
+		}
+		return fmt.Errorf("file %q does not exist", path)
+	case err != nil:
+		return err
+	case dir && !info.IsDir():
+		return fmt.Errorf("%q is not a directory", path)
+	case !dir && info.IsDir():
+		return fmt.Errorf("%q is a directory, not a file", path)
+	}
+	return nil
+}
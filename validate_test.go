@@ -0,0 +1,193 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOneOfTag(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_LEVEL", "warn")
+	os.Setenv("APP_FORMATS", "json, text")
+
+	spec := struct {
+		Level   string   `oneof:"debug info warn error"`
+		Formats []string `oneof:"json text"`
+		Mode    string   `oneof:"fast safe" default:"safe"`
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+	if spec.Level != "warn" || spec.Mode != "safe" {
+		t.Fatalf("expected level to be warn and mode safe, got %s and %s", spec.Level, spec.Mode)
+	}
+
+	tests := []struct {
+		description string
+		key, value  string
+		err         string
+	}{
+		{description: "unknown value", key: "APP_LEVEL", value: "verbose", err: `value "verbose" is not one of debug, info, warn, error`},
+		{description: "case-sensitive", key: "APP_LEVEL", value: "WARN", err: `value "WARN" is not one of debug, info, warn, error`},
+		{description: "slice item", key: "APP_FORMATS", value: "json,xml", err: `value "xml" is not one of json, text`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			os.Setenv("APP_LEVEL", "warn")
+			os.Setenv("APP_FORMATS", "json")
+			os.Setenv(tt.key, tt.value)
+
+			err := Parse("app", &spec)
+			var fieldErr *FieldError
+			if !errors.As(err, &fieldErr) || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected a FieldError containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestPatternTag(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("APP_SLUG", "my-service-2")
+	os.Setenv("APP_PORT", "8080")
+
+	spec := struct {
+		Slug string `pattern:"^[a-z0-9-]+$"`
+		Port int    `pattern:"^[0-9]{4}$"`
+	}{}
+
+	if err := Parse("app", &spec); err != nil {
+		t.Fatal(err)
+	}
+	if spec.Slug != "my-service-2" || spec.Port != 8080 {
+		t.Fatalf("expected slug to be my-service-2 and port 8080, got %s and %d", spec.Slug, spec.Port)
+	}
+
+	os.Setenv("APP_SLUG", "My Service")
+	err := Parse("app", &spec)
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || !strings.Contains(err.Error(), `value "My Service" does not match the pattern ^[a-z0-9-]+$`) {
+		t.Fatalf("expected a FieldError for the slug, got %v", err)
+	}
+
+	// The raw value is matched before it is parsed.
+	os.Setenv("APP_SLUG", "my-service")
+	os.Setenv("APP_PORT", "80")
+	if err := Parse("app", &spec); !errors.As(err, &fieldErr) || !strings.Contains(err.Error(), "does not match") {
+		t.Fatalf("expected a FieldError for the port, got %v", err)
+	}
+
+	invalid := struct {
+		Slug string `pattern:"["`
+	}{}
+	if err := Parse("app", &invalid); err == nil || !strings.Contains(err.Error(), `invalid pattern tag "["`) {
+		t.Fatalf("expected an error for the invalid pattern, got %v", err)
+	}
+}
+
+func TestLengthTags(t *testing.T) {
+	type spec struct {
+		APIKey string            `minlen:"32" maxlen:"64"`
+		Region string            `len:"2"`
+		Hosts  []string          `minlen:"1"`
+		Labels map[string]string `maxlen:"2"`
+		Token  *string           `len:"4"`
+	}
+
+	tests := []struct {
+		description string
+		env         map[string]string
+		err         string
+	}{
+		{description: "valid lengths", env: map[string]string{"APP_APIKEY": strings.Repeat("k", 32), "APP_REGION": "éu", "APP_HOSTS": "a", "APP_TOKEN": "abcd"}},
+		{description: "too short", env: map[string]string{"APP_APIKEY": "short"}, err: "length 5 is less than the minimum 32"},
+		{description: "too long", env: map[string]string{"APP_APIKEY": strings.Repeat("k", 65)}, err: "length 65 is greater than the maximum 64"},
+		{description: "exact length", env: map[string]string{"APP_REGION": "eu-west"}, err: "length 7 is not 2"},
+		{description: "empty slice", env: map[string]string{"APP_HOSTS": ""}, err: "length 0 is less than the minimum 1"},
+		{description: "map", env: map[string]string{"APP_LABELS": "a:1,b:2,c:3"}, err: "length 3 is greater than the maximum 2"},
+		{description: "pointer", env: map[string]string{"APP_TOKEN": "abc"}, err: "length 3 is not 4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			os.Clearenv()
+			for key, value := range tt.env {
+				os.Setenv(key, value)
+			}
+
+			var cfg spec
+			err := Parse("app", &cfg)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var fieldErr *FieldError
+			if !errors.As(err, &fieldErr) || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected a FieldError containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+
+	os.Clearenv()
+	os.Setenv("APP_PORT", "80")
+	invalid := struct {
+		Port int `minlen:"2"`
+	}{}
+	if err := Parse("app", &invalid); err == nil || !strings.Contains(err.Error(), "minlen tag on int") {
+		t.Fatalf("expected an error for the minlen tag on an int, got %v", err)
+	}
+}
+
+func TestExistsTag(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "cert.pem")
+	if err := os.WriteFile(file, []byte("cert"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	type spec struct {
+		Cert    string   `exists:"file"`
+		Data    Path     `exists:"dir"`
+		Include []string `exists:"dir"`
+		Key     *string  `exists:"file"`
+	}
+
+	tests := []struct {
+		description string
+		env         map[string]string
+		err         string
+	}{
+		{description: "existing paths", env: map[string]string{"APP_CERT": file, "APP_DATA": dir, "APP_INCLUDE": dir + "," + dir}},
+		{description: "missing file", env: map[string]string{"APP_CERT": filepath.Join(dir, "missing.pem")}, err: "missing.pem\" does not exist"},
+		{description: "directory for a file", env: map[string]string{"APP_KEY": dir}, err: "is a directory, not a file"},
+		{description: "file for a directory", env: map[string]string{"APP_INCLUDE": dir + "," + file}, err: "is not a directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			os.Clearenv()
+			for key, value := range tt.env {
+				os.Setenv(key, value)
+			}
+
+			var cfg spec
+			err := Parse("app", &cfg)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var fieldErr *FieldError
+			if !errors.As(err, &fieldErr) || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected a FieldError containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
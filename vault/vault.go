@@ -0,0 +1,173 @@
+// Package vault provides a config.Source backed by a HashiCorp Vault KV version 2 secret. The secret is
+// read directly from Vault so secret values never pass through the environment of the process.
+//
+//	source := vault.New("https://vault.example.com:8200", "app", "secret/data/app",
+//		vault.WithAppRole(roleID, secretID),
+//	)
+//	err := config.ParseSources("app", &cfg, config.EnvSource(), source)
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/josemukorivo/config"
+)
+
+// Option configures the Source.
+type Option func(*source)
+
+// WithToken authenticates with the given token. By default the token is read from the VAULT_TOKEN
+// environment variable.
+func WithToken(token string) Option {
+	return func(s *source) {
+		s.token = token
+	}
+}
+
+// WithAppRole authenticates with the AppRole auth method mounted at approle/.
+func WithAppRole(roleID, secretID string) Option {
+	return func(s *source) {
+		s.roleID = roleID
+		s.secretID = secretID
+	}
+}
+
+// WithNamespace sets the Vault Enterprise namespace of the requests.
+func WithNamespace(namespace string) Option {
+	return func(s *source) {
+		s.namespace = namespace
+	}
+}
+
+// WithTimeout bounds the time spent reading the secret, it defaults to 5 seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(s *source) {
+		s.timeout = d
+	}
+}
+
+// WithHTTPClient uses the given HTTP client to talk to Vault, for example to configure TLS.
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *source) {
+		s.client = client
+	}
+}
+
+type source struct {
+	address   string
+	prefix    string
+	path      string
+	client    *http.Client
+	token     string
+	roleID    string
+	secretID  string
+	namespace string
+	timeout   time.Duration
+}
+
+// New returns a config.Source that looks up the keys of the KV version 2 secret at path. The path is
+// the API path of the secret, for example "secret/data/app" for the secret app of the KV engine mounted
+// at secret/. The keys of the secret are mapped to the keys of the fields with config.NormalizeKey and
+// prefixed with prefix, so with the prefix "app" the key db_password maps to APP_DB_PASSWORD. The
+// secret is read on the first lookup.
+func New(address, prefix, path string, opts ...Option) config.Source {
+	s := &source{
+		address: strings.TrimSuffix(address, "/"),
+		prefix:  prefix,
+		path:    strings.Trim(path, "/"),
+		client:  &http.Client{},
+		token:   os.Getenv("VAULT_TOKEN"),
+		timeout: 5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return config.LoadOnce(s.load)
+}
+
+// load reads the secret.
+func (s *source) load() (config.MapSource, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	token := s.token
+	if s.roleID != "" {
+		var resp struct {
+			Auth struct {
+				ClientToken string `json:"client_token"`
+			} `json:"auth"`
+		}
+		req := map[string]string{"role_id": s.roleID, "secret_id": s.secretID}
+		if err := s.call(ctx, http.MethodPost, "auth/approle/login", "", req, &resp); err != nil {
+			return nil, fmt.Errorf("vault: approle login: %w", err)
+		}
+		token = resp.Auth.ClientToken
+	}
+
+	var resp struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := s.call(ctx, http.MethodGet, s.path, token, nil, &resp); err != nil {
+		return nil, fmt.Errorf("vault: reading secret %s: %w", s.path, err)
+	}
+
+	values := make(config.MapSource, len(resp.Data.Data))
+	for k, v := range resp.Data.Data {
+		key := k
+		if s.prefix != "" {
+			key = s.prefix + "_" + k
+		}
+		values[config.NormalizeKey(key)] = fmt.Sprint(v)
+	}
+	return values, nil
+}
+
+// call sends a request to the API path and decodes the response into resp.
+func (s *source) call(ctx context.Context, method, path, token string, req, resp any) error {
+	var body io.Reader
+	if req != nil {
+		data, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	r, err := http.NewRequestWithContext(ctx, method, s.address+"/v1/"+path, body)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		r.Header.Set("X-Vault-Token", token)
+	}
+	if s.namespace != "" {
+		r.Header.Set("X-Vault-Namespace", s.namespace)
+	}
+
+	res, err := s.client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var e struct {
+			Errors []string `json:"errors"`
+		}
+		json.NewDecoder(io.LimitReader(res.Body, 4096)).Decode(&e)
+		return fmt.Errorf("unexpected status %s: %s", res.Status, strings.Join(e.Errors, ", "))
+	}
+
+	dec := json.NewDecoder(res.Body)
+	dec.UseNumber()
+	return dec.Decode(resp)
+}
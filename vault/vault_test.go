@@ -0,0 +1,121 @@
+package vault
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/josemukorivo/config"
+)
+
+type Config struct {
+	Host string
+	DB   struct {
+		Password string
+		Port     int
+	}
+}
+
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			var req map[string]string
+			json.NewDecoder(r.Body).Decode(&req)
+			if req["role_id"] != "role" || req["secret_id"] != "secret" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string][]string{"errors": {"invalid role or secret ID"}})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"auth": map[string]string{"client_token": "approle-token"}})
+		case "/v1/secret/data/app":
+			token := r.Header.Get("X-Vault-Token")
+			if token != "token" && token != "approle-token" {
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(map[string][]string{"errors": {"permission denied"}})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{
+				"data": map[string]any{
+					"data":     map[string]any{"db_password": "s3cr3t", "db_port": 5432},
+					"metadata": map[string]any{"version": 1},
+				},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSource(t *testing.T) {
+	server := newServer(t)
+	os.Clearenv()
+	os.Setenv("APP_HOST", "example.com")
+
+	tests := []struct {
+		description string
+		opts        []Option
+	}{
+		{
+			description: "token",
+			opts:        []Option{WithToken("token")},
+		},
+		{
+			description: "approle",
+			opts:        []Option{WithAppRole("role", "secret")},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var cfg Config
+			source := New(server.URL, "app", "secret/data/app", tc.opts...)
+			if err := config.ParseSources("app", &cfg, config.EnvSource(), source); err != nil {
+				t.Fatal(err)
+			}
+
+			if cfg.Host != "example.com" {
+				t.Fatalf("expected host to be example.com, got %s", cfg.Host)
+			}
+			if cfg.DB.Password != "s3cr3t" {
+				t.Fatalf("expected db password to be s3cr3t, got %s", cfg.DB.Password)
+			}
+			if cfg.DB.Port != 5432 {
+				t.Fatalf("expected db port to be 5432, got %d", cfg.DB.Port)
+			}
+		})
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	server := newServer(t)
+
+	tests := []struct {
+		description string
+		opts        []Option
+	}{
+		{
+			description: "permission denied",
+			opts:        []Option{WithToken("wrong")},
+		},
+		{
+			description: "invalid approle",
+			opts:        []Option{WithAppRole("role", "wrong")},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var cfg Config
+			source := New(server.URL, "app", "secret/data/app", tc.opts...)
+			if err := config.ParseSources("app", &cfg, source); err == nil {
+				t.Fatal("expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+// Package zookeeper provides a config.Source backed by ZooKeeper znodes.
+//
+//	source := zookeeper.New([]string{"zk1:2181", "zk2:2181"}, "/config",
+//		zookeeper.WithTimeout(3*time.Second),
+//	)
+//	err := config.ParseSources("app", &cfg, source, config.EnvSource())
+package zookeeper
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+	"github.com/josemukorivo/config"
+)
+
+// Conn is the part of *zk.Conn used by the Source.
+type Conn interface {
+	Children(path string) ([]string, *zk.Stat, error)
+	Get(path string) ([]byte, *zk.Stat, error)
+}
+
+// Option configures the Source.
+type Option func(*source)
+
+// WithTimeout bounds the time spent connecting to the ensemble, it is also the session timeout and
+// defaults to 5 seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(s *source) {
+		s.timeout = d
+	}
+}
+
+// WithAuth adds the given authentication to the connection, for example the "digest" scheme with
+// "user:password".
+func WithAuth(scheme, auth string) Option {
+	return func(s *source) {
+		s.scheme = scheme
+		s.auth = auth
+	}
+}
+
+// WithConn reads the znodes through the given connection instead of connecting to the servers. The
+// connection is not closed by the Source.
+func WithConn(conn Conn) Option {
+	return func(s *source) {
+		s.conn = conn
+	}
+}
+
+type source struct {
+	servers []string
+	root    string
+	timeout time.Duration
+	scheme  string
+	auth    string
+	conn    Conn
+}
+
+// New returns a config.Source that looks up the znodes under root. The path of a znode relative to root
+// is mapped to the key of a field with config.NormalizeKey, for example with the root "/config" the
+// znode /config/app/db/host maps to APP_DB_HOST. The znodes are read on the first lookup, over a
+// connection that is closed once they are read.
+func New(servers []string, root string, opts ...Option) config.Source {
+	s := &source{
+		servers: servers,
+		root:    path.Clean("/" + root),
+		timeout: 5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return config.LoadOnce(s.load)
+}
+
+// load reads the znodes under the root.
+func (s *source) load() (config.MapSource, error) {
+	conn := s.conn
+	if conn == nil {
+		c, err := s.connect()
+		if err != nil {
+			return nil, fmt.Errorf("zookeeper: connecting to %s: %w", strings.Join(s.servers, ","), err)
+		}
+		defer c.Close()
+		conn = c
+	}
+
+	values := make(config.MapSource)
+	if err := s.walk(conn, s.root, values); err != nil {
+		return nil, fmt.Errorf("zookeeper: reading znodes under %s: %w", s.root, err)
+	}
+	return values, nil
+}
+
+// connect connects to the ensemble and waits for a session to be established.
+func (s *source) connect() (*zk.Conn, error) {
+	conn, events, err := zk.Connect(s.servers, s.timeout, zk.WithLogger(nopLogger{}))
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := time.After(s.timeout)
+	for {
+		select {
+		case event := <-events:
+			if event.State == zk.StateAuthFailed {
+				conn.Close()
+				return nil, errors.New("authentication failed")
+			}
+			if event.State != zk.StateHasSession {
+				continue
+			}
+			if s.scheme != "" {
+				if err := conn.AddAuth(s.scheme, []byte(s.auth)); err != nil {
+					conn.Close()
+					return nil, err
+				}
+			}
+			return conn, nil
+		case <-timeout:
+			conn.Close()
+			return nil, fmt.Errorf("no session after %s", s.timeout)
+		}
+	}
+}
+
+// walk reads the data of the znode at p and its descendants into values.
+func (s *source) walk(conn Conn, p string, values config.MapSource) error {
+	data, _, err := conn.Get(p)
+	if errors.Is(err, zk.ErrNoNode) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(data) > 0 && p != s.root {
+		values[config.NormalizeKey(strings.TrimPrefix(p, s.root))] = string(data)
+	}
+
+	children, _, err := conn.Children(p)
+	if errors.Is(err, zk.ErrNoNode) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		if err := s.walk(conn, path.Join(p, child), values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nopLogger discards the logs of the connection.
+type nopLogger struct{}
+
+func (nopLogger) Printf(string, ...any) {}
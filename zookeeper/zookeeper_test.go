@@ -0,0 +1,107 @@
+package zookeeper
+
+import (
+	"errors"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+	"github.com/josemukorivo/config"
+)
+
+// fakeConn serves znodes from a map of paths to data.
+type fakeConn struct {
+	znodes map[string]string
+	err    error
+}
+
+func (c *fakeConn) Get(p string) ([]byte, *zk.Stat, error) {
+	if c.err != nil {
+		return nil, nil, c.err
+	}
+	data, ok := c.znodes[p]
+	if !ok {
+		return nil, nil, zk.ErrNoNode
+	}
+	return []byte(data), &zk.Stat{}, nil
+}
+
+func (c *fakeConn) Children(p string) ([]string, *zk.Stat, error) {
+	var children []string
+	for znode := range c.znodes {
+		if path.Dir(znode) == p && znode != p {
+			children = append(children, strings.TrimPrefix(znode, strings.TrimSuffix(p, "/")+"/"))
+		}
+	}
+	sort.Strings(children)
+	return children, &zk.Stat{}, nil
+}
+
+type Config struct {
+	Host string
+	DB   struct {
+		Host string
+		Port int
+	}
+}
+
+func TestSource(t *testing.T) {
+	conn := &fakeConn{znodes: map[string]string{
+		"/":                   "",
+		"/config":             "",
+		"/config/app":         "",
+		"/config/app/host":    "zk.example.com",
+		"/config/app/db":      "",
+		"/config/app/db/host": "db.example.com",
+		"/config/app/db/port": "5432",
+		"/other/app/db/port":  "6543",
+	}}
+
+	os.Clearenv()
+	os.Setenv("APP_HOST", "example.com")
+
+	var cfg Config
+	source := New(nil, "/config/", WithConn(conn))
+	if err := config.ParseSources("app", &cfg, source, config.EnvSource()); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Host != "example.com" {
+		t.Fatalf("expected host to be example.com, got %s", cfg.Host)
+	}
+	if cfg.DB.Host != "db.example.com" {
+		t.Fatalf("expected db host to be db.example.com, got %s", cfg.DB.Host)
+	}
+	if cfg.DB.Port != 5432 {
+		t.Fatalf("expected db port to be 5432, got %d", cfg.DB.Port)
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	tests := []struct {
+		description string
+		source      config.Source
+	}{
+		{
+			description: "read error",
+			source:      New(nil, "/config", WithConn(&fakeConn{err: errors.New("not authenticated")})),
+		},
+		{
+			description: "connection timeout",
+			source:      New([]string{"127.0.0.1:1"}, "/config", WithTimeout(100*time.Millisecond)),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var cfg Config
+			if err := config.ParseSources("app", &cfg, tc.source); err == nil {
+				t.Fatal("expected error, got nil")
+			}
+		})
+	}
+}